@@ -0,0 +1,31 @@
+package light
+
+import (
+	"context"
+	"time"
+
+	"github.com/ptflp/go-light/types"
+)
+
+type AuditEvent struct {
+	UUID      types.NullUUID   `json:"event_id" db:"uuid" ops:"create" orm_type:"binary(16)" orm_default:"not null primary key"`
+	Type      types.NullInt64  `json:"type" db:"type" ops:"create" orm_type:"int" orm_default:"not null" orm_index:"index"`
+	UserUUID  types.NullUUID   `json:"user_id" db:"user_uuid" ops:"create" orm_type:"binary(16)" orm_default:"not null" orm_index:"index"`
+	ActorUUID types.NullUUID   `json:"actor_id" db:"actor_uuid" ops:"create" orm_type:"binary(16)" orm_default:"null" orm_index:"index"`
+	Reason    types.NullString `json:"reason" db:"reason" ops:"create" orm_type:"varchar(255)"`
+	IP        types.NullString `json:"ip" db:"ip" ops:"create" orm_type:"varchar(45)"`
+	CreatedAt time.Time        `json:"created_at" db:"created_at" orm_type:"timestamp" orm_default:"default (now()) not null" orm_index:"index"`
+}
+
+func (a AuditEvent) OnCreate() string {
+	return ""
+}
+
+func (a AuditEvent) TableName() string {
+	return "audit_events"
+}
+
+type AuditRepository interface {
+	Create(ctx context.Context, event AuditEvent) error
+	FindByUser(ctx context.Context, user User) ([]AuditEvent, error)
+}
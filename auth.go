@@ -7,12 +7,17 @@ import (
 )
 
 type AuthService interface {
-	SendCode(ctx context.Context, req *request.PhoneCodeRequest) bool
+	SendCode(ctx context.Context, req *request.PhoneCodeRequest) (request.PhoneCodeData, error)
 	CheckCode(ctx context.Context, req *request.CheckCodeRequest) (*request.AuthTokenData, error)
 	EmailActivation(ctx context.Context, req *request.EmailActivationRequest) error
 	EmailVerification(ctx context.Context, req *request.EmailVerificationRequest) (*request.AuthTokenData, error)
 	SocialCallback(ctx context.Context, state string) (string, error)
 	Oauth2Token(ctx context.Context, tokenRequest request.StateRequest) (*request.AuthTokenData, error)
 	EmailLogin(ctx context.Context, req *request.EmailLoginRequest) (*request.AuthTokenData, error)
+	Login(ctx context.Context, req *request.LoginRequest) (*request.AuthTokenData, error)
 	RefreshToken(ctx context.Context, req *request.RefreshTokenRequest) (*request.AuthTokenData, error)
+	GuestLogin(ctx context.Context, req *request.GuestLoginRequest) (*request.AuthTokenData, error)
+	QRChallenge(ctx context.Context) (request.QRChallengeData, error)
+	QRStatus(ctx context.Context, req request.QRStatusRequest) (request.QRStatusData, error)
+	QRApprove(ctx context.Context, req request.QRApproveRequest) error
 }
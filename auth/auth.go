@@ -1,29 +1,28 @@
 package auth
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"math/rand"
-	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/ptflp/go-light/types"
 
 	"github.com/ptflp/go-light/decoder"
+	"github.com/ptflp/go-light/email"
 
 	"github.com/ptflp/go-light/components"
+	"github.com/ptflp/go-light/config"
 
 	"github.com/google/uuid"
 
 	"github.com/ptflp/go-light/hasher"
 
-	"github.com/ptflp/go-light/validators"
-
 	"github.com/ptflp/go-light/request"
 
 	"github.com/ptflp/go-light/providers"
@@ -37,11 +36,25 @@ import (
 
 const (
 	EmailVerificationKey = "email:verification:%s"
+	EmailGuestKey        = "email:guest:%s"
 	PhoneRegistrationKey = "phone:registration:%s"
 
-	SocialsAuthKey = "socials:auth:%s"
+	SocialsAuthKey   = "socials:auth:%s"
+	SocialsSignUpKey = "socials:signup:%s"
+
+	LoginAttemptsKey = "login:attempts:%s"
+	loginAttempts    = 5
+	loginLockout     = 15 * time.Minute
+
+	deviceIDMinLength = 8
 )
 
+// ErrLogin single error of password login, cause of failure is not disclosed
+var ErrLogin = errors.New("wrong login or password")
+
+// errChannelUnavailable channel can't be used for phone, next channel is tried without logging
+var errChannelUnavailable = errors.New("phone code channel is not available")
+
 type Provider struct{}
 type State struct{}
 
@@ -49,24 +62,51 @@ type service struct {
 	*decoder.Decoder
 	smsProvider    providers.SMS
 	userRepository light.UserRepository
+	invites        light.InviteService
 	components.Componenter
+	// qrMu serializes approval and one-time token issue of qr login challenges
+	qrMu sync.Mutex
 }
 
 func NewAuthService(
 	repositories light.Repositories,
 	cmps components.Componenter,
+	invites light.InviteService,
 ) *service {
-	return &service{Componenter: cmps, userRepository: repositories.Users, smsProvider: cmps.SMS(), Decoder: decoder.NewDecoder()}
+	return &service{Componenter: cmps, userRepository: repositories.Users, invites: invites, smsProvider: cmps.SMS(), Decoder: decoder.NewDecoder()}
 }
 
 func (a *service) EmailActivation(ctx context.Context, req *request.EmailActivationRequest) error {
+	if a.invites.Required() {
+		err := a.invites.Check(ctx, req.InviteCode)
+		if err != nil {
+			return err
+		}
+	}
+
+	address, err := a.EmailValidator().Validate(req.Email)
+	if err != nil {
+		return err
+	}
+	req.Email = address
+
 	// 1. Check user existance
 	u := light.User{
 		Email: types.NewNullString(req.Email),
 	}
-	u, err := a.userRepository.FindByEmail(ctx, u)
-	if err == nil && !u.UUID.Valid {
-		return errors.New("user with specified email already exist")
+	u, err = a.userRepository.FindByEmail(ctx, u)
+	if err == nil && u.UUID.Valid {
+		if !a.Config().Security.AntiEnumeration {
+			return errors.New("user with specified email already exist")
+		}
+		// owner is notified instead of revealing account existence
+		_ = components.Dispatch(a.Componenter, func() error {
+			return a.registrationAttempt(u)
+		})
+		// password hashing dominates registration branch time
+		_, _ = hasher.HashPassword(req.Password)
+
+		return nil
 	}
 
 	activationUrl, activationID, err := a.generateActivationUrl(req.Email)
@@ -74,7 +114,9 @@ func (a *service) EmailActivation(ctx context.Context, req *request.EmailActivat
 		return err
 	}
 
-	err = sendEmail(req.Email, activationUrl)
+	err = components.Dispatch(a.Componenter, func() error {
+		return a.sendActivation(req.Email, req.Language, activationUrl)
+	})
 	if err != nil {
 		return err
 	}
@@ -88,6 +130,9 @@ func (a *service) EmailActivation(ctx context.Context, req *request.EmailActivat
 
 	// 2. Set email code to cache
 	a.Cache().Set(fmt.Sprintf(EmailVerificationKey, activationID), data, 3*24*time.Hour)
+	if guest, ok := a.guest(ctx); ok {
+		a.Cache().Set(fmt.Sprintf(EmailGuestKey, activationID), &guest.UUID.String, 3*24*time.Hour)
+	}
 
 	return nil
 }
@@ -102,9 +147,25 @@ func (a *service) EmailVerification(ctx context.Context, req *request.EmailVerif
 	if err != nil {
 		return nil, err
 	}
+	var activation request.EmailActivationRequest
+	err = a.Cache().Get(key, &activation)
+	if err != nil {
+		return nil, err
+	}
 
 	u.EmailVerified = types.NewNullBool(true)
-	err = a.userRepository.CreateUser(ctx, u)
+	existing, err := a.userRepository.FindByEmail(ctx, u)
+	if err == nil && existing.EmailVerified.Bool {
+		return nil, fmt.Errorf("user with email %s already verified", u.Email.String)
+	}
+	if err != nil {
+		var guestUUID string
+		if a.Cache().Get(fmt.Sprintf(EmailGuestKey, req.ActivationID), &guestUUID) == nil {
+			_, err = a.upgradeGuest(ctx, light.User{UUID: types.NewNullUUID(guestUUID)}, u, activation.InviteCode)
+		} else {
+			err = a.createUser(ctx, u, activation.InviteCode)
+		}
+	}
 	if err != nil {
 		if strings.Contains(err.Error(), "Duplicate entry") {
 			u, err = a.userRepository.FindByEmail(ctx, u)
@@ -127,6 +188,8 @@ func (a *service) EmailVerification(ctx context.Context, req *request.EmailVerif
 		return nil, errors.New("email verification wrong user.UUID")
 	}
 
+	_ = a.Cache().Del(fmt.Sprintf(EmailGuestKey, req.ActivationID))
+
 	authTokens, err := a.JWTKeys().GenerateAuthTokens(&u)
 	if err != nil {
 		return nil, err
@@ -141,6 +204,9 @@ func (a *service) RefreshToken(ctx context.Context, req *request.RefreshTokenReq
 	if err != nil {
 		return nil, err
 	}
+	if refreshToken.Device != "" && refreshToken.Device != hasher.NewSHA256([]byte(req.DeviceID)) {
+		return nil, errors.New("refresh token device mismatch")
+	}
 
 	key := strings.Join([]string{session.RefreshTokenKey, refreshToken.UUID, refreshToken.Token}, ":")
 	err = a.Cache().Get(key, &u)
@@ -155,6 +221,18 @@ func (a *service) RefreshToken(ctx context.Context, req *request.RefreshTokenReq
 	if err != nil {
 		return nil, err
 	}
+	if u.DeletedAt.Valid {
+		return nil, errors.New("user deleted")
+	}
+
+	if u.IsGuest() {
+		// guests not refreshing their tokens are purged
+		err = a.userRepository.Touch(ctx, u)
+		if err != nil {
+			a.Logger().Error("guest activity", zap.String("uuid", u.UUID.String), zap.Error(err))
+		}
+		return a.JWTKeys().GenerateDeviceAuthTokens(&u, req.DeviceID)
+	}
 
 	authTokens, err := a.JWTKeys().GenerateAuthTokens(&u)
 	if err != nil {
@@ -164,23 +242,102 @@ func (a *service) RefreshToken(ctx context.Context, req *request.RefreshTokenReq
 	return authTokens, nil
 }
 
+// GuestLogin creates anonymous account with refresh token bound to device
+func (a *service) GuestLogin(ctx context.Context, req *request.GuestLoginRequest) (*request.AuthTokenData, error) {
+	if len(req.DeviceID) < deviceIDMinLength {
+		return nil, errors.New("device_id is required")
+	}
+	u, err := createDefaultUser()
+	if err != nil {
+		return nil, err
+	}
+	u.Role = types.NewNullInt64(types.RoleGuest)
+
+	err = a.userRepository.CreateUser(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+	u, err = a.userRepository.Find(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+
+	return a.JWTKeys().GenerateDeviceAuthTokens(&u, req.DeviceID)
+}
+
 func (a *service) EmailLogin(ctx context.Context, req *request.EmailLoginRequest) (*request.AuthTokenData, error) {
-	var u light.User
-	u.Email = types.NewNullString(req.Email)
+	address := a.canonicalEmail(req.Email)
+
+	return a.passwordLogin(ctx, req.Password, func() (light.User, error) {
+		return a.userRepository.FindByEmail(ctx, light.User{Email: types.NewNullString(address)})
+	})
+}
 
-	u, err := a.userRepository.FindByEmail(ctx, u)
+// canonicalEmail stored form of address, malformed address is returned as is and is not found
+func (a *service) canonicalEmail(address string) string {
+	canonical, err := a.EmailValidator().Canonical(address)
+	if err != nil {
+		return address
+	}
+
+	return canonical
+}
+
+// Login password login by email, phone or nickname
+func (a *service) Login(ctx context.Context, req *request.LoginRequest) (*request.AuthTokenData, error) {
+	login := strings.TrimSpace(req.Login)
+	if login == "" {
+		return nil, errors.New("login is required")
+	}
+
+	if strings.Contains(login, "@") {
+		login = a.canonicalEmail(login)
+		return a.passwordLogin(ctx, req.Password, func() (light.User, error) {
+			return a.userRepository.FindByEmail(ctx, light.User{Email: types.NewNullString(login)})
+		})
+	}
+	if isPhoneLike(login) {
+		phone, err := a.PhoneValidator().Normalize(ctx, login)
+		if err == nil {
+			return a.passwordLogin(ctx, req.Password, func() (light.User, error) {
+				return a.userRepository.FindByPhone(ctx, light.User{Phone: types.NewNullString(phone)})
+			})
+		}
+	}
+
+	return a.passwordLogin(ctx, req.Password, func() (light.User, error) {
+		// deleted account is restored by login within grace period
+		return a.userRepository.FindByNicknameWithDeleted(ctx, light.User{NickName: types.NewNullString(login)})
+	})
+}
+
+// passwordLogin checks password of user found by identifier, failed attempts are throttled per account.
+// Every failure returns ErrLogin so response doesn't reveal whether account exists
+func (a *service) passwordLogin(ctx context.Context, password string, find func() (light.User, error)) (*request.AuthTokenData, error) {
+	u, err := find()
+	if err != nil || !u.UUID.Valid || !u.Password.Valid {
+		// hash is checked anyway so response time is the same for unknown accounts
+		hasher.CheckPasswordHash(password, dummyPasswordHash())
+		return nil, ErrLogin
+	}
+
+	// attempt is counted before password check so concurrent requests can't exceed limit
+	key := fmt.Sprintf(LoginAttemptsKey, u.UUID.String)
+	attempts, err := a.Cache().Incr(key, loginLockout)
 	if err != nil {
 		return nil, err
 	}
-	if !u.UUID.Valid {
-		return nil, errors.New("wrong user.ID")
+	if attempts > loginAttempts {
+		return nil, ErrLogin
 	}
-	if !u.Password.Valid {
-		return nil, errors.New("user password not set")
+	if !hasher.CheckPasswordHash(password, u.Password.String) {
+		return nil, ErrLogin
 	}
+	_ = a.Cache().Del(key)
 
-	if !hasher.CheckPasswordHash(req.Password, u.Password.String) {
-		return nil, errors.New("wrong email password")
+	err = a.restore(ctx, &u)
+	if err != nil {
+		return nil, err
 	}
 
 	token, err := a.JWTKeys().GenerateAuthTokens(&u)
@@ -191,6 +348,20 @@ func (a *service) EmailLogin(ctx context.Context, req *request.EmailLoginRequest
 	return token, nil
 }
 
+var (
+	dummyHash     string
+	dummyHashOnce sync.Once
+)
+
+// dummyPasswordHash bcrypt hash of random password compared when account is not found
+func dummyPasswordHash() string {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = hasher.HashPassword(uuid.New().String())
+	})
+
+	return dummyHash
+}
+
 func (a *service) generateActivationUrl(email string) (string, string, error) {
 	uid := uuid.New()
 	dh, err := uid.MarshalBinary()
@@ -210,30 +381,96 @@ func (a *service) generateActivationUrl(email string) (string, string, error) {
 	return u.String(), hash, err
 }
 
-func (a *service) SendCode(ctx context.Context, req *request.PhoneCodeRequest) bool {
-	phone, err := validators.CheckPhoneFormat(req.Phone)
+func (a *service) SendCode(ctx context.Context, req *request.PhoneCodeRequest) (request.PhoneCodeData, error) {
+	phone, err := a.PhoneValidator().Mobile(ctx, req.Phone)
 	if err != nil {
-		return false
+		return request.PhoneCodeData{}, err
 	}
-	code := genCode()
-	if a.Config().SMSC.Dev {
-		code = 3455
+	channels, err := a.codeChannels(req.Channel)
+	if err != nil {
+		return request.PhoneCodeData{}, err
 	}
-	a.Cache().Set(fmt.Sprintf(PhoneRegistrationKey, phone), &code, 15*time.Minute)
 	if a.Config().SMSC.Dev {
-		return true
+		code := 3455
+		a.Cache().Set(fmt.Sprintf(PhoneRegistrationKey, phone), &code, 15*time.Minute)
+		return request.PhoneCodeData{Channel: channels[0]}, nil
+	}
+
+	var failed, skipped bool
+	// next channel is tried when delivery fails, e.g. sms to operator is not available
+	for _, channel := range channels {
+		code, err := a.deliverCode(ctx, channel, phone)
+		if errors.Is(err, errChannelUnavailable) {
+			skipped = true
+			continue
+		}
+		if err != nil {
+			failed = true
+			a.Logger().Error("send phone code err", zap.String("channel", channel), zap.String("phone", phone), zap.Error(err))
+			continue
+		}
+		a.Cache().Set(fmt.Sprintf(PhoneRegistrationKey, phone), &code, 15*time.Minute)
+		// channel used after telegram was skipped would reveal phone has no linked telegram account
+		if skipped {
+			channel = config.PhoneChannelTelegram
+		}
+
+		return request.PhoneCodeData{Channel: channel}, nil
+	}
+	// phone without linked telegram account looks the same when telegram is the only channel
+	if !failed {
+		return request.PhoneCodeData{Channel: config.PhoneChannelTelegram}, nil
 	}
 
-	err = a.smsProvider.Send(ctx, phone, fmt.Sprintf("Ваш код: %d", code))
-	if err != nil {
-		a.Logger().Error("send sms err", zap.String("phone", phone), zap.Int("code", code))
+	return request.PhoneCodeData{}, errors.New("phone code not sent")
+}
+
+// codeChannels configured channels, channel chosen by client goes first
+func (a *service) codeChannels(chosen string) ([]string, error) {
+	configured := a.Config().Phone.Channels()
+	if chosen == "" {
+		return configured, nil
+	}
+	channels := []string{chosen}
+	for _, channel := range configured {
+		if channel != chosen {
+			channels = append(channels, channel)
+		}
+	}
+	if len(channels) > len(configured) {
+		return nil, fmt.Errorf("phone code channel %q is not available", chosen)
 	}
 
-	return err == nil
+	return channels, nil
+}
+
+func (a *service) deliverCode(ctx context.Context, channel, phone string) (int, error) {
+	switch channel {
+	case config.PhoneChannelFlash:
+		digits, err := a.Voice().FlashCall(ctx, phone)
+		if err != nil {
+			return 0, err
+		}
+		return strconv.Atoi(digits)
+	case config.PhoneChannelVoice:
+		code := genCode()
+		return code, a.Voice().Call(ctx, phone, fmt.Sprintf("Ваш код: %s", spellCode(code)))
+	case config.PhoneChannelTelegram:
+		// telegram is used for login of existing accounts only, new phones are verified by other channels
+		user, err := a.userRepository.FindByPhone(ctx, light.User{Phone: types.NewNullString(phone)})
+		if err != nil || !user.TelegramID.Valid {
+			return 0, errChannelUnavailable
+		}
+		code := genCode()
+		return code, a.Telegram().SendMessage(ctx, user.TelegramID.Int64, fmt.Sprintf("Ваш код: %d", code))
+	default:
+		code := genCode()
+		return code, a.smsProvider.Send(ctx, phone, fmt.Sprintf("Ваш код: %d", code))
+	}
 }
 
 func (a *service) CheckCode(ctx context.Context, req *request.CheckCodeRequest) (*request.AuthTokenData, error) {
-	phone, err := validators.CheckPhoneFormat(req.Phone)
+	phone, err := a.PhoneValidator().Normalize(ctx, req.Phone)
 	if err != nil {
 		return nil, err
 	}
@@ -255,26 +492,38 @@ func (a *service) CheckCode(ctx context.Context, req *request.CheckCodeRequest)
 	}
 	u, err = a.userRepository.FindByPhone(ctx, u)
 	if err != nil && err.Error() == "sql: no rows in result set" {
-		u, err = createDefaultUser()
-		if err != nil {
-			return nil, err
-		}
-		u.Phone = phoneEnt
+		if guest, ok := a.guest(ctx); ok {
+			u, err = a.upgradeGuest(ctx, guest, light.User{Phone: phoneEnt}, req.InviteCode)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			u, err = createDefaultUser()
+			if err != nil {
+				return nil, err
+			}
+			u.Phone = phoneEnt
 
-		err = a.userRepository.CreateUser(ctx, u)
-		if err != nil {
-			return nil, err
-		}
+			err = a.createUser(ctx, u, req.InviteCode)
+			if err != nil {
+				return nil, err
+			}
 
-		u, err = a.userRepository.Find(ctx, u)
-		if err != nil {
-			return nil, err
+			u, err = a.userRepository.Find(ctx, u)
+			if err != nil {
+				return nil, err
+			}
 		}
 	}
 	if err != nil {
 		return nil, err
 	}
 
+	err = a.restore(ctx, &u)
+	if err != nil {
+		return nil, err
+	}
+
 	token, err := a.JWTKeys().GenerateAuthTokens(&u)
 	if err != nil {
 		return nil, err
@@ -295,6 +544,8 @@ func (a *service) SocialCallback(ctx context.Context, state string) (string, err
 		return "", fmt.Errorf("wrong provider")
 	}
 
+	// new user creation deferred to Oauth2Token where invite code can be provided
+	var signUp bool
 	var user light.User
 	switch provider {
 	case "facebook":
@@ -306,10 +557,7 @@ func (a *service) SocialCallback(ctx context.Context, state string) (string, err
 			}
 			user.FacebookID = u.FacebookID
 			user.Name = u.Name
-			err = a.userRepository.CreateUser(ctx, user)
-			if err != nil {
-				return "", err
-			}
+			signUp = true
 		}
 	case "google":
 		user, err = a.userRepository.FindByGoogle(ctx, *u)
@@ -320,15 +568,21 @@ func (a *service) SocialCallback(ctx context.Context, state string) (string, err
 			}
 			user.GoogleID = u.GoogleID
 			user.Name = u.Name
-			err = a.userRepository.CreateUser(ctx, user)
-			if err != nil {
-				return "", err
-			}
+			signUp = true
 		}
 	default:
 		return "", fmt.Errorf("unknown provider %s", provider)
 	}
 
+	if signUp {
+		a.Cache().Set(fmt.Sprintf(SocialsSignUpKey, state), &signUp, 10*time.Minute)
+	}
+
+	err = a.restore(ctx, &user)
+	if err != nil {
+		return "", err
+	}
+
 	a.Cache().Set(fmt.Sprintf(SocialsAuthKey, state), &user, 10*time.Minute)
 
 	uri, err := url.Parse(a.Config().App.FrontEnd)
@@ -342,13 +596,30 @@ func (a *service) SocialCallback(ctx context.Context, state string) (string, err
 }
 
 func (a *service) Oauth2Token(ctx context.Context, stateRequest request.StateRequest) (*request.AuthTokenData, error) {
-	_ = ctx
 	var u light.User
 	key := fmt.Sprintf(SocialsAuthKey, stateRequest.State)
 	err := a.Cache().Get(key, &u)
 	if err != nil {
 		return nil, err
 	}
+
+	var signUp bool
+	signUpKey := fmt.Sprintf(SocialsSignUpKey, stateRequest.State)
+	if err = a.Cache().Get(signUpKey, &signUp); err == nil && signUp {
+		if guest, ok := a.guest(ctx); ok {
+			u, err = a.upgradeGuest(ctx, guest, u, stateRequest.InviteCode)
+		} else {
+			err = a.createUser(ctx, u, stateRequest.InviteCode)
+		}
+		if err != nil {
+			return nil, err
+		}
+		err = a.Cache().Del(signUpKey)
+		if err != nil {
+			a.Logger().Error("social signup key deletion", zap.Error(err))
+		}
+	}
+
 	err = a.Cache().Del(key)
 	if err != nil {
 		a.Logger().Error("social auth key deletion", zap.Error(err))
@@ -362,52 +633,128 @@ func (a *service) Oauth2Token(ctx context.Context, stateRequest request.StateReq
 	return token, err
 }
 
-func genCode() int {
-	rand.Seed(time.Now().UnixNano())
-	code := rand.Intn(8999) + 1000
+// createUser creates new user, invite code redeemed when registration is invite-only or code provided
+func (a *service) createUser(ctx context.Context, u light.User, inviteCode string) error {
+	if a.invites.Required() || inviteCode != "" {
+		// user is created in transaction of invite usage
+		return a.invites.Redeem(ctx, inviteCode, u)
+	}
 
-	return code
+	return a.userRepository.CreateUser(ctx, u)
 }
 
-func createDefaultUser() (light.User, error) {
-	return light.User{
-		UUID:        types.NewNullUUID(),
-		Trial:       types.NewNullBool(true),
-		NotifyEmail: types.NewNullBool(true),
-		Language:    types.NewNullInt64(1),
-	}, nil
-}
+// registrationAttempt notifies account owner that someone tried to register with their address
+func (a *service) registrationAttempt(u light.User) error {
+	uri, err := url.Parse(a.Config().App.FrontEnd)
+	if err != nil {
+		return err
+	}
+	uri.Path = "recover"
 
-type Payload struct {
-	Accesstoken    string `json:"accessToken"`
-	Email          string `json:"email"`
-	Activationlink string `json:"activationLink"`
+	msg, err := a.Templates().Message(email.TemplateRegistrationAttempt, u.Language.Int64, struct {
+		Link string
+	}{
+		Link: uri.String(),
+	})
+	if err != nil {
+		return err
+	}
+	msg.SetReceiver(u.Email.String)
+
+	return a.Email().Send(msg)
 }
 
-func sendEmail(email, link string) error {
-	data := Payload{
-		Accesstoken:    "",
-		Email:          email,
-		Activationlink: link,
+// guest returns guest account which authorized request, route must be wrapped with token.Check
+func (a *service) guest(ctx context.Context) (light.User, bool) {
+	u, ok := ctx.Value(types.User{}).(*light.User)
+	if !ok || !u.UUID.Valid || !u.IsGuest() {
+		return light.User{}, false
+	}
+	guest, err := a.userRepository.Find(ctx, *u)
+	if err != nil || !guest.IsGuest() || guest.DeletedAt.Valid {
+		return light.User{}, false
 	}
 
-	payloadBytes, err := json.Marshal(data)
+	return guest, true
+}
+
+// upgradeGuest attaches identity to guest account keeping its uuid and data
+func (a *service) upgradeGuest(ctx context.Context, guest light.User, identity light.User, inviteCode string) (light.User, error) {
+	identity.UUID = guest.UUID
+	var err error
+	if a.invites.Required() || inviteCode != "" {
+		// guest is upgraded in transaction of invite usage
+		err = a.invites.RedeemGuest(ctx, inviteCode, identity)
+	} else {
+		err = a.userRepository.UpgradeGuest(ctx, identity)
+	}
 	if err != nil {
-		return err
+		return light.User{}, err
+	}
+
+	return a.userRepository.Find(ctx, guest)
+}
+
+// restore brings back deleted account on login within grace period
+func (a *service) restore(ctx context.Context, u *light.User) error {
+	if !u.DeletedAt.Valid {
+		return nil
+	}
+	if time.Since(u.DeletedAt.Time.Time) > a.Config().App.DeletionGrace() {
+		return errors.New("user deleted")
 	}
-	body := bytes.NewReader(payloadBytes)
 
-	req, err := http.NewRequest("POST", "http://localhost/api/v1/activation", body)
+	err := a.userRepository.Restore(ctx, *u)
 	if err != nil {
 		return err
 	}
-	req.Header.Set("Content-Type", "application/json")
+	u.DeletedAt = types.NullTime{}
+	a.Logger().Info("user restored", zap.String("uuid", u.UUID.String))
 
-	resp, err := http.DefaultClient.Do(req)
+	return nil
+}
+
+func isPhoneLike(login string) bool {
+	for _, r := range login {
+		if !strings.ContainsRune("+0123456789()- ", r) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// spellCode separates digits, so speech synthesis reads them one by one
+func spellCode(code int) string {
+	return strings.Join(strings.Split(strconv.Itoa(code), ""), " ")
+}
+
+func genCode() int {
+	rand.Seed(time.Now().UnixNano())
+	code := rand.Intn(8999) + 1000
+
+	return code
+}
+
+func createDefaultUser() (light.User, error) {
+	return light.User{
+		UUID:        types.NewNullUUID(),
+		Trial:       types.NewNullBool(true),
+		NotifyEmail: types.NewNullBool(true),
+		Language:    types.NewNullInt64(types.LanguageRu),
+	}, nil
+}
+
+func (a *service) sendActivation(address string, language int64, link string) error {
+	msg, err := a.Templates().Message(email.TemplateActivation, language, struct {
+		Link string
+	}{
+		Link: link,
+	})
 	if err != nil {
 		return err
 	}
-	defer resp.Body.Close()
+	msg.SetReceiver(address)
 
-	return nil
+	return a.Email().Send(msg)
 }
@@ -0,0 +1,397 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	light "github.com/ptflp/go-light"
+	"github.com/ptflp/go-light/components/componentstest"
+	"github.com/ptflp/go-light/config"
+	"github.com/ptflp/go-light/providers"
+	"github.com/ptflp/go-light/request"
+	"github.com/ptflp/go-light/types"
+	"github.com/volatiletech/null/v8"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const testInviteCode = "invite"
+
+// testInvites redeems testInviteCode only, users are created in fake repository like in invite transaction
+type testInvites struct {
+	light.InviteService
+	users    *componentstest.Users
+	required bool
+	redeemed []string
+}
+
+func (i *testInvites) Required() bool {
+	return i.required
+}
+
+func (i *testInvites) Redeem(ctx context.Context, code string, invitee light.User) error {
+	if code != testInviteCode {
+		return errors.New("invite not found")
+	}
+	i.redeemed = append(i.redeemed, invitee.UUID.String)
+
+	return i.users.CreateUser(ctx, invitee)
+}
+
+func (i *testInvites) RedeemGuest(ctx context.Context, code string, identity light.User) error {
+	if code != testInviteCode {
+		return errors.New("invite not found")
+	}
+	i.redeemed = append(i.redeemed, identity.UUID.String)
+
+	return i.users.UpgradeGuest(ctx, identity)
+}
+
+func newTestService(t *testing.T) (*service, *componentstest.Users) {
+	t.Helper()
+	users := &componentstest.Users{}
+
+	return &service{Componenter: componentstest.New(t, nil), userRepository: users, invites: &testInvites{users: users}}, users
+}
+
+func testPassword(t *testing.T, password string) types.NullString {
+	t.Helper()
+	// minimal cost keeps tests fast, cost is read from hash on check
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return types.NewNullString(string(hash))
+}
+
+func TestLogin(t *testing.T) {
+	a, users := newTestService(t)
+	ctx := context.Background()
+	u := users.Add(light.User{
+		UUID:     types.NewNullUUID(),
+		Email:    types.NewNullString("user@example.com"),
+		Phone:    types.NewNullString("79644288083"),
+		NickName: types.NewNullString("user"),
+		Password: testPassword(t, "secret"),
+	})
+
+	for _, login := range []string{"User@Example.com", "+7 (964) 428-80-83", "user"} {
+		token, err := a.Login(ctx, &request.LoginRequest{Login: login, Password: "secret"})
+		if err != nil {
+			t.Fatalf("Login(%q) error = %v", login, err)
+		}
+		if token.User.UUID.String != u.UUID.String {
+			t.Errorf("Login(%q) uuid = %s", login, token.User.UUID.String)
+		}
+	}
+
+	tests := []request.LoginRequest{
+		{Login: "user", Password: "wrong"},
+		{Login: "unknown", Password: "secret"},
+		{Login: "unknown@example.com", Password: "secret"},
+	}
+	for _, req := range tests {
+		if _, err := a.Login(ctx, &req); !errors.Is(err, ErrLogin) {
+			t.Errorf("Login(%q) error = %v, want ErrLogin", req.Login, err)
+		}
+	}
+}
+
+func TestLogin_Throttling(t *testing.T) {
+	a, users := newTestService(t)
+	ctx := context.Background()
+	users.Add(light.User{
+		UUID:     types.NewNullUUID(),
+		Email:    types.NewNullString("user@example.com"),
+		NickName: types.NewNullString("user"),
+		Password: testPassword(t, "secret"),
+	})
+
+	// attempts of every identifier are counted for account
+	var wg sync.WaitGroup
+	for i := 0; i < loginAttempts; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			login := "user"
+			if i%2 == 0 {
+				login = "user@example.com"
+			}
+			_, _ = a.Login(ctx, &request.LoginRequest{Login: login, Password: "wrong"})
+		}(i)
+	}
+	wg.Wait()
+
+	_, err := a.Login(ctx, &request.LoginRequest{Login: "user", Password: "secret"})
+	if !errors.Is(err, ErrLogin) {
+		t.Errorf("Login() after %d failed attempts error = %v, want ErrLogin", loginAttempts, err)
+	}
+	_, err = a.EmailLogin(ctx, &request.EmailLoginRequest{Email: "user@example.com", Password: "secret"})
+	if !errors.Is(err, ErrLogin) {
+		t.Errorf("EmailLogin() of locked account error = %v, want ErrLogin", err)
+	}
+}
+
+func TestLogin_RestoreByNickname(t *testing.T) {
+	a, users := newTestService(t)
+	ctx := context.Background()
+	deletedAt := types.NullTime{Time: null.TimeFrom(time.Now().Add(-time.Hour))}
+	u := users.Add(light.User{
+		UUID:      types.NewNullUUID(),
+		NickName:  types.NewNullString("user"),
+		Password:  testPassword(t, "secret"),
+		DeletedAt: deletedAt,
+	})
+
+	_, err := a.Login(ctx, &request.LoginRequest{Login: "user", Password: "secret"})
+	if err != nil {
+		t.Fatalf("Login() of deleted account error = %v", err)
+	}
+	if len(users.Restored()) != 1 || users.Restored()[0] != u.UUID.String {
+		t.Fatalf("restored = %v", users.Restored())
+	}
+
+	// account is not restored after grace period
+	users.Add(light.User{
+		UUID:      types.NewNullUUID(),
+		NickName:  types.NewNullString("purged"),
+		Password:  testPassword(t, "secret"),
+		DeletedAt: types.NullTime{Time: null.TimeFrom(time.Now().Add(-a.Config().App.DeletionGrace() - time.Hour))},
+	})
+	if _, err = a.Login(ctx, &request.LoginRequest{Login: "purged", Password: "secret"}); err == nil {
+		t.Error("Login() after grace period error is nil")
+	}
+}
+
+func TestCheckCode_Invite(t *testing.T) {
+	a, users := newTestService(t)
+	ctx := context.Background()
+	a.Config().SMSC.Dev = true
+	invites := a.invites.(*testInvites)
+	invites.required = true
+	phone := types.NewNullString("79644288083")
+
+	for _, code := range []string{"", "wrong"} {
+		_, err := a.CheckCode(ctx, &request.CheckCodeRequest{Phone: phone.String, Code: 3455, InviteCode: code})
+		if err == nil {
+			t.Errorf("CheckCode() with invite code %q error is nil", code)
+		}
+	}
+	if _, err := users.FindByPhone(ctx, light.User{Phone: phone}); err == nil {
+		t.Fatal("user is created without invite")
+	}
+
+	token, err := a.CheckCode(ctx, &request.CheckCodeRequest{Phone: phone.String, Code: 3455, InviteCode: testInviteCode})
+	if err != nil {
+		t.Fatalf("CheckCode() error = %v", err)
+	}
+	if len(invites.redeemed) != 1 || invites.redeemed[0] != token.User.UUID.String {
+		t.Errorf("redeemed = %v, want %s", invites.redeemed, token.User.UUID.String)
+	}
+
+	// existing user logs in without invite
+	if _, err = a.CheckCode(ctx, &request.CheckCodeRequest{Phone: phone.String, Code: 3455}); err != nil {
+		t.Errorf("CheckCode() of existing user error = %v", err)
+	}
+	if len(invites.redeemed) != 1 {
+		t.Errorf("redeemed = %v", invites.redeemed)
+	}
+}
+
+func TestCheckCode_UpgradeGuest(t *testing.T) {
+	a, users := newTestService(t)
+	a.Config().SMSC.Dev = true
+
+	if _, err := a.GuestLogin(context.Background(), &request.GuestLoginRequest{DeviceID: "short"}); err == nil {
+		t.Error("GuestLogin() without device id error is nil")
+	}
+	guestToken, err := a.GuestLogin(context.Background(), &request.GuestLoginRequest{DeviceID: "device-id"})
+	if err != nil {
+		t.Fatalf("GuestLogin() error = %v", err)
+	}
+	guest, err := a.JWTKeys().ExtractRefreshToken(guestToken.RefreshToken)
+	if err != nil || guest.Device == "" {
+		t.Fatalf("guest refresh token = %+v, %v", guest, err)
+	}
+	// refresh of guest marks it active
+	if _, err = a.RefreshToken(context.Background(), &request.RefreshTokenRequest{RefreshToken: guestToken.RefreshToken}); err == nil {
+		t.Error("RefreshToken() of guest without device id error is nil")
+	}
+	guestToken, err = a.RefreshToken(context.Background(), &request.RefreshTokenRequest{RefreshToken: guestToken.RefreshToken, DeviceID: "device-id"})
+	if err != nil {
+		t.Fatalf("RefreshToken() of guest error = %v", err)
+	}
+	if len(users.Touched()) != 1 || users.Touched()[0] != guestToken.User.UUID.String {
+		t.Errorf("touched = %v", users.Touched())
+	}
+	// request authorized by guest access token, as set by token.Check
+	ctx := context.WithValue(context.Background(), types.User{}, &light.User{
+		UUID: guestToken.User.UUID,
+		Role: types.NewNullInt64(types.RoleGuest),
+	})
+
+	token, err := a.CheckCode(ctx, &request.CheckCodeRequest{Phone: "79644288083", Code: 3455})
+	if err != nil {
+		t.Fatalf("CheckCode() error = %v", err)
+	}
+	if token.User.UUID.String != guestToken.User.UUID.String {
+		t.Errorf("upgraded uuid = %s, want guest uuid %s", token.User.UUID.String, guestToken.User.UUID.String)
+	}
+	u, err := users.Find(ctx, light.User{UUID: guestToken.User.UUID})
+	if err != nil || u.IsGuest() || u.Phone.String != "79644288083" {
+		t.Errorf("upgraded user = %+v, %v", u, err)
+	}
+	if users.Len() != 1 {
+		t.Errorf("users = %d, guest must not be duplicated", users.Len())
+	}
+}
+
+func TestCheckCode_UpgradeGuestInvite(t *testing.T) {
+	a, users := newTestService(t)
+	a.Config().SMSC.Dev = true
+	invites := a.invites.(*testInvites)
+	invites.required = true
+	guest := users.Add(light.User{UUID: types.NewNullUUID(), Role: types.NewNullInt64(types.RoleGuest)})
+	ctx := context.WithValue(context.Background(), types.User{}, &light.User{UUID: guest.UUID, Role: guest.Role})
+
+	if _, err := a.CheckCode(ctx, &request.CheckCodeRequest{Phone: "79644288083", Code: 3455}); err == nil {
+		t.Fatal("CheckCode() of guest without invite error is nil")
+	}
+	if u, _ := users.Find(ctx, guest); !u.IsGuest() {
+		t.Fatal("guest is upgraded without invite")
+	}
+
+	_, err := a.CheckCode(ctx, &request.CheckCodeRequest{Phone: "79644288083", Code: 3455, InviteCode: testInviteCode})
+	if err != nil {
+		t.Fatalf("CheckCode() error = %v", err)
+	}
+	if len(invites.redeemed) != 1 || invites.redeemed[0] != guest.UUID.String {
+		t.Errorf("redeemed = %v, want %s", invites.redeemed, guest.UUID.String)
+	}
+}
+
+func TestCheckCode_Restore(t *testing.T) {
+	a, users := newTestService(t)
+	ctx := context.Background()
+	a.Config().SMSC.Dev = true
+	u := users.Add(light.User{
+		UUID:      types.NewNullUUID(),
+		Phone:     types.NewNullString("79644288083"),
+		DeletedAt: types.NullTime{Time: null.TimeFrom(time.Now().Add(-time.Hour))},
+	})
+	a.JWTKeys().RevokeSessions(u.UUID.String)
+
+	token, err := a.CheckCode(ctx, &request.CheckCodeRequest{Phone: "79644288083", Code: 3455})
+	if err != nil {
+		t.Fatalf("CheckCode() of deleted account error = %v", err)
+	}
+	if token.User.UUID.String != u.UUID.String || len(users.Restored()) != 1 {
+		t.Fatalf("CheckCode() uuid = %s, restored = %v", token.User.UUID.String, users.Restored())
+	}
+	if found, _ := users.Find(ctx, u); found.DeletedAt.Valid {
+		t.Error("user is still deleted")
+	}
+	// tokens issued on restore are not affected by revocation on deletion
+	if _, err = a.JWTKeys().ExtractRefreshToken(token.RefreshToken); err != nil {
+		t.Errorf("refresh token issued on restore error = %v", err)
+	}
+}
+
+func TestEmailActivation_AntiEnumeration(t *testing.T) {
+	a, users := newTestService(t)
+	mailer := a.Componenter.(*componentstest.Components).Mailer()
+	ctx := context.Background()
+	users.Add(light.User{UUID: types.NewNullUUID(), Email: types.NewNullString("user@example.com")})
+
+	req := &request.EmailActivationRequest{Email: "user@example.com", Password: "secret"}
+	if err := a.EmailActivation(ctx, req); err == nil {
+		t.Fatal("EmailActivation() of registered email without anti-enumeration error is nil")
+	}
+
+	a.Config().Security.AntiEnumeration = true
+	// registered and new emails get the same response, each address is emailed
+	for _, address := range []string{"user@example.com", "new@example.com"} {
+		if err := a.EmailActivation(ctx, &request.EmailActivationRequest{Email: address, Password: "secret"}); err != nil {
+			t.Errorf("EmailActivation(%s) error = %v", address, err)
+		}
+	}
+	sent := mailer.WaitSent(2)
+	if len(sent) != 2 {
+		t.Fatalf("sent = %d messages, want 2", len(sent))
+	}
+	subjects := make(map[string]string)
+	for _, msg := range sent {
+		subjects[msg.GetReceiver()] = msg.GetSubject()
+	}
+	// owner is told about registration attempt instead of activation link
+	if subjects["user@example.com"] == subjects["new@example.com"] {
+		t.Errorf("subjects = %v, registration attempt and activation must differ", subjects)
+	}
+}
+
+type testSMS struct {
+	err  error
+	sent []string
+}
+
+func (s *testSMS) Send(ctx context.Context, phone, msg string) error {
+	s.sent = append(s.sent, phone)
+
+	return s.err
+}
+
+func TestSendCode_Channel(t *testing.T) {
+	var telegramSent int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		telegramSent++
+		_, _ = w.Write([]byte(`{"ok":true,"result":{}}`))
+	}))
+	defer server.Close()
+
+	const phone = "79644288083"
+	tests := []struct {
+		name     string
+		channels []string
+		chosen   string
+		linked   bool
+		smsErr   error
+		want     string
+		sms      int
+		telegram int
+	}{
+		{name: "sms", channels: []string{config.PhoneChannelSMS, config.PhoneChannelTelegram}, want: config.PhoneChannelSMS, sms: 1},
+		{name: "sms failed", channels: []string{config.PhoneChannelSMS, config.PhoneChannelTelegram}, linked: true, smsErr: errors.New("operator unavailable"), want: config.PhoneChannelTelegram, sms: 1, telegram: 1},
+		{name: "telegram", channels: []string{config.PhoneChannelTelegram, config.PhoneChannelSMS}, linked: true, want: config.PhoneChannelTelegram, telegram: 1},
+		// fallback to sms is masked, phone of account without telegram looks the same
+		{name: "telegram not linked", channels: []string{config.PhoneChannelTelegram, config.PhoneChannelSMS}, want: config.PhoneChannelTelegram, sms: 1},
+		{name: "sms chosen", channels: []string{config.PhoneChannelTelegram, config.PhoneChannelSMS}, chosen: config.PhoneChannelSMS, linked: true, want: config.PhoneChannelSMS, sms: 1},
+	}
+	for _, tt := range tests {
+		a, users := newTestService(t)
+		a.Componenter.(*componentstest.Components).SetTelegram(providers.NewTelegram(&config.Telegram{Token: "token", URL: server.URL}))
+		a.Config().Phone.CodeChannels = tt.channels
+		sms := &testSMS{err: tt.smsErr}
+		a.smsProvider = sms
+		if tt.linked {
+			users.Add(light.User{UUID: types.NewNullUUID(), Phone: types.NewNullString(phone), TelegramID: types.NewNullInt64(1)})
+		}
+		telegramSent = 0
+
+		data, err := a.SendCode(context.Background(), &request.PhoneCodeRequest{Phone: phone, Channel: tt.chosen})
+		if err != nil {
+			t.Errorf("SendCode() %s error = %v", tt.name, err)
+			continue
+		}
+		if data.Channel != tt.want {
+			t.Errorf("SendCode() %s channel = %s, want %s", tt.name, data.Channel, tt.want)
+		}
+		if len(sms.sent) != tt.sms || telegramSent != tt.telegram {
+			t.Errorf("SendCode() %s sent sms %d, telegram %d", tt.name, len(sms.sent), telegramSent)
+		}
+	}
+}
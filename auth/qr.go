@@ -0,0 +1,172 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	light "github.com/ptflp/go-light"
+	"github.com/ptflp/go-light/hasher"
+	"github.com/ptflp/go-light/request"
+	"github.com/ptflp/go-light/types"
+	"go.uber.org/zap"
+)
+
+const (
+	QRLoginKey = "qr:login:%s"
+
+	QRStatusPending  = "pending"
+	QRStatusApproved = "approved"
+	QRStatusExpired  = "expired"
+
+	qrLoginTTL      = 2 * time.Minute
+	qrLongPollLimit = 25 * time.Second
+	qrPollInterval  = 500 * time.Millisecond
+)
+
+type qrChallenge struct {
+	PollHash  string `json:"poll_hash"`
+	Status    string `json:"status"`
+	UserUUID  string `json:"user_uuid"`
+	ExpiresAt int64  `json:"expires_at"`
+}
+
+// QRChallenge creates login challenge for web client, poll token is known to requester only
+func (a *service) QRChallenge(ctx context.Context) (request.QRChallengeData, error) {
+	_ = ctx
+	challengeID, err := randomHex(16)
+	if err != nil {
+		return request.QRChallengeData{}, err
+	}
+	pollToken, err := randomHex(32)
+	if err != nil {
+		return request.QRChallengeData{}, err
+	}
+
+	expiresAt := time.Now().Add(qrLoginTTL)
+	challenge := qrChallenge{
+		PollHash:  hasher.NewSHA256([]byte(pollToken)),
+		Status:    QRStatusPending,
+		ExpiresAt: expiresAt.Unix(),
+	}
+	a.Cache().Set(fmt.Sprintf(QRLoginKey, challengeID), &challenge, qrLoginTTL)
+
+	uri, err := url.Parse(a.Config().App.FrontEnd)
+	if err != nil {
+		return request.QRChallengeData{}, err
+	}
+	uri.Path = fmt.Sprintf("qr/%s", challengeID)
+
+	return request.QRChallengeData{
+		ChallengeID: challengeID,
+		PollToken:   pollToken,
+		Payload:     uri.String(),
+		ExpiresAt:   expiresAt,
+	}, nil
+}
+
+// QRStatus returns challenge status, waits for approval up to long poll limit when requested.
+// Tokens are issued once, challenge is removed after approved status is read
+func (a *service) QRStatus(ctx context.Context, req request.QRStatusRequest) (request.QRStatusData, error) {
+	deadline := time.Now()
+	if req.Wait {
+		deadline = deadline.Add(qrLongPollLimit)
+	}
+
+	for {
+		data, done, err := a.qrPoll(ctx, req)
+		if err != nil || done || !time.Now().Before(deadline) {
+			return data, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return data, nil
+		case <-time.After(qrPollInterval):
+		}
+	}
+}
+
+func (a *service) qrPoll(ctx context.Context, req request.QRStatusRequest) (request.QRStatusData, bool, error) {
+	a.qrMu.Lock()
+	defer a.qrMu.Unlock()
+
+	key := fmt.Sprintf(QRLoginKey, req.ChallengeID)
+	var challenge qrChallenge
+	err := a.Cache().Get(key, &challenge)
+	if err != nil || time.Now().Unix() > challenge.ExpiresAt {
+		return request.QRStatusData{Status: QRStatusExpired}, true, nil
+	}
+	if challenge.PollHash != hasher.NewSHA256([]byte(req.PollToken)) {
+		return request.QRStatusData{}, true, errors.New("wrong poll token")
+	}
+	if challenge.Status != QRStatusApproved {
+		return request.QRStatusData{Status: challenge.Status}, false, nil
+	}
+
+	err = a.Cache().Del(key)
+	if err != nil {
+		return request.QRStatusData{}, true, err
+	}
+
+	u, err := a.userRepository.Find(ctx, light.User{UUID: types.NewNullUUID(challenge.UserUUID)})
+	if err != nil {
+		return request.QRStatusData{}, true, err
+	}
+	if u.DeletedAt.Valid {
+		return request.QRStatusData{}, true, errors.New("user deleted")
+	}
+
+	authTokens, err := a.JWTKeys().GenerateAuthTokens(&u)
+	if err != nil {
+		return request.QRStatusData{}, true, err
+	}
+
+	return request.QRStatusData{Status: QRStatusApproved, Auth: authTokens}, true, nil
+}
+
+// QRApprove confirms challenge by authenticated user from another device
+func (a *service) QRApprove(ctx context.Context, req request.QRApproveRequest) error {
+	u, ok := ctx.Value(types.User{}).(*light.User)
+	if !ok || !u.UUID.Valid {
+		return errors.New("type assertion to user err")
+	}
+	if u.Impersonated() {
+		return light.ErrImpersonated
+	}
+
+	a.qrMu.Lock()
+	defer a.qrMu.Unlock()
+
+	key := fmt.Sprintf(QRLoginKey, req.ChallengeID)
+	var challenge qrChallenge
+	err := a.Cache().Get(key, &challenge)
+	ttl := time.Until(time.Unix(challenge.ExpiresAt, 0))
+	if err != nil || ttl <= 0 {
+		return errors.New("login challenge expired")
+	}
+	if challenge.Status != QRStatusPending {
+		return errors.New("login challenge already approved")
+	}
+
+	challenge.Status = QRStatusApproved
+	challenge.UserUUID = u.UUID.String
+	a.Cache().Set(key, &challenge, ttl)
+	a.Logger().Info("qr login approved", zap.String("uuid", u.UUID.String))
+
+	return nil
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
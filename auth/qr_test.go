@@ -0,0 +1,83 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	light "github.com/ptflp/go-light"
+	"github.com/ptflp/go-light/request"
+	"github.com/ptflp/go-light/types"
+)
+
+func TestQRLogin(t *testing.T) {
+	a, users := newTestService(t)
+	u := users.Add(light.User{UUID: types.NewNullUUID(), Role: types.NewNullInt64(types.RoleUser)})
+	ctx := context.Background()
+
+	challenge, err := a.QRChallenge(ctx)
+	if err != nil {
+		t.Fatalf("QRChallenge() error = %v", err)
+	}
+	statusReq := request.QRStatusRequest{ChallengeID: challenge.ChallengeID, PollToken: challenge.PollToken}
+
+	status, err := a.QRStatus(ctx, statusReq)
+	if err != nil || status.Status != QRStatusPending || status.Auth != nil {
+		t.Fatalf("QRStatus() before approval = %+v, %v", status, err)
+	}
+	if _, err = a.QRStatus(ctx, request.QRStatusRequest{ChallengeID: challenge.ChallengeID, PollToken: "wrong"}); err == nil {
+		t.Error("QRStatus() with wrong poll token error is nil")
+	}
+
+	approveReq := request.QRApproveRequest{ChallengeID: challenge.ChallengeID}
+	impersonated := u
+	impersonated.Actor = types.NewNullUUID()
+	if err = a.QRApprove(context.WithValue(ctx, types.User{}, &impersonated), approveReq); err == nil {
+		t.Fatal("QRApprove() with impersonation token error is nil")
+	}
+	if err = a.QRApprove(ctx, approveReq); err == nil {
+		t.Fatal("QRApprove() without user error is nil")
+	}
+
+	userCtx := context.WithValue(ctx, types.User{}, &u)
+	if err = a.QRApprove(userCtx, approveReq); err != nil {
+		t.Fatalf("QRApprove() error = %v", err)
+	}
+	if err = a.QRApprove(userCtx, approveReq); err == nil {
+		t.Error("second QRApprove() error is nil")
+	}
+
+	status, err = a.QRStatus(ctx, statusReq)
+	if err != nil || status.Status != QRStatusApproved || status.Auth == nil {
+		t.Fatalf("QRStatus() after approval = %+v, %v", status, err)
+	}
+	if status.Auth.User.UUID.String != u.UUID.String {
+		t.Errorf("QRStatus() uuid = %s, want %s", status.Auth.User.UUID.String, u.UUID.String)
+	}
+	// tokens are issued once
+	status, err = a.QRStatus(ctx, statusReq)
+	if err != nil || status.Status != QRStatusExpired || status.Auth != nil {
+		t.Errorf("second QRStatus() = %+v, %v", status, err)
+	}
+}
+
+func TestQRStatus_Wait(t *testing.T) {
+	a, users := newTestService(t)
+	u := users.Add(light.User{UUID: types.NewNullUUID(), Role: types.NewNullInt64(types.RoleUser)})
+
+	challenge, err := a.QRChallenge(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		time.Sleep(qrPollInterval)
+		_ = a.QRApprove(context.WithValue(context.Background(), types.User{}, &u), request.QRApproveRequest{ChallengeID: challenge.ChallengeID})
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	status, err := a.QRStatus(ctx, request.QRStatusRequest{ChallengeID: challenge.ChallengeID, PollToken: challenge.PollToken, Wait: true})
+	if err != nil || status.Status != QRStatusApproved || status.Auth == nil {
+		t.Errorf("QRStatus() long poll = %+v, %v", status, err)
+	}
+}
@@ -13,4 +13,6 @@ type Cache interface {
 	Get(key string, ptrValue interface{}) error
 	Set(key string, ptrValue interface{}, expires time.Duration)
 	Del(key string) error
+	// Incr atomically increments counter, expiration is set when counter is created
+	Incr(key string, expires time.Duration) (int64, error)
 }
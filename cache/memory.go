@@ -0,0 +1,108 @@
+package cache
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// memorySweepInterval how often expired keys are removed on write
+const memorySweepInterval = time.Minute
+
+type memoryItem struct {
+	value     []byte
+	expiresAt time.Time
+}
+
+func (i memoryItem) expired(now time.Time) bool {
+	return !i.expiresAt.IsZero() && now.After(i.expiresAt)
+}
+
+// Memory in-process cache, values are stored json encoded so callers never share memory
+type Memory struct {
+	mu      sync.Mutex
+	items   map[string]memoryItem
+	sweptAt time.Time
+}
+
+func NewMemory() *Memory {
+	return &Memory{items: make(map[string]memoryItem), sweptAt: time.Now()}
+}
+
+func (m *Memory) Get(key string, ptrValue interface{}) error {
+	m.mu.Lock()
+	item, ok := m.items[key]
+	m.mu.Unlock()
+	if !ok || item.expired(time.Now()) {
+		return ErrCacheMiss
+	}
+
+	return json.Unmarshal(item.value, ptrValue)
+}
+
+// Set zero expires keeps value until it is deleted, negative expires removes value
+func (m *Memory) Set(key string, ptrValue interface{}, expires time.Duration) {
+	value, err := json.Marshal(ptrValue)
+	if err != nil {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.set(key, value, expires)
+}
+
+func (m *Memory) Del(key string) error {
+	m.mu.Lock()
+	delete(m.items, key)
+	m.mu.Unlock()
+
+	return nil
+}
+
+// Incr atomically increments counter and returns its new value, expiration is set by first increment
+func (m *Memory) Incr(key string, expires time.Duration) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var n int64
+	item, ok := m.items[key]
+	if ok && !item.expired(time.Now()) {
+		if err := json.Unmarshal(item.value, &n); err != nil {
+			return 0, err
+		}
+	} else {
+		item = memoryItem{}
+		if expires > 0 {
+			item.expiresAt = time.Now().Add(expires)
+		}
+	}
+	n++
+	item.value, _ = json.Marshal(n)
+	m.items[key] = item
+
+	return n, nil
+}
+
+func (m *Memory) set(key string, value []byte, expires time.Duration) {
+	if expires < 0 {
+		delete(m.items, key)
+		return
+	}
+	now := time.Now()
+	item := memoryItem{value: value}
+	if expires > 0 {
+		item.expiresAt = now.Add(expires)
+	}
+	m.items[key] = item
+
+	if now.Sub(m.sweptAt) < memorySweepInterval {
+		return
+	}
+	m.sweptAt = now
+	for k, v := range m.items {
+		if v.expired(now) {
+			delete(m.items, k)
+		}
+	}
+}
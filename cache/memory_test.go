@@ -0,0 +1,58 @@
+package cache
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMemory(t *testing.T) {
+	m := NewMemory()
+	value := map[string]int{"a": 1}
+	m.Set("key", &value, time.Minute)
+	// stored value is a copy
+	value["a"] = 2
+
+	var got map[string]int
+	if err := m.Get("key", &got); err != nil || got["a"] != 1 {
+		t.Fatalf("Get() = %v, %v", got, err)
+	}
+	if err := m.Del("key"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Get("key", &got); err != ErrCacheMiss {
+		t.Errorf("Get() of deleted key error = %v", err)
+	}
+
+	m.Set("expired", &value, time.Millisecond)
+	time.Sleep(2 * time.Millisecond)
+	if err := m.Get("expired", &got); err != ErrCacheMiss {
+		t.Errorf("Get() of expired key error = %v", err)
+	}
+}
+
+func TestMemory_Incr(t *testing.T) {
+	m := NewMemory()
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = m.Incr("counter", time.Minute)
+		}()
+	}
+	wg.Wait()
+
+	var n int64
+	if err := m.Get("counter", &n); err != nil || n != 50 {
+		t.Fatalf("counter = %d, %v", n, err)
+	}
+	if n, _ = m.Incr("counter", time.Minute); n != 51 {
+		t.Errorf("Incr() = %d, want 51", n)
+	}
+
+	m.Set("counter", &n, -time.Second)
+	if n, _ = m.Incr("counter", time.Minute); n != 1 {
+		t.Errorf("Incr() of removed counter = %d, want 1", n)
+	}
+}
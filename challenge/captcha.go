@@ -0,0 +1,76 @@
+package challenge
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ptflp/go-light/config"
+	"github.com/ptflp/go-light/request"
+)
+
+// Captcha verifies tokens with siteverify compatible api (hCaptcha, reCAPTCHA, Turnstile)
+type Captcha struct {
+	client *http.Client
+	cfg    *config.Challenge
+}
+
+type captchaResponse struct {
+	Success    bool     `json:"success"`
+	ErrorCodes []string `json:"error-codes"`
+}
+
+func NewCaptcha(cfg *config.Challenge) *Captcha {
+	return &Captcha{
+		client: &http.Client{Timeout: 10 * time.Second},
+		cfg:    cfg,
+	}
+}
+
+func (c *Captcha) Challenge(ctx context.Context) (request.ChallengeData, error) {
+	_ = ctx
+
+	return request.ChallengeData{
+		Type:    config.ChallengeCaptcha,
+		SiteKey: c.cfg.CaptchaSiteKey,
+	}, nil
+}
+
+func (c *Captcha) Verify(ctx context.Context, solution Solution) error {
+	if solution.Response == "" {
+		return ErrRequired
+	}
+	form := url.Values{}
+	form.Set("secret", c.cfg.CaptchaSecret)
+	form.Set("response", solution.Response)
+	if solution.RemoteIP != "" {
+		form.Set("remoteip", solution.RemoteIP)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.cfg.CaptchaURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var res captchaResponse
+	err = json.NewDecoder(resp.Body).Decode(&res)
+	if err != nil {
+		return err
+	}
+	if !res.Success {
+		return fmt.Errorf("%w: %s", ErrInvalid, strings.Join(res.ErrorCodes, ","))
+	}
+
+	return nil
+}
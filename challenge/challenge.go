@@ -0,0 +1,30 @@
+package challenge
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ptflp/go-light/request"
+)
+
+var (
+	ErrRequired = errors.New("challenge required")
+	ErrInvalid  = errors.New("challenge solution invalid")
+	ErrExpired  = errors.New("challenge expired")
+	ErrSpent    = errors.New("challenge already used")
+)
+
+// Solution challenge response sent by client
+type Solution struct {
+	// Challenge issued challenge, empty for external captcha
+	Challenge string
+	// Response nonce of proof-of-work or captcha token
+	Response string
+	RemoteIP string
+}
+
+// Verifier issues challenges and verifies their solutions
+type Verifier interface {
+	Challenge(ctx context.Context) (request.ChallengeData, error)
+	Verify(ctx context.Context, solution Solution) error
+}
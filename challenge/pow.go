@@ -0,0 +1,192 @@
+package challenge
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ptflp/go-light/cache"
+	"github.com/ptflp/go-light/config"
+	"github.com/ptflp/go-light/request"
+)
+
+const (
+	SpentKey = "challenge:spent:%s"
+
+	defaultDifficulty    = 18
+	defaultMaxDifficulty = 24
+	loadWindow           = time.Minute
+)
+
+// PoW stateless proof-of-work, challenge carries its expiration and difficulty signed with hmac.
+// Client has to find nonce so that sha256(challenge + nonce) starts with difficulty zero bits
+type PoW struct {
+	secret        []byte
+	difficulty    int
+	maxDifficulty int
+	threshold     int
+	ttl           time.Duration
+	// spent protects from solution replay, store is local to instance as solutions are verified by instance
+	// which receives request, solution replayed on another instance is accepted once there
+	spent cache.Cache
+
+	mu          sync.Mutex
+	windowStart time.Time
+	current     int
+	previous    int
+}
+
+func NewPoW(cfg *config.Challenge) (*PoW, error) {
+	secret := []byte(cfg.Secret)
+	if len(secret) == 0 {
+		// challenges issued by other instances or before restart are not accepted
+		secret = make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, err
+		}
+	}
+	p := &PoW{
+		secret:        secret,
+		difficulty:    cfg.Difficulty,
+		maxDifficulty: cfg.MaxDifficulty,
+		threshold:     cfg.LoadThreshold,
+		ttl:           cfg.Lifetime(),
+		spent:         cache.NewMemory(),
+	}
+	if p.difficulty <= 0 {
+		p.difficulty = defaultDifficulty
+	}
+	if p.maxDifficulty < p.difficulty {
+		p.maxDifficulty = defaultMaxDifficulty
+	}
+	if p.maxDifficulty < p.difficulty {
+		p.maxDifficulty = p.difficulty
+	}
+
+	return p, nil
+}
+
+func (p *PoW) Challenge(ctx context.Context) (request.ChallengeData, error) {
+	_ = ctx
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return request.ChallengeData{}, err
+	}
+	difficulty := p.currentDifficulty(false)
+	expiresAt := time.Now().Add(p.ttl)
+
+	payload := strings.Join([]string{
+		strconv.FormatInt(expiresAt.Unix(), 10),
+		strconv.Itoa(difficulty),
+		hex.EncodeToString(nonce),
+	}, ".")
+	challenge := payload + "." + p.sign(payload)
+
+	return request.ChallengeData{
+		Type:       config.ChallengePoW,
+		Challenge:  challenge,
+		Difficulty: difficulty,
+		ExpiresAt:  expiresAt,
+	}, nil
+}
+
+func (p *PoW) Verify(ctx context.Context, solution Solution) error {
+	_ = ctx
+	if solution.Challenge == "" || solution.Response == "" {
+		return ErrRequired
+	}
+	parts := strings.Split(solution.Challenge, ".")
+	if len(parts) != 4 {
+		return ErrInvalid
+	}
+	payload := strings.Join(parts[:3], ".")
+	if !hmac.Equal([]byte(parts[3]), []byte(p.sign(payload))) {
+		return ErrInvalid
+	}
+	expiresAt, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || time.Now().Unix() > expiresAt {
+		return ErrExpired
+	}
+	difficulty, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return ErrInvalid
+	}
+	// load is measured by submitted solutions, issuing challenges is free and doesn't raise difficulty
+	p.currentDifficulty(true)
+
+	sum := sha256.Sum256([]byte(solution.Challenge + solution.Response))
+	if leadingZeroBits(sum[:]) < difficulty {
+		return ErrInvalid
+	}
+
+	// challenge is spent by first valid solution, key lives until challenge expires
+	used, err := p.spent.Incr(fmt.Sprintf(SpentKey, parts[2]), time.Until(time.Unix(expiresAt+1, 0)))
+	if err != nil {
+		return err
+	}
+	if used > 1 {
+		return ErrSpent
+	}
+
+	return nil
+}
+
+func (p *PoW) sign(payload string) string {
+	mac := hmac.New(sha256.New, p.secret)
+	mac.Write([]byte(payload))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// currentDifficulty raises difficulty by one bit every time rate of submitted solutions doubles over threshold,
+// solution is counted when submitted is true
+func (p *PoW) currentDifficulty(submitted bool) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	switch elapsed := now.Sub(p.windowStart); {
+	case elapsed >= 2*loadWindow:
+		p.windowStart, p.previous, p.current = now, 0, 0
+	case elapsed >= loadWindow:
+		p.windowStart, p.previous, p.current = p.windowStart.Add(loadWindow), p.current, 0
+	}
+	if submitted {
+		p.current++
+	}
+
+	if p.threshold <= 0 {
+		return p.difficulty
+	}
+	// sliding window estimate of solutions submitted during last minute
+	weight := 1 - float64(now.Sub(p.windowStart))/float64(loadWindow)
+	rate := int(float64(p.previous)*weight) + p.current
+
+	difficulty := p.difficulty
+	for load := rate / p.threshold; load > 1 && difficulty < p.maxDifficulty; load >>= 1 {
+		difficulty++
+	}
+
+	return difficulty
+}
+
+func leadingZeroBits(b []byte) int {
+	var n int
+	for i := range b {
+		if b[i] != 0 {
+			return n + bits.LeadingZeros8(b[i])
+		}
+		n += 8
+	}
+
+	return n
+}
@@ -0,0 +1,173 @@
+package challenge
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ptflp/go-light/config"
+)
+
+func solve(t *testing.T, challenge string, difficulty int) string {
+	t.Helper()
+	for nonce := 0; nonce < 1<<24; nonce++ {
+		response := strconv.Itoa(nonce)
+		sum := sha256.Sum256([]byte(challenge + response))
+		if leadingZeroBits(sum[:]) >= difficulty {
+			return response
+		}
+	}
+	t.Fatal("solution is not found")
+
+	return ""
+}
+
+// unsolve response failing difficulty, fixed wrong response passes low difficulty by chance
+func unsolve(t *testing.T, challenge string, difficulty int) string {
+	t.Helper()
+	for nonce := 0; nonce < 1<<24; nonce++ {
+		response := strconv.Itoa(nonce)
+		sum := sha256.Sum256([]byte(challenge + response))
+		if leadingZeroBits(sum[:]) < difficulty {
+			return response
+		}
+	}
+	t.Fatal("wrong response is not found")
+
+	return ""
+}
+
+func newTestPoW(t *testing.T, cfg config.Challenge) *PoW {
+	t.Helper()
+	p, err := NewPoW(&cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return p
+}
+
+func TestLeadingZeroBits(t *testing.T) {
+	tests := []struct {
+		in   []byte
+		want int
+	}{
+		{[]byte{0x80}, 0},
+		{[]byte{0x01}, 7},
+		{[]byte{0x00, 0x10}, 11},
+		{[]byte{0x00, 0x00}, 16},
+	}
+	for _, tt := range tests {
+		if got := leadingZeroBits(tt.in); got != tt.want {
+			t.Errorf("leadingZeroBits(%x) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestPoW_Verify(t *testing.T) {
+	p := newTestPoW(t, config.Challenge{Secret: "secret", Difficulty: 8})
+	ctx := context.Background()
+
+	data, err := p.Challenge(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data.Difficulty != 8 {
+		t.Fatalf("difficulty = %d", data.Difficulty)
+	}
+	response := solve(t, data.Challenge, data.Difficulty)
+
+	if err = p.Verify(ctx, Solution{}); !errors.Is(err, ErrRequired) {
+		t.Errorf("Verify() of empty solution error = %v", err)
+	}
+	// difficulty is signed, lowered difficulty is rejected
+	parts := strings.Split(data.Challenge, ".")
+	parts[1] = "0"
+	if err = p.Verify(ctx, Solution{Challenge: strings.Join(parts, "."), Response: response}); !errors.Is(err, ErrInvalid) {
+		t.Errorf("Verify() of tampered challenge error = %v", err)
+	}
+	// challenge of another secret is rejected
+	other := newTestPoW(t, config.Challenge{Secret: "other", Difficulty: 8})
+	if err = other.Verify(ctx, Solution{Challenge: data.Challenge, Response: response}); !errors.Is(err, ErrInvalid) {
+		t.Errorf("Verify() with another secret error = %v", err)
+	}
+
+	if err = p.Verify(ctx, Solution{Challenge: data.Challenge, Response: response}); err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if err = p.Verify(ctx, Solution{Challenge: data.Challenge, Response: response}); !errors.Is(err, ErrSpent) {
+		t.Errorf("Verify() of replayed solution error = %v", err)
+	}
+}
+
+func TestPoW_Expired(t *testing.T) {
+	p := newTestPoW(t, config.Challenge{Secret: "secret", Difficulty: 1})
+	payload := strings.Join([]string{strconv.FormatInt(time.Now().Add(-time.Second).Unix(), 10), "1", "00"}, ".")
+	challenge := payload + "." + p.sign(payload)
+
+	err := p.Verify(context.Background(), Solution{Challenge: challenge, Response: solve(t, challenge, 1)})
+	if !errors.Is(err, ErrExpired) {
+		t.Errorf("Verify() of expired challenge error = %v", err)
+	}
+}
+
+func TestPoW_AdaptiveDifficulty(t *testing.T) {
+	p := newTestPoW(t, config.Challenge{Secret: "secret", Difficulty: 1, MaxDifficulty: 3, LoadThreshold: 10})
+	ctx := context.Background()
+
+	// issued challenges are not counted
+	for i := 0; i < 100; i++ {
+		if _, err := p.Challenge(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if d := p.currentDifficulty(false); d != 1 {
+		t.Fatalf("difficulty after issuing challenges = %d, want 1", d)
+	}
+
+	// failed and verified solutions are counted, difficulty is not raised until rate doubles threshold
+	data, err := p.Challenge(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrong := unsolve(t, data.Challenge, data.Difficulty)
+	for i := 0; i < 19; i++ {
+		if err = p.Verify(ctx, Solution{Challenge: data.Challenge, Response: wrong}); !errors.Is(err, ErrInvalid) {
+			t.Fatalf("Verify() of wrong solution error = %v", err)
+		}
+	}
+	if d := p.currentDifficulty(false); d != 1 {
+		t.Fatalf("difficulty below 2x threshold = %d", d)
+	}
+	if err = p.Verify(ctx, Solution{Challenge: data.Challenge, Response: solve(t, data.Challenge, data.Difficulty)}); err != nil {
+		t.Fatal(err)
+	}
+	if d := p.currentDifficulty(false); d != 2 {
+		t.Errorf("difficulty at 2x threshold = %d, want 2", d)
+	}
+	for i := 0; i < 20; i++ {
+		p.currentDifficulty(true)
+	}
+	if d := p.currentDifficulty(false); d != 3 {
+		t.Errorf("difficulty at 4x threshold = %d, want 3", d)
+	}
+	if data, err = p.Challenge(ctx); err != nil || data.Difficulty != 3 {
+		t.Errorf("Challenge() under load = %+v, %v", data, err)
+	}
+	for i := 0; i < 200; i++ {
+		p.currentDifficulty(true)
+	}
+	if d := p.currentDifficulty(false); d != 3 {
+		t.Errorf("difficulty is not capped by max, got %d", d)
+	}
+
+	// load of previous window is forgotten after two windows
+	p.windowStart = time.Now().Add(-2 * loadWindow)
+	if d := p.currentDifficulty(false); d != 1 {
+		t.Errorf("difficulty after idle = %d, want 1", d)
+	}
+}
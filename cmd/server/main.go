@@ -46,6 +46,11 @@ func main() {
 
 	repositories := db.NewRepositories(cmps)
 
+	cmps.StartOutbox(ctx, repositories.Outbox)
+	cmps.UseSuppression(repositories.Suppressions)
+	cmps.StartSMSTracking(ctx, repositories.SMS)
+	cmps.StartTelegram(ctx)
+
 	service := services.NewServices(ctx, cmps, repositories)
 
 	// router initialization
@@ -71,5 +76,6 @@ func main() {
 
 	<-ctx.Done()
 
+	_ = cmps.Email().Close()
 	logger.Info("server stopped")
 }
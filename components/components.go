@@ -1,13 +1,19 @@
 package components
 
 import (
+	"context"
+	"net/url"
+
+	light "github.com/ptflp/go-light"
 	"github.com/ptflp/go-light/cache"
+	"github.com/ptflp/go-light/challenge"
 	"github.com/ptflp/go-light/config"
 	"github.com/ptflp/go-light/decoder"
 	"github.com/ptflp/go-light/email"
 	"github.com/ptflp/go-light/providers"
 	"github.com/ptflp/go-light/respond"
 	"github.com/ptflp/go-light/session"
+	"github.com/ptflp/go-light/validators"
 	"go.uber.org/zap"
 )
 
@@ -17,12 +23,22 @@ type Componenter interface {
 	LogLevel() zap.AtomicLevel
 	JWTKeys() *session.JWTKeys
 	Email() email.Mailer
+	Templates() *email.Templates
+	Catcher() *email.Catcher
 	Config() *config.Config
 	Cache() cache.Cache
 	SMS() providers.SMS
+	SMSGateway() *providers.SMSGateway
+	SMSTracker() *providers.SMSTracker
+	Voice() providers.Voice
+	Telegram() *providers.Telegram
+	Push() *providers.WebPush
 	Decoder() *decoder.Decoder
 	Facebook() providers.Socials
 	Google() providers.Socials
+	Challenge() challenge.Verifier
+	EmailValidator() *validators.EmailValidator
+	PhoneValidator() *validators.PhoneValidator
 }
 
 type Components struct {
@@ -30,13 +46,23 @@ type Components struct {
 	responder respond.Responder
 	logLevel  zap.AtomicLevel
 	jwtKeys   *session.JWTKeys
-	email     *email.Client
+	email     email.Mailer
+	transport email.Transport
+	catcher   *email.Catcher
+	templates *email.Templates
 	config    *config.Config
 	cache     cache.Cache
-	sms       providers.SMS
+	sms       *providers.SMSGateway
+	tracker   *providers.SMSTracker
+	voice     providers.Voice
+	telegram  *providers.Telegram
+	push      *providers.WebPush
 	decoder   *decoder.Decoder
 	facebook  providers.Socials
 	google    providers.Socials
+	challenge challenge.Verifier
+	validator *validators.EmailValidator
+	phones    *validators.PhoneValidator
 }
 
 func (c *Components) Logger() *zap.Logger {
@@ -59,6 +85,15 @@ func (c *Components) Email() email.Mailer {
 	return c.email
 }
 
+func (c *Components) Templates() *email.Templates {
+	return c.templates
+}
+
+// Catcher dev mail catcher, nil when smtp backend is used
+func (c *Components) Catcher() *email.Catcher {
+	return c.catcher
+}
+
 func (c *Components) Config() *config.Config {
 	return c.config
 }
@@ -71,6 +106,27 @@ func (c *Components) SMS() providers.SMS {
 	return c.sms
 }
 
+func (c *Components) SMSGateway() *providers.SMSGateway {
+	return c.sms
+}
+
+// SMSTracker delivery status tracking, nil before StartSMSTracking
+func (c *Components) SMSTracker() *providers.SMSTracker {
+	return c.tracker
+}
+
+func (c *Components) Voice() providers.Voice {
+	return c.voice
+}
+
+func (c *Components) Telegram() *providers.Telegram {
+	return c.telegram
+}
+
+func (c *Components) Push() *providers.WebPush {
+	return c.push
+}
+
 func (c *Components) Decoder() *decoder.Decoder {
 	return c.decoder
 }
@@ -83,6 +139,62 @@ func (c *Components) Google() providers.Socials {
 	return c.google
 }
 
+func (c *Components) Challenge() challenge.Verifier {
+	return c.challenge
+}
+
+func (c *Components) EmailValidator() *validators.EmailValidator {
+	return c.validator
+}
+
+func (c *Components) PhoneValidator() *validators.PhoneValidator {
+	return c.phones
+}
+
+// StartOutbox switches Email to persistent outbox when enabled in config and starts delivery worker
+func (c *Components) StartOutbox(ctx context.Context, repo light.OutboxRepository) {
+	if !c.config.Email.Outbox {
+		return
+	}
+	outbox := email.NewOutbox(c.transport, repo, &c.config.Email, c.logger)
+	c.email = outbox
+	go outbox.Run(ctx)
+}
+
+// StartSMSTracking stores sent sms and starts delivery status poller
+func (c *Components) StartSMSTracking(ctx context.Context, repo light.SMSRepository) {
+	c.tracker = providers.NewSMSTracker(c.sms, repo, &c.config.SMS, c.logger)
+	go c.tracker.Run(ctx)
+}
+
+// StartTelegram registers webhook of telegram bot when bot is configured
+func (c *Components) StartTelegram(ctx context.Context) {
+	if !c.telegram.Enabled() {
+		return
+	}
+	if c.config.Telegram.WebhookSecret == "" {
+		c.logger.Warn("telegram webhook secret is not set, updates are rejected")
+		return
+	}
+	webhook, err := url.Parse(c.config.App.APIURL())
+	if err != nil {
+		c.logger.Error("telegram webhook url", zap.Error(err))
+		return
+	}
+	webhook.Path = "telegram/webhook"
+	go func() {
+		err := c.telegram.SetWebhook(ctx, webhook.String(), c.config.Telegram.WebhookSecret)
+		if err != nil {
+			c.logger.Error("telegram webhook registration", zap.Error(err))
+		}
+	}()
+}
+
+// UseSuppression drops suppressed recipients from every message sent with Email
+func (c *Components) UseSuppression(repo light.SuppressionRepository) {
+	c.email = email.NewSuppressor(c.email, repo, c.logger)
+}
+
 func NewComponents(logger *zap.Logger) *Components {
 	responder, err := respond.NewResponder(logger)
 	if err != nil {
@@ -103,19 +215,98 @@ func NewComponents(logger *zap.Logger) *Components {
 	facebook := providers.NewFacebookAuth(&conf.Oauth2.Facebook)
 	google := providers.NewGoogleAuth(&conf.Oauth2.Google)
 
-	mailClient := email.NewClient(&conf.Email, logger)
+	var mailer email.Mailer
+	var transport email.Transport
+	var catcher *email.Catcher
+	switch conf.Email.BackendType() {
+	case config.EmailBackendCatcher:
+		catcher, err = email.NewCatcher(&conf.Email, logger)
+		if err != nil {
+			logger.Fatal("email catcher initialization error", zap.Error(err))
+		}
+		if !conf.App.Dev {
+			logger.Warn("email catcher backend is used outside of dev mode, emails are not delivered")
+		}
+		mailer, transport = catcher, catcher
+	default:
+		mailClient := email.NewClient(&conf.Email, logger)
+		if conf.Email.DKIMEnabled() {
+			signer, err := email.NewDKIMSigner(&conf.Email)
+			if err != nil {
+				logger.Fatal("dkim initialization error", zap.Error(err))
+			}
+			mailClient.UseDKIM(signer)
+		}
+		mailer, transport = mailClient, mailClient
+	}
+	templates, err := email.NewTemplates(conf.Email.TemplatesDir)
+	if err != nil {
+		logger.Fatal("email templates initialization error", zap.Error(err))
+	}
 	smsc := providers.NewSMSC(&conf.SMSC)
+	smsProviders := []providers.SMSProvider{smsc, providers.NewLogSMS(logger)}
+	for i := range conf.SMS.HTTP {
+		smsProviders = append(smsProviders, providers.NewHTTPSMS(&conf.SMS.HTTP[i]))
+	}
+	sms, err := providers.NewSMSGateway(&conf.SMS, logger, smsProviders...)
+	if err != nil {
+		logger.Fatal("sms gateway initialization error", zap.Error(err))
+	}
+	for _, channel := range conf.Phone.Channels() {
+		switch channel {
+		case config.PhoneChannelSMS, config.PhoneChannelFlash, config.PhoneChannelVoice, config.PhoneChannelTelegram:
+		default:
+			logger.Fatal("unknown phone code channel", zap.String("channel", channel))
+		}
+	}
+
+	subject := conf.Push.Subject
+	if subject == "" {
+		subject = conf.App.APIURL()
+	}
+	vapid, err := providers.LoadVAPID(conf.Push.VAPIDKeyFile(), subject)
+	if err != nil {
+		logger.Fatal("vapid key initialization error", zap.Error(err))
+	}
+
+	validator, err := validators.NewEmailValidator(&conf.Validation)
+	if err != nil {
+		logger.Fatal("email validator initialization error", zap.Error(err))
+	}
+
+	var verifier challenge.Verifier
+	switch conf.Challenge.Provider {
+	case config.ChallengePoW:
+		verifier, err = challenge.NewPoW(&conf.Challenge)
+		if err != nil {
+			logger.Fatal("challenge initialization error", zap.Error(err))
+		}
+		if conf.Challenge.Secret == "" {
+			logger.Warn("challenge secret is not set, challenges are valid for current instance only")
+		}
+	case config.ChallengeCaptcha:
+		verifier = challenge.NewCaptcha(&conf.Challenge)
+	}
 
 	return &Components{
 		logger:    logger,
 		responder: responder,
 		logLevel:  zap.AtomicLevel{},
 		jwtKeys:   jwt,
-		email:     mailClient,
+		email:     mailer,
+		transport: transport,
+		catcher:   catcher,
+		templates: templates,
 		config:    conf,
-		sms:       smsc,
+		sms:       sms,
+		voice:     smsc,
+		telegram:  providers.NewTelegram(&conf.Telegram),
+		push:      providers.NewWebPush(vapid, conf.Push.MessageTTL()),
 		decoder:   decoder.NewDecoder(),
 		facebook:  facebook,
 		google:    google,
+		challenge: verifier,
+		validator: validator,
+		phones:    validators.NewPhoneValidator(&conf.Phone),
 	}
 }
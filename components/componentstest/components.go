@@ -0,0 +1,149 @@
+// Package componentstest in-memory components and repositories shared by service tests
+package componentstest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ptflp/go-light/cache"
+	"github.com/ptflp/go-light/components"
+	"github.com/ptflp/go-light/config"
+	"github.com/ptflp/go-light/email"
+	"github.com/ptflp/go-light/providers"
+	"github.com/ptflp/go-light/session"
+	"github.com/ptflp/go-light/validators"
+	"go.uber.org/zap"
+)
+
+// Components implements components.Componenter with memory cache and test keys,
+// components which are not set panic on use
+type Components struct {
+	components.Componenter
+	cache     cache.Cache
+	jwt       *session.JWTKeys
+	config    *config.Config
+	emails    *validators.EmailValidator
+	phones    *validators.PhoneValidator
+	templates *email.Templates
+	mailer    *Mailer
+	telegram  *providers.Telegram
+}
+
+// New components configured by conf, empty config is used when conf is nil
+func New(t *testing.T, conf *config.Config) *Components {
+	t.Helper()
+	if conf == nil {
+		conf = &config.Config{}
+	}
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	emails, err := validators.NewEmailValidator(&conf.Validation)
+	if err != nil {
+		t.Fatal(err)
+	}
+	templates, err := email.NewTemplates("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := cache.NewMemory()
+
+	return &Components{
+		cache:     store,
+		jwt:       session.NewJWTKeysWithKey(zap.NewNop(), store, key),
+		config:    conf,
+		emails:    emails,
+		phones:    validators.NewPhoneValidator(&conf.Phone),
+		templates: templates,
+		mailer:    &Mailer{},
+	}
+}
+
+// SetTelegram telegram bot, e.g. backed by httptest server
+func (c *Components) SetTelegram(telegram *providers.Telegram) {
+	c.telegram = telegram
+}
+
+func (c *Components) Logger() *zap.Logger {
+	return zap.NewNop()
+}
+
+func (c *Components) Cache() cache.Cache {
+	return c.cache
+}
+
+func (c *Components) JWTKeys() *session.JWTKeys {
+	return c.jwt
+}
+
+func (c *Components) Config() *config.Config {
+	return c.config
+}
+
+func (c *Components) EmailValidator() *validators.EmailValidator {
+	return c.emails
+}
+
+func (c *Components) PhoneValidator() *validators.PhoneValidator {
+	return c.phones
+}
+
+func (c *Components) Templates() *email.Templates {
+	return c.templates
+}
+
+func (c *Components) Email() email.Mailer {
+	return c.mailer
+}
+
+// Mailer same as Email, typed to inspect sent messages
+func (c *Components) Mailer() *Mailer {
+	return c.mailer
+}
+
+func (c *Components) Telegram() *providers.Telegram {
+	return c.telegram
+}
+
+// Mailer keeps sent messages instead of delivering them
+type Mailer struct {
+	mu   sync.Mutex
+	sent []email.Messager
+}
+
+func (m *Mailer) Send(msg email.Messager) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, msg)
+
+	return nil
+}
+
+func (m *Mailer) Close() error {
+	return nil
+}
+
+// Sent messages in order of sending
+func (m *Mailer) Sent() []email.Messager {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]email.Messager(nil), m.sent...)
+}
+
+// WaitSent messages once n of them are sent in background, e.g. by components.Dispatch,
+// sent so far after a second
+func (m *Mailer) WaitSent(n int) []email.Messager {
+	deadline := time.Now().Add(time.Second)
+	for {
+		sent := m.Sent()
+		if len(sent) >= n || time.Now().After(deadline) {
+			return sent
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
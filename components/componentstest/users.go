@@ -0,0 +1,194 @@
+package componentstest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+
+	light "github.com/ptflp/go-light"
+	"github.com/ptflp/go-light/types"
+	"github.com/volatiletech/null/v8"
+)
+
+// Users user repository kept by uuid, lookups behave like queries of db.userRepository,
+// methods which are not implemented panic on use
+type Users struct {
+	light.UserRepository
+
+	mu                 sync.Mutex
+	users              map[string]light.User
+	restored           []string
+	touched            []string
+	anonymized         []string
+	guestsPurgedBefore time.Time
+}
+
+func (r *Users) Add(u light.User) light.User {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.users == nil {
+		r.users = make(map[string]light.User)
+	}
+	r.users[u.UUID.String] = u
+
+	return u
+}
+
+// Len users kept, deleted included
+func (r *Users) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return len(r.users)
+}
+
+// Restored uuids of restored users in order of calls
+func (r *Users) Restored() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]string(nil), r.restored...)
+}
+
+// Touched uuids of users marked active in order of calls
+func (r *Users) Touched() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]string(nil), r.touched...)
+}
+
+// Anonymized uuids of anonymized users in order of calls
+func (r *Users) Anonymized() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]string(nil), r.anonymized...)
+}
+
+// GuestsPurgedBefore inactivity time of last guests purge
+func (r *Users) GuestsPurgedBefore() time.Time {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.guestsPurgedBefore
+}
+
+func (r *Users) find(match func(u light.User) bool) (light.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, u := range r.users {
+		if match(u) {
+			return u, nil
+		}
+	}
+
+	return light.User{}, sql.ErrNoRows
+}
+
+func (r *Users) Find(ctx context.Context, user light.User) (light.User, error) {
+	return r.find(func(u light.User) bool { return u.UUID.String == user.UUID.String })
+}
+
+func (r *Users) FindByEmail(ctx context.Context, user light.User) (light.User, error) {
+	return r.find(func(u light.User) bool { return u.Email.Valid && u.Email.String == user.Email.String })
+}
+
+func (r *Users) FindByPhone(ctx context.Context, user light.User) (light.User, error) {
+	return r.find(func(u light.User) bool { return u.Phone.Valid && u.Phone.String == user.Phone.String })
+}
+
+func (r *Users) FindByNickname(ctx context.Context, user light.User) (light.User, error) {
+	return r.find(func(u light.User) bool {
+		return u.NickName.Valid && u.NickName.String == user.NickName.String && !u.DeletedAt.Valid
+	})
+}
+
+func (r *Users) FindByNicknameWithDeleted(ctx context.Context, user light.User) (light.User, error) {
+	return r.find(func(u light.User) bool { return u.NickName.Valid && u.NickName.String == user.NickName.String })
+}
+
+func (r *Users) FindDeleted(ctx context.Context, before time.Time) ([]light.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var users []light.User
+	for _, u := range r.users {
+		if u.DeletedAt.Valid && u.DeletedAt.Time.Time.Before(before) {
+			users = append(users, u)
+		}
+	}
+
+	return users, nil
+}
+
+func (r *Users) CreateUser(ctx context.Context, user light.User) error {
+	r.Add(user)
+
+	return nil
+}
+
+func (r *Users) UpgradeGuest(ctx context.Context, user light.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	u, ok := r.users[user.UUID.String]
+	if !ok || !u.IsGuest() {
+		return errors.New("guest not found")
+	}
+	u.Role = types.NewNullInt64(types.RoleUser)
+	if user.Phone.Valid {
+		u.Phone = user.Phone
+	}
+	if user.Email.Valid {
+		u.Email = user.Email
+	}
+	r.users[user.UUID.String] = u
+
+	return nil
+}
+
+func (r *Users) Touch(ctx context.Context, user light.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.touched = append(r.touched, user.UUID.String)
+
+	return nil
+}
+
+func (r *Users) Delete(ctx context.Context, user light.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	u := r.users[user.UUID.String]
+	u.DeletedAt = types.NullTime{Time: null.TimeFrom(time.Now())}
+	r.users[user.UUID.String] = u
+
+	return nil
+}
+
+func (r *Users) Restore(ctx context.Context, user light.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	u := r.users[user.UUID.String]
+	u.DeletedAt = types.NullTime{}
+	r.users[user.UUID.String] = u
+	r.restored = append(r.restored, user.UUID.String)
+
+	return nil
+}
+
+func (r *Users) Anonymize(ctx context.Context, user light.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.anonymized = append(r.anonymized, user.UUID.String)
+
+	return nil
+}
+
+func (r *Users) PurgeGuests(ctx context.Context, before time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.guestsPurgedBefore = before
+
+	return 0, nil
+}
@@ -0,0 +1,19 @@
+package components
+
+import "go.uber.org/zap"
+
+// Dispatch runs send in background in anti-enumeration mode, so response time does not reveal the branch taken.
+// Error of background send is logged only
+func Dispatch(c Componenter, send func() error) error {
+	if !c.Config().Security.AntiEnumeration {
+		return send()
+	}
+	go func() {
+		err := send()
+		if err != nil {
+			c.Logger().Error("dispatch", zap.Error(err))
+		}
+	}()
+
+	return nil
+}
@@ -0,0 +1,40 @@
+package components_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ptflp/go-light/components"
+	"github.com/ptflp/go-light/components/componentstest"
+)
+
+func TestDispatch(t *testing.T) {
+	cmps := componentstest.New(t, nil)
+	failed := errors.New("send failed")
+
+	// send error is returned to caller unless anti-enumeration is enabled
+	if err := components.Dispatch(cmps, func() error { return failed }); !errors.Is(err, failed) {
+		t.Errorf("Dispatch() error = %v, want %v", err, failed)
+	}
+
+	cmps.Config().Security.AntiEnumeration = true
+	sent := make(chan struct{})
+	release := make(chan struct{})
+	err := components.Dispatch(cmps, func() error {
+		<-release
+		close(sent)
+
+		return failed
+	})
+	// caller does not wait for send
+	if err != nil {
+		t.Errorf("Dispatch() in anti-enumeration mode error = %v", err)
+	}
+	close(release)
+	select {
+	case <-sent:
+	case <-time.After(time.Second):
+		t.Error("send is not run in background")
+	}
+}
@@ -1,6 +1,32 @@
 package config
 
+import "time"
+
+const defaultDeletionGracePeriod = 30
+
 type App struct {
 	Dev      bool
 	FrontEnd string
+	// API public url of api, used in links handled by api itself, FrontEnd is used when empty.
+	// Unsubscribe links of emails are not added without it
+	API string
+	// DeletionGracePeriod days before a deleted account is anonymized
+	DeletionGracePeriod int
+}
+
+func (a App) DeletionGrace() time.Duration {
+	days := a.DeletionGracePeriod
+	if days <= 0 {
+		days = defaultDeletionGracePeriod
+	}
+
+	return time.Duration(days) * 24 * time.Hour
+}
+
+func (a App) APIURL() string {
+	if a.API == "" {
+		return a.FrontEnd
+	}
+
+	return a.API
 }
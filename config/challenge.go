@@ -0,0 +1,37 @@
+package config
+
+import "time"
+
+const (
+	ChallengePoW     = "pow"
+	ChallengeCaptcha = "captcha"
+)
+
+type Challenge struct {
+	// Provider pow or captcha, empty disables challenge
+	Provider string
+	// Routes paths protected by challenge
+	Routes []string
+	// Secret hmac key of pow challenges, must be shared between instances
+	Secret string `json:"-"`
+	// Difficulty leading zero bits of pow solution
+	Difficulty int
+	// MaxDifficulty upper bound of difficulty under load
+	MaxDifficulty int
+	// LoadThreshold submitted solutions per minute before difficulty is raised
+	LoadThreshold int
+	// TTL challenge lifetime in seconds
+	TTL int
+	// CaptchaURL siteverify endpoint of external captcha
+	CaptchaURL     string
+	CaptchaSiteKey string
+	CaptchaSecret  string `json:"-"`
+}
+
+func (c Challenge) Lifetime() time.Duration {
+	if c.TTL <= 0 {
+		return 5 * time.Minute
+	}
+
+	return time.Duration(c.TTL) * time.Second
+}
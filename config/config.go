@@ -8,12 +8,21 @@ import (
 )
 
 type Config struct {
-	App    App
-	DB     DB
-	Server Server
-	Redis  Redis
-	SMSC   SMSC
-	Email  Email
+	App        App
+	DB         DB
+	Server     Server
+	Redis      Redis
+	SMSC       SMSC
+	SMS        SMS
+	Phone      Phone
+	Telegram   Telegram
+	Push       Push
+	Email      Email
+	Invites    Invites
+	OIDC       OIDC
+	Challenge  Challenge
+	Security   Security
+	Validation Validation
 	Oauth2
 }
 
@@ -1,9 +1,130 @@
 package config
 
+import "time"
+
+const (
+	EmailBackendSMTP    = "smtp"
+	EmailBackendCatcher = "catcher"
+)
+
+const (
+	EmailSecurityTLS      = "tls"
+	EmailSecurityStartTLS = "starttls"
+	EmailSecurityPlain    = "plain"
+)
+
 type Email struct {
+	// Backend smtp or catcher, catcher stores messages for dev inbox instead of sending
+	Backend string
+	// CatcherDir directory of .eml files of catcher, messages are kept in memory only when empty
+	CatcherDir    string
 	ServerAddress string
 	Port          string
 	Login         string `json:"-"`
 	Password      string `json:"-"`
 	From          string
+	// Sender envelope sender (bounce address), From is used when empty
+	Sender string
+	// Security tls (implicit), starttls or plain
+	Security string
+	// InsecureSkipVerify disables server certificate verification, for testing only
+	InsecureSkipVerify bool
+	// LocalName host name sent in EHLO
+	LocalName string
+	// AuthMechanism forces auth mechanism, negotiated with server when empty
+	AuthMechanism string
+	// PoolSize maximum number of open smtp connections
+	PoolSize int
+	// IdleTimeout seconds before idle connection is closed
+	IdleTimeout int
+	// DKIMDomain signing domain (d=), mail is signed when domain, selector and key are set
+	DKIMDomain string
+	// DKIMSelector selector (s=) of public key DNS record
+	DKIMSelector string
+	// DKIMKeyPath PEM encoded RSA or Ed25519 private key
+	DKIMKeyPath string `json:"-"`
+	// TemplatesDir directory with email templates replacing embedded defaults
+	TemplatesDir string
+	// UnsubscribeSecret hmac key of unsubscribe links, must be shared between instances
+	UnsubscribeSecret string `json:"-"`
+	// BounceSecret value of X-Bounce-Secret header required by bounce reports endpoint, endpoint is disabled when empty
+	BounceSecret string `json:"-"`
+	// Outbox enqueues messages into persistent outbox delivered by background worker
+	Outbox bool
+	// OutboxAttempts delivery attempts before message is dead-lettered
+	OutboxAttempts int
+	// OutboxBackoff seconds before first retry, doubled on every next attempt
+	OutboxBackoff int
+	// OutboxRetention days sent and dead-lettered messages are kept
+	OutboxRetention int
+}
+
+func (e Email) BackendType() string {
+	if e.Backend == "" {
+		return EmailBackendSMTP
+	}
+
+	return e.Backend
+}
+
+func (e Email) SecurityMode() string {
+	if e.Security == "" {
+		return EmailSecurityTLS
+	}
+
+	return e.Security
+}
+
+func (e Email) Connections() int {
+	if e.PoolSize <= 0 {
+		return 2
+	}
+
+	return e.PoolSize
+}
+
+func (e Email) IdleLifetime() time.Duration {
+	if e.IdleTimeout <= 0 {
+		return time.Minute
+	}
+
+	return time.Duration(e.IdleTimeout) * time.Second
+}
+
+func (e Email) MaxAttempts() int {
+	if e.OutboxAttempts <= 0 {
+		return 8
+	}
+
+	return e.OutboxAttempts
+}
+
+func (e Email) Retention() time.Duration {
+	if e.OutboxRetention <= 0 {
+		return 30 * 24 * time.Hour
+	}
+
+	return time.Duration(e.OutboxRetention) * 24 * time.Hour
+}
+
+// RetryDelay exponential backoff before next delivery attempt, capped to 6 hours
+func (e Email) RetryDelay(attempt int) time.Duration {
+	base := 30 * time.Second
+	if e.OutboxBackoff > 0 {
+		base = time.Duration(e.OutboxBackoff) * time.Second
+	}
+	maxDelay := 6 * time.Hour
+	delay := base
+	for i := 1; i < attempt && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+
+	return delay
+}
+
+func (e Email) DKIMEnabled() bool {
+	return e.DKIMDomain != "" && e.DKIMSelector != "" && e.DKIMKeyPath != ""
 }
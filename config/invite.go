@@ -0,0 +1,10 @@
+package config
+
+type Invites struct {
+	// Required enables invite-only registration
+	Required bool
+	// Quota invites available to regular user
+	Quota int
+	// TTL invite lifetime in hours
+	TTL int
+}
@@ -0,0 +1,42 @@
+package config
+
+import "time"
+
+type OIDC struct {
+	// Issuer public url of api
+	Issuer string
+	// ConsentURL frontend page rendering consent screen
+	ConsentURL string
+	// CodeTTL authorization code lifetime in seconds
+	CodeTTL int
+	// AccessTokenTTL access and id token lifetime in seconds
+	AccessTokenTTL int
+	// RefreshTokenTTL refresh token lifetime in hours
+	RefreshTokenTTL int
+	// RedirectSchemes custom uri schemes of native apps accepted in redirect uris, e.g. com.example.app
+	RedirectSchemes []string
+}
+
+func (o OIDC) CodeLifetime() time.Duration {
+	if o.CodeTTL <= 0 {
+		return time.Minute
+	}
+
+	return time.Duration(o.CodeTTL) * time.Second
+}
+
+func (o OIDC) AccessTokenLifetime() time.Duration {
+	if o.AccessTokenTTL <= 0 {
+		return time.Hour
+	}
+
+	return time.Duration(o.AccessTokenTTL) * time.Second
+}
+
+func (o OIDC) RefreshTokenLifetime() time.Duration {
+	if o.RefreshTokenTTL <= 0 {
+		return 30 * 24 * time.Hour
+	}
+
+	return time.Duration(o.RefreshTokenTTL) * time.Hour
+}
@@ -0,0 +1,45 @@
+package config
+
+import "strings"
+
+const defaultPhoneRegion = "RU"
+
+// verification code channels
+const (
+	PhoneChannelSMS = "sms"
+	// PhoneChannelFlash code is last digits of caller number
+	PhoneChannelFlash = "flash"
+	// PhoneChannelVoice code is dictated by call
+	PhoneChannelVoice = "voice"
+	// PhoneChannelTelegram code is sent to telegram chat linked to account with phone
+	PhoneChannelTelegram = "telegram"
+)
+
+type Phone struct {
+	// DefaultRegion region of numbers entered without international prefix
+	DefaultRegion string
+	// RegionHeader request header with ISO country code of client (e.g. CF-IPCountry), overrides DefaultRegion
+	RegionHeader string
+	// AllowedCountries only numbers of these countries are accepted when not empty
+	AllowedCountries []string
+	// DeniedCountries numbers of these countries are rejected
+	DeniedCountries []string
+	// CodeChannels verification code channels in fallback order, first one is used when client doesn't choose
+	CodeChannels []string
+}
+
+func (p Phone) Region() string {
+	if p.DefaultRegion == "" {
+		return defaultPhoneRegion
+	}
+
+	return strings.ToUpper(p.DefaultRegion)
+}
+
+func (p Phone) Channels() []string {
+	if len(p.CodeChannels) == 0 {
+		return []string{PhoneChannelSMS}
+	}
+
+	return p.CodeChannels
+}
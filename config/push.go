@@ -0,0 +1,47 @@
+package config
+
+import "time"
+
+type Push struct {
+	// KeyFile PEM encoded P-256 private key of VAPID, generated when file doesn't exist.
+	// Key must not change, browsers bind subscriptions to it
+	KeyFile string
+	// Subject contact of application server, mailto: or https: url, api url when empty
+	Subject string
+	// TTL seconds push service keeps message for offline device
+	TTL int
+	// Hosts push services endpoints of subscriptions may point to, subdomains are allowed
+	Hosts []string
+}
+
+// defaultPushHosts push services of Chrome, Firefox, Edge and Safari
+var defaultPushHosts = []string{
+	"fcm.googleapis.com",
+	"push.services.mozilla.com",
+	"notify.windows.com",
+	"push.apple.com",
+}
+
+func (p Push) PushHosts() []string {
+	if len(p.Hosts) == 0 {
+		return defaultPushHosts
+	}
+
+	return p.Hosts
+}
+
+func (p Push) VAPIDKeyFile() string {
+	if p.KeyFile == "" {
+		return "./keys/vapid"
+	}
+
+	return p.KeyFile
+}
+
+func (p Push) MessageTTL() time.Duration {
+	if p.TTL <= 0 {
+		return 24 * time.Hour
+	}
+
+	return time.Duration(p.TTL) * time.Second
+}
@@ -0,0 +1,38 @@
+package config
+
+import "time"
+
+type Security struct {
+	// AntiEnumeration hides account existence in existence, recovery and registration endpoints
+	AntiEnumeration bool
+	// MinResponseTime milliseconds, responses of protected endpoints are padded to this duration
+	MinResponseTime int
+	// ExistenceLimit existence checks per minute for authenticated user
+	ExistenceLimit int
+	// GuestLimit guest accounts created per hour from one ip
+	GuestLimit int
+}
+
+func (s Security) ResponseTime() time.Duration {
+	if s.MinResponseTime <= 0 {
+		return 500 * time.Millisecond
+	}
+
+	return time.Duration(s.MinResponseTime) * time.Millisecond
+}
+
+func (s Security) ExistenceRate() int {
+	if s.ExistenceLimit <= 0 {
+		return 10
+	}
+
+	return s.ExistenceLimit
+}
+
+func (s Security) GuestRate() int {
+	if s.GuestLimit <= 0 {
+		return 10
+	}
+
+	return s.GuestLimit
+}
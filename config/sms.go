@@ -0,0 +1,78 @@
+package config
+
+import "time"
+
+const (
+	SMSProviderSMSC = "smsc"
+	SMSProviderLog  = "log"
+)
+
+type SMS struct {
+	// Providers failover order, smsc, log or name of http provider, smsc only when empty
+	Providers []string
+	// HTTP generic http providers
+	HTTP []SMSHTTP
+	// Routes per-country provider order, first route matching country of phone is used
+	Routes []SMSRoute
+	// FailureThreshold consecutive failures before provider is skipped
+	FailureThreshold int
+	// Cooldown seconds before skipped provider is tried again
+	Cooldown int
+	// StatusInterval seconds between delivery status polls
+	StatusInterval int
+}
+
+type SMSRoute struct {
+	// Countries ISO 3166 region codes, e.g. RU, KZ
+	Countries []string
+	Providers []string
+}
+
+// SMSHTTP provider sending messages with single http request,
+// {phone} and {message} placeholders of URL and Body are replaced with escaped values
+type SMSHTTP struct {
+	Name string
+	URL  string `json:"-"`
+	// Method GET or POST
+	Method string
+	Body   string `json:"-"`
+	// ContentType of body, values are json escaped for application/json and url escaped for form
+	ContentType string
+	Headers     map[string]string `json:"-"`
+	// IDField field of json response with message id
+	IDField string
+	// Cost price of single message
+	Cost float64
+}
+
+func (s SMS) ProviderOrder() []string {
+	if len(s.Providers) == 0 {
+		return []string{SMSProviderSMSC}
+	}
+
+	return s.Providers
+}
+
+func (s SMS) Threshold() int {
+	if s.FailureThreshold <= 0 {
+		return 3
+	}
+
+	return s.FailureThreshold
+}
+
+func (s SMS) CooldownPeriod() time.Duration {
+	if s.Cooldown <= 0 {
+		return time.Minute
+	}
+
+	return time.Duration(s.Cooldown) * time.Second
+}
+
+func (s SMS) StatusPoll() time.Duration {
+	if s.StatusInterval <= 0 {
+		return time.Minute
+	}
+
+	return time.Duration(s.StatusInterval) * time.Second
+}
@@ -3,7 +3,19 @@ package config
 type SMSC struct {
 	Pwd   string `json:"-"`
 	Login string `json:"-"`
-	Cost  string
-	Fmt   string
-	Dev   bool
+	// Cost 3 returns price of message in send response
+	Cost string
+	// Fmt is ignored, responses are always requested in json
+	Fmt string
+	Dev bool
+	// URL api address, https://smsc.ru when empty
+	URL string
+}
+
+func (s SMSC) BaseURL() string {
+	if s.URL == "" {
+		return "https://smsc.ru"
+	}
+
+	return s.URL
 }
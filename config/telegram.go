@@ -0,0 +1,24 @@
+package config
+
+type Telegram struct {
+	// Token bot token, telegram integration is disabled when empty
+	Token string `json:"-"`
+	// Bot username of bot used in deep links
+	Bot string
+	// URL bot api address, https://api.telegram.org when empty
+	URL string
+	// WebhookSecret sent by telegram in X-Telegram-Bot-Api-Secret-Token header
+	WebhookSecret string `json:"-"`
+}
+
+func (t Telegram) BaseURL() string {
+	if t.URL == "" {
+		return "https://api.telegram.org"
+	}
+
+	return t.URL
+}
+
+func (t Telegram) Enabled() bool {
+	return t.Token != ""
+}
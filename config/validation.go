@@ -0,0 +1,21 @@
+package config
+
+import "time"
+
+type Validation struct {
+	// DisposableDomains blocklist file of disposable email domains, embedded list is used when empty
+	DisposableDomains string
+	// DisposableReload seconds between checks of blocklist file modification
+	DisposableReload int
+	// CanonicalGmail strips dots and +tag of gmail addresses, so aliases of one mailbox can't register twice,
+	// addresses stored before it's enabled are not canonicalized
+	CanonicalGmail bool
+}
+
+func (v Validation) ReloadInterval() time.Duration {
+	if v.DisposableReload <= 0 {
+		return time.Minute
+	}
+
+	return time.Duration(v.DisposableReload) * time.Second
+}
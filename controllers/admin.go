@@ -0,0 +1,145 @@
+package controllers
+
+import (
+	"net"
+	"net/http"
+
+	"github.com/ptflp/go-light/decoder"
+	"github.com/ptflp/go-light/request"
+	"github.com/ptflp/go-light/respond"
+	"github.com/ptflp/go-light/services"
+	"go.uber.org/zap"
+)
+
+type adminController struct {
+	*decoder.Decoder
+	respond.Responder
+	admin  *services.Admin
+	logger *zap.Logger
+}
+
+func NewAdminController(responder respond.Responder, admin *services.Admin, logger *zap.Logger) *adminController {
+	return &adminController{
+		Decoder:   decoder.NewDecoder(),
+		Responder: responder,
+		admin:     admin,
+		logger:    logger,
+	}
+}
+
+func (a *adminController) Impersonate() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		var impersonateReq request.ImpersonateReq
+
+		err := a.Decode(r.Body, &impersonateReq)
+		if err != nil {
+			a.ErrorBadRequest(w, err)
+			return
+		}
+
+		ip, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			ip = r.RemoteAddr
+		}
+
+		impersonateData, err := a.admin.Impersonate(r.Context(), impersonateReq, ip)
+		if err != nil {
+			a.ErrorForbidden(w, err)
+			return
+		}
+
+		a.SendJSON(w, request.Response{
+			Success: true,
+			Data:    impersonateData,
+		})
+	}
+}
+
+func (a *adminController) Audit() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		var auditReq request.AuditReq
+
+		err := a.Decode(r.Body, &auditReq)
+		if err != nil {
+			a.ErrorBadRequest(w, err)
+			return
+		}
+
+		eventsData, err := a.admin.Audit(r.Context(), auditReq)
+		if err != nil {
+			a.ErrorBadRequest(w, err)
+			return
+		}
+
+		a.SendJSON(w, request.Response{
+			Success: true,
+			Data: struct {
+				Events []request.AuditEventData `json:"events"`
+			}{
+				Events: eventsData,
+			},
+		})
+	}
+}
+
+func (a *adminController) Outbox() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		outboxData, err := a.admin.Outbox(r.Context())
+		if err != nil {
+			a.ErrorForbidden(w, err)
+			return
+		}
+
+		a.SendJSON(w, request.Response{
+			Success: true,
+			Data:    outboxData,
+		})
+	}
+}
+
+func (a *adminController) SMS() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		providersData, err := a.admin.SMS(r.Context())
+		if err != nil {
+			a.ErrorForbidden(w, err)
+			return
+		}
+
+		a.SendJSON(w, request.Response{
+			Success: true,
+			Data: struct {
+				Providers []request.SMSProviderData `json:"providers"`
+			}{
+				Providers: providersData,
+			},
+		})
+	}
+}
+
+func (a *adminController) Notify() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		var notifyReq request.NotifyReq
+
+		err := a.Decode(r.Body, &notifyReq)
+		if err != nil {
+			a.ErrorBadRequest(w, err)
+			return
+		}
+
+		err = a.admin.Notify(r.Context(), notifyReq)
+		if err != nil {
+			a.ErrorBadRequest(w, err)
+			return
+		}
+
+		a.SendJSON(w, request.Response{
+			Success: true,
+			Msg:     "notification sent",
+		})
+	}
+}
@@ -2,9 +2,11 @@ package controllers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 
+	"github.com/ptflp/go-light/auth"
 	"github.com/ptflp/go-light/request"
 
 	light "github.com/ptflp/go-light"
@@ -109,9 +111,12 @@ func (a *authController) EmailLogin() http.HandlerFunc {
 
 		token, err := a.authService.EmailLogin(r.Context(), &emailLoginReq)
 		if err != nil {
+			if !errors.Is(err, auth.ErrLogin) {
+				a.logger.Error("email login", zap.Error(err))
+			}
 			a.SendJSON(w, request.Response{
 				Success: false,
-				Msg:     fmt.Sprintf("email login err: %s", err),
+				Msg:     fmt.Sprintf("email login err: %s", auth.ErrLogin),
 				Data:    nil,
 			})
 			return
@@ -133,18 +138,19 @@ func (a *authController) SendCode() http.HandlerFunc {
 			a.ErrorBadRequest(w, err)
 			return
 		}
-		if a.authService.SendCode(r.Context(), &sendCodeReq) {
+		data, err := a.authService.SendCode(r.Context(), &sendCodeReq)
+		if err != nil {
 			a.SendJSON(w, request.Response{
-				Success: true,
-				Msg:     "СМС код оптравлен успешно",
+				Success: false,
+				Msg:     fmt.Sprintf("Ошибка отправки кода: %s", err),
 				Data:    nil,
 			})
 			return
 		}
 		a.SendJSON(w, request.Response{
-			Success: false,
-			Msg:     "Ошибка отправки кода",
-			Data:    nil,
+			Success: true,
+			Msg:     "Код отправлен успешно",
+			Data:    data,
 		})
 	}
 }
@@ -173,3 +179,116 @@ func (a *authController) CheckCode() http.HandlerFunc {
 		})
 	}
 }
+
+func (a *authController) GuestLogin() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var guestLoginReq request.GuestLoginRequest
+		err := json.NewDecoder(r.Body).Decode(&guestLoginReq)
+		if err != nil {
+			a.ErrorBadRequest(w, err)
+			return
+		}
+
+		token, err := a.authService.GuestLogin(r.Context(), &guestLoginReq)
+		if err != nil {
+			a.ErrorBadRequest(w, err)
+			return
+		}
+
+		a.SendJSON(w, request.AuthTokenResponse{
+			Success: true,
+			Msg:     "",
+			Data:    *token,
+		})
+	}
+}
+
+func (a *authController) QRChallenge() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		challengeData, err := a.authService.QRChallenge(r.Context())
+		if err != nil {
+			a.ErrorInternal(w, err)
+			return
+		}
+
+		a.SendJSON(w, request.Response{
+			Success: true,
+			Data:    challengeData,
+		})
+	}
+}
+
+func (a *authController) QRStatus() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var qrStatusReq request.QRStatusRequest
+		err := json.NewDecoder(r.Body).Decode(&qrStatusReq)
+		if err != nil {
+			a.ErrorBadRequest(w, err)
+			return
+		}
+
+		statusData, err := a.authService.QRStatus(r.Context(), qrStatusReq)
+		if err != nil {
+			a.ErrorForbidden(w, err)
+			return
+		}
+
+		a.SendJSON(w, request.Response{
+			Success: true,
+			Data:    statusData,
+		})
+	}
+}
+
+func (a *authController) QRApprove() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var qrApproveReq request.QRApproveRequest
+		err := json.NewDecoder(r.Body).Decode(&qrApproveReq)
+		if err != nil {
+			a.ErrorBadRequest(w, err)
+			return
+		}
+
+		err = a.authService.QRApprove(r.Context(), qrApproveReq)
+		if err != nil {
+			a.ErrorBadRequest(w, err)
+			return
+		}
+
+		a.SendJSON(w, request.Response{
+			Success: true,
+			Msg:     "Вход подтвержден",
+		})
+	}
+}
+
+func (a *authController) Login() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var loginReq request.LoginRequest
+		err := json.NewDecoder(r.Body).Decode(&loginReq)
+		if err != nil {
+			a.ErrorBadRequest(w, err)
+			return
+		}
+
+		token, err := a.authService.Login(r.Context(), &loginReq)
+		if err != nil {
+			// details of failure are logged only, response doesn't reveal whether account exists
+			if !errors.Is(err, auth.ErrLogin) {
+				a.logger.Error("login", zap.Error(err))
+			}
+			a.SendJSON(w, request.Response{
+				Success: false,
+				Msg:     fmt.Sprintf("login err: %s", auth.ErrLogin),
+				Data:    nil,
+			})
+			return
+		}
+
+		a.SendJSON(w, request.AuthTokenResponse{
+			Success: true,
+			Msg:     "",
+			Data:    *token,
+		})
+	}
+}
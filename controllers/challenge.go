@@ -0,0 +1,45 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/ptflp/go-light/challenge"
+	"github.com/ptflp/go-light/request"
+	"github.com/ptflp/go-light/respond"
+	"go.uber.org/zap"
+)
+
+type challengeController struct {
+	respond.Responder
+	verifier challenge.Verifier
+	logger   *zap.Logger
+}
+
+func NewChallengeController(responder respond.Responder, verifier challenge.Verifier, logger *zap.Logger) *challengeController {
+	return &challengeController{
+		Responder: responder,
+		verifier:  verifier,
+		logger:    logger,
+	}
+}
+
+func (c *challengeController) Challenge() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if c.verifier == nil {
+			c.ErrorBadRequest(w, errors.New("challenge disabled"))
+			return
+		}
+
+		challengeData, err := c.verifier.Challenge(r.Context())
+		if err != nil {
+			c.ErrorInternal(w, err)
+			return
+		}
+
+		c.SendJSON(w, request.Response{
+			Success: true,
+			Data:    challengeData,
+		})
+	}
+}
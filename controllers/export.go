@@ -0,0 +1,55 @@
+package controllers
+
+import (
+	"net/http"
+	"path/filepath"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/ptflp/go-light/request"
+	"github.com/ptflp/go-light/respond"
+	"github.com/ptflp/go-light/services"
+	"go.uber.org/zap"
+)
+
+type exportController struct {
+	respond.Responder
+	export *services.Export
+	logger *zap.Logger
+}
+
+func NewExportController(responder respond.Responder, export *services.Export, logger *zap.Logger) *exportController {
+	return &exportController{
+		Responder: responder,
+		export:    export,
+		logger:    logger,
+	}
+}
+
+func (e *exportController) Request() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		err := e.export.Request(r.Context())
+		if err != nil {
+			e.ErrorBadRequest(w, err)
+			return
+		}
+
+		e.SendJSON(w, request.Response{
+			Success: true,
+			Msg:     "Архив с данными будет отправлен на почту",
+		})
+	}
+}
+
+func (e *exportController) Download() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		path, err := e.export.Download(r.Context(), chi.URLParam(r, "exportID"))
+		if err != nil {
+			e.ErrorForbidden(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", "attachment; filename=\""+filepath.Base(path)+"\"")
+		http.ServeFile(w, r, path)
+	}
+}
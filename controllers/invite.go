@@ -0,0 +1,95 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/ptflp/go-light/decoder"
+	"github.com/ptflp/go-light/request"
+	"github.com/ptflp/go-light/respond"
+	"github.com/ptflp/go-light/services"
+	"go.uber.org/zap"
+)
+
+type invitesController struct {
+	*decoder.Decoder
+	respond.Responder
+	invite *services.Invite
+	logger *zap.Logger
+}
+
+func NewInvitesController(responder respond.Responder, invite *services.Invite, logger *zap.Logger) *invitesController {
+	return &invitesController{
+		Decoder:   decoder.NewDecoder(),
+		Responder: responder,
+		invite:    invite,
+		logger:    logger,
+	}
+}
+
+func (i *invitesController) Create() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		var inviteCreateReq request.InviteCreateReq
+
+		err := i.Decode(r.Body, &inviteCreateReq)
+		if err != nil {
+			i.ErrorBadRequest(w, err)
+			return
+		}
+
+		inviteData, err := i.invite.Create(r.Context(), inviteCreateReq)
+		if err != nil {
+			i.ErrorBadRequest(w, err)
+			return
+		}
+
+		i.SendJSON(w, request.Response{
+			Success: true,
+			Data: struct {
+				Invite request.InviteData `json:"invite"`
+			}{
+				Invite: inviteData,
+			},
+		})
+	}
+}
+
+func (i *invitesController) List() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		invitesData, err := i.invite.List(r.Context())
+		if err != nil {
+			i.ErrorBadRequest(w, err)
+			return
+		}
+
+		i.SendJSON(w, request.Response{
+			Success: true,
+			Data: struct {
+				Invites []request.InviteData `json:"invites"`
+			}{
+				Invites: invitesData,
+			},
+		})
+	}
+}
+
+func (i *invitesController) Referrals() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		referralsData, err := i.invite.Referrals(r.Context())
+		if err != nil {
+			i.ErrorBadRequest(w, err)
+			return
+		}
+
+		i.SendJSON(w, request.Response{
+			Success: true,
+			Data: struct {
+				Referrals []request.ReferralData `json:"referrals"`
+			}{
+				Referrals: referralsData,
+			},
+		})
+	}
+}
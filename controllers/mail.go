@@ -0,0 +1,240 @@
+package controllers
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/ptflp/go-light/email"
+	"github.com/ptflp/go-light/request"
+	"github.com/ptflp/go-light/respond"
+	"go.uber.org/zap"
+)
+
+const (
+	inboxTemplate = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="UTF-8">
+<title>Inbox</title>
+<style>
+body { font-family: Arial, Helvetica, sans-serif; margin: 24px; color: #18181b; }
+table { border-collapse: collapse; width: 100%; }
+td, th { text-align: left; padding: 6px 8px; border-bottom: 1px solid #e4e4e7; }
+a { color: #2563eb; }
+</style>
+</head>
+<body>
+<h1>Inbox ({{len .}})</h1>
+<form method="post" action="/dev/mail/clear"><button type="submit">Clear</button></form>
+<table>
+<tr><th>Date</th><th>To</th><th>Subject</th><th>Size</th></tr>
+{{range .}}<tr>
+<td>{{.Date.Format "02.01.2006 15:04:05"}}</td>
+<td>{{.To}}</td>
+<td><a href="/dev/mail/{{.ID}}">{{if .Subject}}{{.Subject}}{{else}}(no subject){{end}}</a></td>
+<td>{{.Size}}</td>
+</tr>{{end}}
+</table>
+</body>
+</html>`
+
+	messageTemplate = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="UTF-8">
+<title>{{.Message.Subject}}</title>
+<style>
+body { font-family: Arial, Helvetica, sans-serif; margin: 24px; color: #18181b; }
+dt { font-weight: bold; } dd { margin: 0 0 8px; }
+iframe { width: 100%; height: 600px; border: 1px solid #e4e4e7; }
+pre { white-space: pre-wrap; background: #f4f4f5; padding: 12px; }
+a { color: #2563eb; }
+</style>
+</head>
+<body>
+<p><a href="/dev/mail">&larr; Inbox</a> | <a href="/dev/mail/{{.Message.ID}}/raw">Download .eml</a></p>
+<dl>
+<dt>Subject</dt><dd>{{.Message.Subject}}</dd>
+<dt>From</dt><dd>{{.Message.From}}</dd>
+<dt>To</dt><dd>{{.Message.To}}</dd>
+<dt>Envelope</dt><dd>{{.Message.Sender}} &rarr; {{range $i, $r := .Message.Recipients}}{{if $i}}, {{end}}{{$r}}{{end}}</dd>
+<dt>Date</dt><dd>{{.Message.Date.Format "02.01.2006 15:04:05"}}</dd>
+</dl>
+{{if .Content.Attachments}}<h3>Attachments</h3>
+<ul>{{range $i, $a := .Content.Attachments}}
+<li><a href="/dev/mail/{{$.Message.ID}}/attachments/{{$i}}">{{if $a.FileName}}{{$a.FileName}}{{else}}attachment {{$i}}{{end}}</a> ({{$a.ContentType}}, {{$a.Size}} bytes)</li>{{end}}
+</ul>{{end}}
+{{if .Content.HTML}}<h3>HTML</h3>
+<iframe sandbox src="/dev/mail/{{.Message.ID}}/html"></iframe>{{end}}
+{{if .Content.Text}}<h3>Text</h3>
+<pre>{{.Content.Text}}</pre>{{end}}
+</body>
+</html>`
+)
+
+type mailController struct {
+	respond.Responder
+	catcher *email.Catcher
+	logger  *zap.Logger
+	inbox   *template.Template
+	message *template.Template
+}
+
+func NewMailController(responder respond.Responder, catcher *email.Catcher, logger *zap.Logger) *mailController {
+	return &mailController{
+		Responder: responder,
+		catcher:   catcher,
+		logger:    logger,
+		inbox:     template.Must(template.New("inbox").Parse(inboxTemplate)),
+		message:   template.Must(template.New("message").Parse(messageTemplate)),
+	}
+}
+
+func (m *mailController) Inbox() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := m.inbox.Execute(w, m.catcher.List()); err != nil {
+			m.logger.Error("inbox template", zap.Error(err))
+		}
+	}
+}
+
+func (m *mailController) List() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		m.SendJSON(w, request.Response{
+			Success: true,
+			Data: struct {
+				Messages []email.CaughtMessage `json:"messages"`
+			}{
+				Messages: m.catcher.List(),
+			},
+		})
+	}
+}
+
+func (m *mailController) Clear() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := m.catcher.Clear(); err != nil {
+			m.ErrorInternal(w, err)
+			return
+		}
+
+		http.Redirect(w, r, "/dev/mail", http.StatusSeeOther)
+	}
+}
+
+func (m *mailController) Message() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		msg, content, ok := m.content(w, r)
+		if !ok {
+			return
+		}
+
+		if r.URL.Query().Get("format") == "json" {
+			m.SendJSON(w, request.Response{
+				Success: true,
+				Data: struct {
+					email.CaughtMessage
+					email.CaughtContent
+				}{msg, content},
+			})
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		err := m.message.Execute(w, struct {
+			Message email.CaughtMessage
+			Content email.CaughtContent
+		}{msg, content})
+		if err != nil {
+			m.logger.Error("message template", zap.Error(err))
+		}
+	}
+}
+
+// HTML part is rendered sandboxed, inline images are linked to attachments
+func (m *mailController) HTML() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		msg, content, ok := m.content(w, r)
+		if !ok {
+			return
+		}
+
+		body := content.HTML
+		for i, a := range content.Attachments {
+			if a.ContentID != "" {
+				body = strings.ReplaceAll(body, "cid:"+a.ContentID, fmt.Sprintf("/dev/mail/%s/attachments/%d", msg.ID, i))
+			}
+		}
+		w.Header().Set("Content-Security-Policy", "sandbox")
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write([]byte(body))
+	}
+}
+
+func (m *mailController) Text() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		_, content, ok := m.content(w, r)
+		if !ok {
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, _ = w.Write([]byte(content.Text))
+	}
+}
+
+func (m *mailController) Raw() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := chi.URLParam(r, "messageID")
+		raw, err := m.catcher.Raw(id)
+		if err != nil {
+			m.ErrorBadRequest(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "message/rfc822")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", id+".eml"))
+		_, _ = w.Write(raw)
+	}
+}
+
+func (m *mailController) Attachment() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		_, content, ok := m.content(w, r)
+		if !ok {
+			return
+		}
+		i, err := strconv.Atoi(chi.URLParam(r, "index"))
+		if err != nil || i < 0 || i >= len(content.Attachments) {
+			m.ErrorBadRequest(w, fmt.Errorf("attachment not found"))
+			return
+		}
+
+		attachment := content.Attachments[i]
+		w.Header().Set("Content-Type", attachment.ContentType)
+		if attachment.FileName != "" {
+			w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", attachment.FileName))
+		}
+		_, _ = w.Write(attachment.Data)
+	}
+}
+
+func (m *mailController) content(w http.ResponseWriter, r *http.Request) (email.CaughtMessage, email.CaughtContent, bool) {
+	msg, err := m.catcher.Get(chi.URLParam(r, "messageID"))
+	if err != nil {
+		m.ErrorBadRequest(w, err)
+		return email.CaughtMessage{}, email.CaughtContent{}, false
+	}
+	content, err := m.catcher.Content(msg.ID)
+	if err != nil {
+		m.ErrorInternal(w, err)
+		return email.CaughtMessage{}, email.CaughtContent{}, false
+	}
+
+	return msg, content, true
+}
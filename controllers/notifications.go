@@ -0,0 +1,280 @@
+package controllers
+
+import (
+	"errors"
+	"html/template"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/ptflp/go-light/decoder"
+	"github.com/ptflp/go-light/providers"
+	"github.com/ptflp/go-light/request"
+	"github.com/ptflp/go-light/respond"
+	"github.com/ptflp/go-light/services"
+	"go.uber.org/zap"
+)
+
+const (
+	BounceSecretHeader   = "X-Bounce-Secret"
+	TelegramSecretHeader = "X-Telegram-Bot-Api-Secret-Token"
+
+	maxReportSize = 1 << 20
+
+	unsubscribeTemplate = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="UTF-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Unsubscribe</title>
+<style>
+body { font-family: Arial, Helvetica, sans-serif; margin: 48px auto; max-width: 480px; color: #18181b; text-align: center; }
+button { padding: 8px 16px; font-size: 16px; cursor: pointer; }
+</style>
+</head>
+<body>
+{{if .Error}}<p>Ссылка недействительна.<br>The link is invalid.</p>
+{{else if .Done}}<p>Вы отписались от рассылки.<br>You have been unsubscribed.</p>
+{{else}}<p>Отписаться от рассылки?<br>Unsubscribe from these emails?</p>
+<form method="post"><button type="submit">Отписаться / Unsubscribe</button></form>
+{{end}}
+</body>
+</html>`
+)
+
+type notificationsController struct {
+	*decoder.Decoder
+	respond.Responder
+	notifications *services.Notifications
+	logger        *zap.Logger
+	page          *template.Template
+}
+
+func NewNotificationsController(responder respond.Responder, notifications *services.Notifications, logger *zap.Logger) *notificationsController {
+	return &notificationsController{
+		Decoder:       decoder.NewDecoder(),
+		Responder:     responder,
+		notifications: notifications,
+		logger:        logger,
+		page:          template.Must(template.New("unsubscribe").Parse(unsubscribeTemplate)),
+	}
+}
+
+// UnsubscribePage asks for confirmation, link scanners of mail services must not unsubscribe user with GET
+func (n *notificationsController) UnsubscribePage() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		n.render(w, http.StatusOK, false, false)
+	}
+}
+
+// Unsubscribe one-click unsubscribe (RFC 8058) and confirmation form, html is returned to browsers
+func (n *notificationsController) Unsubscribe() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		err := n.notifications.Unsubscribe(r.Context(), r.URL.Query().Get("token"))
+		if strings.Contains(r.Header.Get("Accept"), "text/html") {
+			if err != nil {
+				n.logger.Info("unsubscribe", zap.Error(err))
+				n.render(w, http.StatusBadRequest, false, true)
+				return
+			}
+			n.render(w, http.StatusOK, true, false)
+			return
+		}
+		if err != nil {
+			n.ErrorBadRequest(w, err)
+			return
+		}
+
+		n.SendJSON(w, request.Response{
+			Success: true,
+			Msg:     "unsubscribed",
+		})
+	}
+}
+
+func (n *notificationsController) Preferences() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		preferences, err := n.notifications.Preferences(r.Context())
+		if err != nil {
+			n.ErrorInternal(w, err)
+			return
+		}
+
+		n.SendJSON(w, request.Response{
+			Success: true,
+			Data:    preferences,
+		})
+	}
+}
+
+func (n *notificationsController) SetEmailPreference() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		var preferenceReq request.NotificationPreferenceReq
+
+		err := n.Decode(r.Body, &preferenceReq)
+		if err != nil {
+			n.ErrorBadRequest(w, err)
+			return
+		}
+
+		preferences, err := n.notifications.SetEmailPreference(r.Context(), preferenceReq)
+		if err != nil {
+			n.ErrorBadRequest(w, err)
+			return
+		}
+
+		n.SendJSON(w, request.Response{
+			Success: true,
+			Data:    preferences,
+		})
+	}
+}
+
+func (n *notificationsController) Bounces() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxReportSize))
+		if err != nil {
+			n.ErrorBadRequest(w, err)
+			return
+		}
+
+		bouncesData, err := n.notifications.Bounces(r.Context(), r.Header.Get(BounceSecretHeader), r.Header.Get("Content-Type"), body)
+		if errors.Is(err, services.ErrBounceSecret) {
+			n.ErrorForbidden(w, err)
+			return
+		}
+		if err != nil {
+			n.ErrorBadRequest(w, err)
+			return
+		}
+
+		n.SendJSON(w, request.Response{
+			Success: true,
+			Data:    bouncesData,
+		})
+	}
+}
+
+func (n *notificationsController) TelegramLink() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		linkData, err := n.notifications.TelegramLink(r.Context())
+		if err != nil {
+			n.ErrorBadRequest(w, err)
+			return
+		}
+
+		n.SendJSON(w, request.Response{
+			Success: true,
+			Data:    linkData,
+		})
+	}
+}
+
+func (n *notificationsController) TelegramUnlink() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		preferencesData, err := n.notifications.TelegramUnlink(r.Context())
+		if err != nil {
+			n.ErrorBadRequest(w, err)
+			return
+		}
+
+		n.SendJSON(w, request.Response{
+			Success: true,
+			Data:    preferencesData,
+		})
+	}
+}
+
+func (n *notificationsController) PushKey() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		n.SendJSON(w, request.Response{
+			Success: true,
+			Data:    n.notifications.PushKey(),
+		})
+	}
+}
+
+func (n *notificationsController) PushSubscribe() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var subscriptionReq request.PushSubscriptionReq
+		err := n.Decode(r.Body, &subscriptionReq)
+		if err != nil {
+			n.ErrorBadRequest(w, err)
+			return
+		}
+
+		preferencesData, err := n.notifications.PushSubscribe(r.Context(), subscriptionReq, r.UserAgent())
+		if err != nil {
+			n.ErrorBadRequest(w, err)
+			return
+		}
+
+		n.SendJSON(w, request.Response{
+			Success: true,
+			Data:    preferencesData,
+		})
+	}
+}
+
+func (n *notificationsController) PushUnsubscribe() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var unsubscribeReq request.PushUnsubscribeReq
+		err := n.Decode(r.Body, &unsubscribeReq)
+		if err != nil {
+			n.ErrorBadRequest(w, err)
+			return
+		}
+
+		preferencesData, err := n.notifications.PushUnsubscribe(r.Context(), unsubscribeReq)
+		if err != nil {
+			n.ErrorBadRequest(w, err)
+			return
+		}
+
+		n.SendJSON(w, request.Response{
+			Success: true,
+			Data:    preferencesData,
+		})
+	}
+}
+
+// TelegramWebhook failed updates are acknowledged too, telegram would redeliver them otherwise
+func (n *notificationsController) TelegramWebhook() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var update providers.TelegramUpdate
+		err := n.Decode(http.MaxBytesReader(w, r.Body, maxReportSize), &update)
+		if err != nil {
+			n.ErrorBadRequest(w, err)
+			return
+		}
+
+		err = n.notifications.TelegramUpdate(r.Context(), r.Header.Get(TelegramSecretHeader), update)
+		if errors.Is(err, services.ErrTelegramSecret) {
+			n.ErrorForbidden(w, err)
+			return
+		}
+		if err != nil {
+			n.logger.Error("telegram update", zap.Int64("update_id", update.UpdateID), zap.Error(err))
+		}
+
+		n.SendJSON(w, request.Response{
+			Success: true,
+		})
+	}
+}
+
+func (n *notificationsController) render(w http.ResponseWriter, status int, done, failed bool) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	err := n.page.Execute(w, struct {
+		Done  bool
+		Error bool
+	}{
+		Done:  done,
+		Error: failed,
+	})
+	if err != nil {
+		n.logger.Error("unsubscribe template", zap.Error(err))
+	}
+}
@@ -0,0 +1,225 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	light "github.com/ptflp/go-light"
+	"github.com/ptflp/go-light/decoder"
+	"github.com/ptflp/go-light/oidc"
+	"github.com/ptflp/go-light/request"
+	"github.com/ptflp/go-light/respond"
+	"go.uber.org/zap"
+)
+
+type oidcController struct {
+	*decoder.Decoder
+	respond.Responder
+	oidc   light.OIDCService
+	logger *zap.Logger
+}
+
+func NewOIDCController(responder respond.Responder, oidcService light.OIDCService, logger *zap.Logger) *oidcController {
+	return &oidcController{
+		Decoder:   decoder.NewDecoder(),
+		Responder: responder,
+		oidc:      oidcService,
+		logger:    logger,
+	}
+}
+
+func (o *oidcController) Discovery() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		o.SendJSON(w, o.oidc.Discovery())
+	}
+}
+
+func (o *oidcController) JWKS() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		jwks, err := o.oidc.JWKS()
+		if err != nil {
+			o.ErrorInternal(w, err)
+			return
+		}
+		o.SendJSON(w, jwks)
+	}
+}
+
+func (o *oidcController) RegisterClient() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var clientCreateReq request.OAuthClientCreateReq
+		err := o.Decode(r.Body, &clientCreateReq)
+		if err != nil {
+			o.ErrorBadRequest(w, err)
+			return
+		}
+
+		clientData, err := o.oidc.RegisterClient(r.Context(), clientCreateReq)
+		if err != nil {
+			o.ErrorBadRequest(w, err)
+			return
+		}
+
+		o.SendJSON(w, request.Response{
+			Success: true,
+			Data: struct {
+				Client request.OAuthClientData `json:"client"`
+			}{
+				Client: clientData,
+			},
+		})
+	}
+}
+
+func (o *oidcController) ListClients() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		clientsData, err := o.oidc.ListClients(r.Context())
+		if err != nil {
+			o.ErrorBadRequest(w, err)
+			return
+		}
+
+		o.SendJSON(w, request.Response{
+			Success: true,
+			Data: struct {
+				Clients []request.OAuthClientData `json:"clients"`
+			}{
+				Clients: clientsData,
+			},
+		})
+	}
+}
+
+func (o *oidcController) Authorize() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		location, err := o.oidc.Authorize(r.Context(), request.AuthorizeRequest{
+			ResponseType:        q.Get("response_type"),
+			ClientID:            q.Get("client_id"),
+			RedirectURI:         q.Get("redirect_uri"),
+			Scope:               q.Get("scope"),
+			State:               q.Get("state"),
+			Nonce:               q.Get("nonce"),
+			CodeChallenge:       q.Get("code_challenge"),
+			CodeChallengeMethod: q.Get("code_challenge_method"),
+		})
+		if err != nil {
+			o.ErrorBadRequest(w, err)
+			return
+		}
+
+		http.Redirect(w, r, location, http.StatusFound)
+	}
+}
+
+func (o *oidcController) ConsentData() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var authorizeReq request.AuthorizeRequest
+		err := o.Decode(r.Body, &authorizeReq)
+		if err != nil {
+			o.ErrorBadRequest(w, err)
+			return
+		}
+
+		consentData, err := o.oidc.ConsentData(r.Context(), authorizeReq)
+		if err != nil {
+			o.ErrorBadRequest(w, err)
+			return
+		}
+
+		o.SendJSON(w, request.Response{
+			Success: true,
+			Data:    consentData,
+		})
+	}
+}
+
+func (o *oidcController) Consent() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var consentReq request.ConsentRequest
+		err := o.Decode(r.Body, &consentReq)
+		if err != nil {
+			o.ErrorBadRequest(w, err)
+			return
+		}
+
+		consentResult, err := o.oidc.Consent(r.Context(), consentReq)
+		if err != nil {
+			o.ErrorBadRequest(w, err)
+			return
+		}
+
+		o.SendJSON(w, request.Response{
+			Success: true,
+			Data:    consentResult,
+		})
+	}
+}
+
+func (o *oidcController) Token() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		err := r.ParseForm()
+		if err != nil {
+			o.oauthError(w, err)
+			return
+		}
+		tokenReq := request.TokenRequest{
+			GrantType:    r.PostForm.Get("grant_type"),
+			Code:         r.PostForm.Get("code"),
+			RedirectURI:  r.PostForm.Get("redirect_uri"),
+			ClientID:     r.PostForm.Get("client_id"),
+			ClientSecret: r.PostForm.Get("client_secret"),
+			CodeVerifier: r.PostForm.Get("code_verifier"),
+			RefreshToken: r.PostForm.Get("refresh_token"),
+			Scope:        r.PostForm.Get("scope"),
+		}
+		if clientID, clientSecret, ok := r.BasicAuth(); ok {
+			tokenReq.ClientID = clientID
+			tokenReq.ClientSecret = clientSecret
+		}
+
+		tokenData, err := o.oidc.Token(r.Context(), tokenReq)
+		if err != nil {
+			o.oauthError(w, err)
+			return
+		}
+
+		w.Header().Set("Cache-Control", "no-store")
+		w.Header().Set("Pragma", "no-cache")
+		o.SendJSON(w, tokenData)
+	}
+}
+
+func (o *oidcController) UserInfo() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		accessToken := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+
+		userInfo, err := o.oidc.UserInfo(r.Context(), accessToken)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			o.oauthError(w, err)
+			return
+		}
+
+		o.SendJSON(w, userInfo)
+	}
+}
+
+func (o *oidcController) oauthError(w http.ResponseWriter, err error) {
+	oauthErr := &oidc.Error{Code: "invalid_request", Description: err.Error(), Status: http.StatusBadRequest}
+	var e *oidc.Error
+	if errors.As(err, &e) {
+		oauthErr = e
+	}
+	o.logger.Warn("oauth2 error response", zap.Error(err))
+
+	w.Header().Set("Content-Type", "application/json;charset=utf-8")
+	w.WriteHeader(oauthErr.Status)
+	if err = o.Encode(w, request.OAuthErrorData{
+		Error:            oauthErr.Code,
+		ErrorDescription: oauthErr.Description,
+	}); err != nil {
+		o.logger.Error("response writer error on write", zap.Error(err))
+	}
+}
@@ -0,0 +1,47 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/ptflp/go-light/providers"
+	"github.com/ptflp/go-light/request"
+	"github.com/ptflp/go-light/respond"
+	"go.uber.org/zap"
+)
+
+type smsController struct {
+	respond.Responder
+	tracker *providers.SMSTracker
+	logger  *zap.Logger
+}
+
+func NewSMSController(responder respond.Responder, tracker *providers.SMSTracker, logger *zap.Logger) *smsController {
+	return &smsController{
+		Responder: responder,
+		tracker:   tracker,
+		logger:    logger,
+	}
+}
+
+// Status delivery status callback of sms provider
+func (s *smsController) Status() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		err := r.ParseForm()
+		if err != nil {
+			s.ErrorBadRequest(w, err)
+			return
+		}
+
+		err = s.tracker.Callback(r.Context(), chi.URLParam(r, "provider"), r.Form)
+		if err != nil {
+			s.logger.Warn("sms status callback", zap.Error(err))
+			s.ErrorBadRequest(w, err)
+			return
+		}
+
+		s.SendJSON(w, request.Response{
+			Success: true,
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/ptflp/go-light/email"
+	"github.com/ptflp/go-light/request"
+	"github.com/ptflp/go-light/respond"
+	"go.uber.org/zap"
+)
+
+type templatesController struct {
+	respond.Responder
+	templates *email.Templates
+	logger    *zap.Logger
+}
+
+func NewTemplatesController(responder respond.Responder, templates *email.Templates, logger *zap.Logger) *templatesController {
+	return &templatesController{
+		Responder: responder,
+		templates: templates,
+		logger:    logger,
+	}
+}
+
+func (t *templatesController) List() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		languages := make([]string, 0, len(email.Languages))
+		for _, lang := range email.Languages {
+			languages = append(languages, lang)
+		}
+
+		t.SendJSON(w, request.Response{
+			Success: true,
+			Data: struct {
+				Templates []string `json:"templates"`
+				Languages []string `json:"languages"`
+			}{
+				Templates: t.templates.Names(),
+				Languages: languages,
+			},
+		})
+	}
+}
+
+// Preview renders template with sample data as html page, format=text and format=json return text alternative and subject
+func (t *templatesController) Preview() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rendered, err := t.templates.Preview(chi.URLParam(r, "name"), r.URL.Query().Get("lang"))
+		if err != nil {
+			t.ErrorBadRequest(w, err)
+			return
+		}
+
+		switch r.URL.Query().Get("format") {
+		case "json":
+			t.SendJSON(w, request.Response{
+				Success: true,
+				Data:    rendered,
+			})
+		case "text":
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			_, _ = w.Write([]byte("Subject: " + rendered.Subject + "\n\n" + rendered.Text))
+		default:
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			_, _ = w.Write([]byte(rendered.HTML))
+		}
+	}
+}
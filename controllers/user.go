@@ -257,6 +257,32 @@ func (u *usersController) PasswordReset() http.HandlerFunc {
 	}
 }
 
+func (u *usersController) Delete() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		var deleteAccountReq request.DeleteAccountReq
+
+		err := u.Decode(r.Body, &deleteAccountReq)
+
+		if err != nil {
+			u.ErrorBadRequest(w, err)
+			return
+		}
+
+		err = u.user.Delete(r.Context(), deleteAccountReq)
+
+		if err != nil {
+			u.ErrorBadRequest(w, err)
+			return
+		}
+
+		u.SendJSON(w, request.Response{
+			Success: true,
+			Msg:     "Аккаунт удален, его можно восстановить, авторизовавшись повторно",
+		})
+	}
+}
+
 func (u *usersController) EmailExist() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 
@@ -323,3 +349,50 @@ func extractUser(ctx context.Context) (light.User, error) {
 
 	return *u, nil
 }
+
+func (u *usersController) Profile() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		userData, err := u.user.GetProfile(r.Context())
+
+		if err != nil {
+			u.ErrorBadRequest(w, err)
+			return
+		}
+
+		u.SendJSON(w, request.Response{
+			Success: true,
+			Data: struct {
+				User request.UserData `json:"user"`
+			}{
+				User: userData,
+			},
+		})
+	}
+}
+
+func (u *usersController) SetPassword() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		var setPasswordReq request.SetPasswordReq
+
+		err := u.Decode(r.Body, &setPasswordReq)
+
+		if err != nil {
+			u.ErrorBadRequest(w, err)
+			return
+		}
+
+		err = u.user.SetPassword(r.Context(), setPasswordReq)
+
+		if err != nil {
+			u.ErrorBadRequest(w, err)
+			return
+		}
+
+		u.SendJSON(w, request.Response{
+			Success: true,
+			Msg:     "Пароль изменен",
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package db
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+	light "github.com/ptflp/go-light"
+)
+
+type auditRepository struct {
+	db *sqlx.DB
+	crud
+}
+
+func NewAuditRepository(db *sqlx.DB) light.AuditRepository {
+	return &auditRepository{db: db, crud: crud{db: db}}
+}
+
+func (a *auditRepository) Create(ctx context.Context, event light.AuditEvent) error {
+	return a.create(ctx, &event)
+}
+
+func (a *auditRepository) FindByUser(ctx context.Context, user light.User) ([]light.AuditEvent, error) {
+	var events []light.AuditEvent
+	err := a.listx(ctx, &events, light.AuditEvent{}, light.Condition{
+		Other: &light.Other{
+			Condition: "user_uuid = ? OR actor_uuid = ?",
+			Args:      []interface{}{user.UUID, user.UUID},
+		},
+		Order: &light.Order{Field: "created_at"},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
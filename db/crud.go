@@ -210,6 +210,12 @@ func (c *crud) listx(ctx context.Context, dest interface{}, entity light.Tabler,
 
 	sQuery := sq.Select(fields...).From(entity.TableName())
 
+	// hide soft deleted entities
+	if _, ok := light.GetTables()[entity.TableName()].FieldsMap["deleted_at"]; ok {
+		sQuery = sQuery.Where(sq.Eq{"deleted_at": nil})
+		whereState = true
+	}
+
 	if condition.Equal != nil {
 		sQuery = sQuery.Where(condition.Equal)
 		whereState = true
@@ -0,0 +1,73 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	light "github.com/ptflp/go-light"
+)
+
+const userReferenceField = "user_uuid"
+
+type exportRepository struct {
+	db *sqlx.DB
+}
+
+func NewExportRepository(db *sqlx.DB) light.ExportRepository {
+	return &exportRepository{db: db}
+}
+
+func (e *exportRepository) FindByUser(ctx context.Context, table light.Table, fields []string, user light.User) ([]map[string]interface{}, error) {
+	if _, ok := table.FieldsMap[userReferenceField]; !ok || len(fields) == 0 {
+		return nil, nil
+	}
+	for i := range fields {
+		if _, ok := table.FieldsMap[fields[i]]; !ok {
+			return nil, fmt.Errorf("field %s not exist in table %s", fields[i], table.Name)
+		}
+	}
+
+	query, args, err := sq.Select(fields...).From(table.Name).Where(sq.Eq{userReferenceField: user.UUID}).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := e.db.QueryxContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []map[string]interface{}
+	for rows.Next() {
+		row := make(map[string]interface{}, len(fields))
+		err = rows.MapScan(row)
+		if err != nil {
+			return nil, err
+		}
+		for name, value := range row {
+			row[name] = exportValue(table.FieldsMap[name], value)
+		}
+		result = append(result, row)
+	}
+
+	return result, rows.Err()
+}
+
+func exportValue(field light.Field, value interface{}) interface{} {
+	b, ok := value.([]byte)
+	if !ok {
+		return value
+	}
+	if field.Type == "binary(16)" {
+		uid, err := uuid.FromBytes(b)
+		if err == nil {
+			return uid.String()
+		}
+	}
+
+	return string(b)
+}
@@ -0,0 +1,137 @@
+package db
+
+import (
+	"context"
+	"errors"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+	light "github.com/ptflp/go-light"
+)
+
+const (
+	redeemInvite = "UPDATE invites SET uses = uses + 1 WHERE uuid = ? AND uses < max_uses AND (expires_at IS NULL OR expires_at > now()) AND deleted_at IS NULL"
+	countInvites = "SELECT COUNT(uuid) FROM invites WHERE user_uuid = ? AND deleted_at IS NULL"
+)
+
+type inviteRepository struct {
+	db *sqlx.DB
+	crud
+}
+
+func NewInviteRepository(db *sqlx.DB) light.InviteRepository {
+	return &inviteRepository{db: db, crud: crud{db: db}}
+}
+
+func (i *inviteRepository) Create(ctx context.Context, invite light.Invite) error {
+	return i.create(ctx, &invite)
+}
+
+func (i *inviteRepository) FindByCode(ctx context.Context, invite light.Invite) (light.Invite, error) {
+	fields, err := light.GetFields(&light.Invite{})
+	if err != nil {
+		return light.Invite{}, err
+	}
+
+	query, args, err := sq.Select(fields...).From("invites").Where(sq.Eq{"code": invite.Code, "deleted_at": nil}).ToSql()
+	if err != nil {
+		return light.Invite{}, err
+	}
+
+	if err = i.db.QueryRowxContext(ctx, query, args...).StructScan(&invite); err != nil {
+		return light.Invite{}, err
+	}
+
+	return invite, nil
+}
+
+func (i *inviteRepository) CountByUser(ctx context.Context, user light.User) (int64, error) {
+	var count int64
+	err := i.db.QueryRowxContext(ctx, countInvites, user.UUID).Scan(&count)
+
+	return count, err
+}
+
+func (i *inviteRepository) ListByUser(ctx context.Context, user light.User) ([]light.Invite, error) {
+	var invites []light.Invite
+	err := i.listx(ctx, &invites, light.Invite{}, light.Condition{
+		Equal: &sq.Eq{"user_uuid": user.UUID},
+		Order: &light.Order{Field: "created_at"},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return invites, nil
+}
+
+// Redeem increments invite usage, creates invitee and stores inviter and invitee relation in single transaction
+func (i *inviteRepository) Redeem(ctx context.Context, invite light.Invite, inviteUse light.InviteUse, invitee light.User) error {
+	return i.redeem(ctx, invite, inviteUse, func(tx *sqlx.Tx) error {
+		return insertUser(ctx, tx, invitee)
+	})
+}
+
+// RedeemGuest same as Redeem, guest account is upgraded instead of creating user
+func (i *inviteRepository) RedeemGuest(ctx context.Context, invite light.Invite, inviteUse light.InviteUse, guest light.User) error {
+	return i.redeem(ctx, invite, inviteUse, func(tx *sqlx.Tx) error {
+		return upgradeGuestUser(ctx, tx, guest)
+	})
+}
+
+func (i *inviteRepository) redeem(ctx context.Context, invite light.Invite, inviteUse light.InviteUse, invitee func(tx *sqlx.Tx) error) (err error) {
+	tx, err := i.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	res, err := tx.ExecContext(ctx, redeemInvite, invite.UUID)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected < 1 {
+		err = errors.New("invite code expired or exhausted")
+		return err
+	}
+
+	err = invitee(tx)
+	if err != nil {
+		return err
+	}
+
+	createFields, err := light.GetFields(&inviteUse, "create")
+	if err != nil {
+		return err
+	}
+	query, args, err := sq.Insert(inviteUse.TableName()).Columns(createFields...).Values(light.GetFieldsPointers(&inviteUse, "create")...).ToSql()
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, query, args...)
+
+	return err
+}
+
+func (i *inviteRepository) Referrals(ctx context.Context, user light.User) ([]light.InviteUse, error) {
+	var referrals []light.InviteUse
+	err := i.listx(ctx, &referrals, light.InviteUse{}, light.Condition{
+		Equal: &sq.Eq{"inviter_uuid": user.UUID},
+		Order: &light.Order{Field: "created_at"},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return referrals, nil
+}
@@ -0,0 +1,80 @@
+package db
+
+import (
+	"context"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+	light "github.com/ptflp/go-light"
+)
+
+type oauthRepository struct {
+	db *sqlx.DB
+	crud
+}
+
+func NewOAuthRepository(db *sqlx.DB) light.OAuthRepository {
+	return &oauthRepository{db: db, crud: crud{db: db}}
+}
+
+func (o *oauthRepository) CreateClient(ctx context.Context, client light.OAuthClient) error {
+	return o.create(ctx, &client)
+}
+
+func (o *oauthRepository) FindClient(ctx context.Context, client light.OAuthClient) (light.OAuthClient, error) {
+	fields, err := light.GetFields(&light.OAuthClient{})
+	if err != nil {
+		return light.OAuthClient{}, err
+	}
+
+	query, args, err := sq.Select(fields...).From("oauth_clients").Where(sq.Eq{"client_id": client.ClientID, "deleted_at": nil}).ToSql()
+	if err != nil {
+		return light.OAuthClient{}, err
+	}
+
+	if err = o.db.QueryRowxContext(ctx, query, args...).StructScan(&client); err != nil {
+		return light.OAuthClient{}, err
+	}
+
+	return client, nil
+}
+
+func (o *oauthRepository) ListClients(ctx context.Context) ([]light.OAuthClient, error) {
+	var clients []light.OAuthClient
+	err := o.listx(ctx, &clients, light.OAuthClient{}, light.Condition{
+		Order: &light.Order{Field: "created_at"},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return clients, nil
+}
+
+func (o *oauthRepository) FindConsent(ctx context.Context, consent light.OAuthConsent) (light.OAuthConsent, error) {
+	fields, err := light.GetFields(&light.OAuthConsent{})
+	if err != nil {
+		return light.OAuthConsent{}, err
+	}
+
+	query, args, err := sq.Select(fields...).From("oauth_consents").Where(sq.Eq{"user_uuid": consent.UserUUID, "client_uuid": consent.ClientUUID}).ToSql()
+	if err != nil {
+		return light.OAuthConsent{}, err
+	}
+
+	if err = o.db.QueryRowxContext(ctx, query, args...).StructScan(&consent); err != nil {
+		return light.OAuthConsent{}, err
+	}
+
+	return consent, nil
+}
+
+func (o *oauthRepository) SaveConsent(ctx context.Context, consent light.OAuthConsent) error {
+	existing, err := o.FindConsent(ctx, consent)
+	if err != nil {
+		return o.create(ctx, &consent)
+	}
+	existing.Scope = consent.Scope
+
+	return o.update(ctx, &existing)
+}
@@ -0,0 +1,146 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+	light "github.com/ptflp/go-light"
+	"github.com/ptflp/go-light/types"
+)
+
+const (
+	outboxStats = `SELECT
+	COUNT(CASE WHEN status = ? THEN 1 END),
+	COUNT(CASE WHEN status = ? AND attempts > 0 THEN 1 END),
+	COUNT(CASE WHEN status = ? THEN 1 END),
+	COUNT(CASE WHEN status = ? THEN 1 END),
+	MIN(CASE WHEN status IN (?, ?) THEN created_at END)
+	FROM email_outbox`
+)
+
+type outboxRepository struct {
+	db *sqlx.DB
+	crud
+}
+
+func NewOutboxRepository(db *sqlx.DB) light.OutboxRepository {
+	return &outboxRepository{db: db, crud: crud{db: db}}
+}
+
+func (o *outboxRepository) Create(ctx context.Context, msg light.OutboxMessage) (bool, error) {
+	createFields, err := light.GetFields(&msg, "create")
+	if err != nil {
+		return false, err
+	}
+	// duplicate idempotency key is ignored by unique index
+	query, args, err := sq.Insert(msg.TableName()).Options("IGNORE").Columns(createFields...).Values(light.GetFieldsPointers(&msg, "create")...).ToSql()
+	if err != nil {
+		return false, err
+	}
+	res, err := o.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+
+	return affected > 0, err
+}
+
+func (o *outboxRepository) Claim(ctx context.Context, limit uint64, lease time.Duration) (messages []light.OutboxMessage, err error) {
+	fields, err := light.GetFields(&light.OutboxMessage{})
+	if err != nil {
+		return nil, err
+	}
+	tx, err := o.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	now := time.Now()
+	query, args, err := sq.Select(fields...).From("email_outbox").
+		Where(sq.Eq{"status": []int64{types.OutboxPending, types.OutboxSending}}).
+		Where(sq.LtOrEq{"next_attempt_at": now}).
+		OrderBy("next_attempt_at").
+		Limit(limit).
+		Suffix("FOR UPDATE SKIP LOCKED").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+	if err = tx.SelectContext(ctx, &messages, query, args...); err != nil {
+		return nil, err
+	}
+	if len(messages) == 0 {
+		return nil, nil
+	}
+
+	uuids := make([]interface{}, 0, len(messages))
+	for i := range messages {
+		messages[i].Status = types.NewNullInt64(types.OutboxSending)
+		messages[i].NextAttemptAt.SetValid(now.Add(lease))
+		uuids = append(uuids, messages[i].UUID)
+	}
+	query, args, err = sq.Update("email_outbox").
+		Set("status", types.OutboxSending).
+		Set("next_attempt_at", now.Add(lease)).
+		Where(sq.Eq{"uuid": uuids}).
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+	_, err = tx.ExecContext(ctx, query, args...)
+
+	return messages, err
+}
+
+func (o *outboxRepository) Update(ctx context.Context, msg light.OutboxMessage) error {
+	return o.update(ctx, &msg)
+}
+
+func (o *outboxRepository) Stats(ctx context.Context) (light.OutboxStats, error) {
+	var stats light.OutboxStats
+	err := o.db.QueryRowxContext(ctx, outboxStats,
+		types.OutboxPending, types.OutboxPending, types.OutboxSending, types.OutboxDead, types.OutboxPending, types.OutboxSending,
+	).Scan(&stats.Pending, &stats.Retrying, &stats.Sending, &stats.Dead, &stats.OldestPending)
+
+	return stats, err
+}
+
+func (o *outboxRepository) ListDead(ctx context.Context, limit uint64) ([]light.OutboxMessage, error) {
+	var messages []light.OutboxMessage
+	err := o.listx(ctx, &messages, light.OutboxMessage{}, light.Condition{
+		Equal:       &sq.Eq{"status": types.OutboxDead},
+		Order:       &light.Order{Field: "updated_at"},
+		LimitOffset: &light.LimitOffset{Limit: int64(limit)},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return messages, nil
+}
+
+func (o *outboxRepository) Purge(ctx context.Context, before time.Time) (int64, error) {
+	query, args, err := sq.Delete("email_outbox").
+		Where(sq.Eq{"status": []int64{types.OutboxSent, types.OutboxDead}}).
+		Where(sq.Lt{"updated_at": before}).
+		ToSql()
+	if err != nil {
+		return 0, err
+	}
+	res, err := o.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
@@ -0,0 +1,66 @@
+package db
+
+import (
+	"context"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+	light "github.com/ptflp/go-light"
+)
+
+const savePushSubscription = "INSERT INTO push_subscriptions (uuid, user_uuid, endpoint, p256dh, auth, user_agent) VALUES (?, ?, ?, ?, ?, ?) ON DUPLICATE KEY UPDATE user_uuid = VALUES(user_uuid), p256dh = VALUES(p256dh), auth = VALUES(auth), user_agent = VALUES(user_agent)"
+
+type pushRepository struct {
+	db *sqlx.DB
+	crud
+}
+
+func NewPushRepository(db *sqlx.DB) light.PushRepository {
+	return &pushRepository{db: db, crud: crud{db: db}}
+}
+
+func (p *pushRepository) Save(ctx context.Context, subscription light.PushSubscription) error {
+	_, err := p.db.ExecContext(ctx, savePushSubscription, subscription.UUID, subscription.UserUUID, subscription.Endpoint, subscription.P256dh, subscription.Auth, subscription.UserAgent)
+
+	return err
+}
+
+func (p *pushRepository) Delete(ctx context.Context, user light.User, endpoint string) error {
+	condition := sq.Eq{"endpoint": endpoint}
+	if user.UUID.Valid {
+		condition["user_uuid"] = user.UUID
+	}
+	query, args, err := sq.Delete("push_subscriptions").Where(condition).ToSql()
+	if err != nil {
+		return err
+	}
+	_, err = p.db.ExecContext(ctx, query, args...)
+
+	return err
+}
+
+func (p *pushRepository) DeleteByUser(ctx context.Context, user light.User) error {
+	query, args, err := sq.Delete("push_subscriptions").Where(sq.Eq{"user_uuid": user.UUID}).ToSql()
+	if err != nil {
+		return err
+	}
+	_, err = p.db.ExecContext(ctx, query, args...)
+
+	return err
+}
+
+func (p *pushRepository) ListByUser(ctx context.Context, user light.User) ([]light.PushSubscription, error) {
+	fields, err := light.GetFields(&light.PushSubscription{})
+	if err != nil {
+		return nil, err
+	}
+	query, args, err := sq.Select(fields...).From("push_subscriptions").Where(sq.Eq{"user_uuid": user.UUID}).OrderBy("created_at").ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var subscriptions []light.PushSubscription
+	err = p.db.SelectContext(ctx, &subscriptions, query, args...)
+
+	return subscriptions, err
+}
@@ -20,7 +20,15 @@ func NewRepositories(cmps components.Componenter) light.Repositories {
 	}
 
 	r := light.Repositories{
-		Users: NewUserRepository(mainDB),
+		Users:        NewUserRepository(mainDB),
+		Exports:      NewExportRepository(mainDB),
+		Invites:      NewInviteRepository(mainDB),
+		OAuth:        NewOAuthRepository(mainDB),
+		Audit:        NewAuditRepository(mainDB),
+		Outbox:       NewOutboxRepository(mainDB),
+		Suppressions: NewSuppressionRepository(mainDB),
+		SMS:          NewSMSRepository(mainDB),
+		Push:         NewPushRepository(mainDB),
 	}
 
 	return r
@@ -0,0 +1,64 @@
+package db
+
+import (
+	"context"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+	light "github.com/ptflp/go-light"
+	"github.com/ptflp/go-light/types"
+)
+
+type smsRepository struct {
+	db *sqlx.DB
+	crud
+}
+
+func NewSMSRepository(db *sqlx.DB) light.SMSRepository {
+	return &smsRepository{db: db, crud: crud{db: db}}
+}
+
+func (s *smsRepository) Create(ctx context.Context, msg light.SMSMessage) error {
+	return s.create(ctx, &msg)
+}
+
+func (s *smsRepository) Update(ctx context.Context, msg light.SMSMessage) error {
+	return s.update(ctx, &msg)
+}
+
+func (s *smsRepository) FindByProviderID(ctx context.Context, provider, providerID string) (light.SMSMessage, error) {
+	fields, err := light.GetFields(&light.SMSMessage{})
+	if err != nil {
+		return light.SMSMessage{}, err
+	}
+	query, args, err := sq.Select(fields...).From("sms_messages").Where(sq.Eq{"provider": provider, "provider_id": providerID}).ToSql()
+	if err != nil {
+		return light.SMSMessage{}, err
+	}
+
+	var msg light.SMSMessage
+	err = s.db.QueryRowxContext(ctx, query, args...).StructScan(&msg)
+
+	return msg, err
+}
+
+func (s *smsRepository) ListSent(ctx context.Context, limit uint64) ([]light.SMSMessage, error) {
+	fields, err := light.GetFields(&light.SMSMessage{})
+	if err != nil {
+		return nil, err
+	}
+	// never checked messages (null) go first
+	query, args, err := sq.Select(fields...).From("sms_messages").
+		Where(sq.Eq{"status": types.SMSSent}).
+		OrderBy("checked_at").
+		Limit(limit).
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var messages []light.SMSMessage
+	err = s.db.SelectContext(ctx, &messages, query, args...)
+
+	return messages, err
+}
@@ -0,0 +1,77 @@
+package db
+
+import (
+	"context"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+	light "github.com/ptflp/go-light"
+)
+
+const (
+	suppressEmail = "INSERT INTO email_suppressions (uuid, email, reason, details) VALUES (?, ?, ?, ?) ON DUPLICATE KEY UPDATE reason = VALUES(reason), details = VALUES(details)"
+	resubscribe   = "DELETE FROM email_unsubscribes WHERE user_uuid = ? AND category = ?"
+)
+
+type suppressionRepository struct {
+	db *sqlx.DB
+	crud
+}
+
+func NewSuppressionRepository(db *sqlx.DB) light.SuppressionRepository {
+	return &suppressionRepository{db: db, crud: crud{db: db}}
+}
+
+func (s *suppressionRepository) Suppress(ctx context.Context, suppression light.EmailSuppression) error {
+	_, err := s.db.ExecContext(ctx, suppressEmail, suppression.UUID, suppression.Email, suppression.Reason, suppression.Details)
+
+	return err
+}
+
+func (s *suppressionRepository) Suppressed(ctx context.Context, emails []string) ([]string, error) {
+	if len(emails) == 0 {
+		return nil, nil
+	}
+	query, args, err := sq.Select("email").From("email_suppressions").Where(sq.Eq{"email": emails}).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var suppressed []string
+	err = s.db.SelectContext(ctx, &suppressed, query, args...)
+
+	return suppressed, err
+}
+
+func (s *suppressionRepository) Unsubscribe(ctx context.Context, unsubscribe light.EmailUnsubscribe) error {
+	createFields, err := light.GetFields(&unsubscribe, "create")
+	if err != nil {
+		return err
+	}
+	// repeated unsubscribe is ignored by unique index
+	query, args, err := sq.Insert(unsubscribe.TableName()).Options("IGNORE").Columns(createFields...).Values(light.GetFieldsPointers(&unsubscribe, "create")...).ToSql()
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, query, args...)
+
+	return err
+}
+
+func (s *suppressionRepository) Resubscribe(ctx context.Context, user light.User, category string) error {
+	_, err := s.db.ExecContext(ctx, resubscribe, user.UUID, category)
+
+	return err
+}
+
+func (s *suppressionRepository) Unsubscribed(ctx context.Context, user light.User) ([]string, error) {
+	query, args, err := sq.Select("category").From("email_unsubscribes").Where(sq.Eq{"user_uuid": user.UUID}).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var categories []string
+	err = s.db.SelectContext(ctx, &categories, query, args...)
+
+	return categories, err
+}
@@ -2,17 +2,26 @@ package db
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	sq "github.com/Masterminds/squirrel"
 	"github.com/jmoiron/sqlx"
 	light "github.com/ptflp/go-light"
+	"github.com/ptflp/go-light/types"
 )
 
 const (
 	setPassword               = "UPDATE users SET password = ? WHERE uuid = ?"
 	createUserByEmailPassword = "INSERT INTO users (uuid, email, password, active, email_verified) VALUES (?, ?, ?, 1, 1)"
+	deleteUser                = "UPDATE users SET deleted_at = now() WHERE uuid = ?"
+	restoreUser               = "UPDATE users SET deleted_at = NULL WHERE uuid = ?"
+	upgradeGuest              = "UPDATE users SET role = ?, phone = COALESCE(?, phone), email = COALESCE(?, email), email_verified = COALESCE(?, email_verified), password = COALESCE(?, password), facebook_id = COALESCE(?, facebook_id), google_id = COALESCE(?, google_id), name = COALESCE(?, name) WHERE uuid = ? AND role = ?"
+	touchUser                 = "UPDATE users SET updated_at = now() WHERE uuid = ?"
+	purgeGuests               = "DELETE FROM users WHERE role = ? AND updated_at < ?"
+	anonymizeUser             = "UPDATE users SET phone = NULL, email = NULL, avatar = NULL, password = NULL, active = 0, name = NULL, second_name = NULL, email_verified = NULL, description = NULL, nickname = NULL, facebook_id = NULL, google_id = NULL, telegram_id = NULL WHERE uuid = ? AND deleted_at IS NOT NULL"
 )
 
 type userRepository struct {
@@ -63,6 +72,11 @@ func (u *userRepository) FindByPhone(ctx context.Context, user light.User) (ligh
 }
 
 func (u *userRepository) CreateUser(ctx context.Context, user light.User) error {
+	return insertUser(ctx, u.db, user)
+}
+
+// insertUser is shared with transactions of other repositories, e.g. invite redemption
+func insertUser(ctx context.Context, db sqlx.ExecerContext, user light.User) error {
 	createFields, err := light.GetFields(&light.User{}, "create")
 	if err != nil {
 		return err
@@ -75,7 +89,7 @@ func (u *userRepository) CreateUser(ctx context.Context, user light.User) error
 		return err
 	}
 
-	_, err = u.db.QueryxContext(ctx, query, args...)
+	_, err = db.ExecContext(ctx, query, args...)
 
 	return err
 }
@@ -117,6 +131,90 @@ func (u *userRepository) SetPassword(ctx context.Context, user light.User) error
 	return err
 }
 
+func (u *userRepository) Delete(ctx context.Context, user light.User) error {
+	_, err := u.db.ExecContext(ctx, deleteUser, user.UUID)
+
+	return err
+}
+
+// UpgradeGuest turns guest into regular user keeping uuid, only provided identity fields are set
+func (u *userRepository) UpgradeGuest(ctx context.Context, user light.User) error {
+	return upgradeGuestUser(ctx, u.db, user)
+}
+
+func upgradeGuestUser(ctx context.Context, db sqlx.ExecerContext, user light.User) error {
+	res, err := db.ExecContext(ctx, upgradeGuest, types.RoleUser, user.Phone, user.Email, user.EmailVerified, user.Password, user.FacebookID, user.GoogleID, user.Name, user.UUID, types.RoleGuest)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected < 1 {
+		return errors.New("guest not found")
+	}
+
+	return nil
+}
+
+func (u *userRepository) Restore(ctx context.Context, user light.User) error {
+	_, err := u.db.ExecContext(ctx, restoreUser, user.UUID)
+
+	return err
+}
+
+// FindDeleted returns users deleted before specified time which still hold personal data
+func (u *userRepository) FindDeleted(ctx context.Context, before time.Time) ([]light.User, error) {
+	fields, err := light.GetFields(&light.User{})
+	if err != nil {
+		return nil, err
+	}
+
+	query, args, err := sq.Select(fields...).From("users").
+		Where(sq.LtOrEq{"deleted_at": before}).
+		Where(sq.Or{
+			sq.NotEq{"phone": nil},
+			sq.NotEq{"email": nil},
+			sq.NotEq{"nickname": nil},
+			sq.NotEq{"name": nil},
+			sq.NotEq{"facebook_id": nil},
+			sq.NotEq{"google_id": nil},
+			sq.NotEq{"telegram_id": nil},
+		}).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var users []light.User
+	if err = u.db.SelectContext(ctx, &users, query, args...); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+func (u *userRepository) Anonymize(ctx context.Context, user light.User) error {
+	_, err := u.db.ExecContext(ctx, anonymizeUser, user.UUID)
+
+	return err
+}
+
+func (u *userRepository) Touch(ctx context.Context, user light.User) error {
+	_, err := u.db.ExecContext(ctx, touchUser, user.UUID)
+
+	return err
+}
+
+func (u *userRepository) PurgeGuests(ctx context.Context, before time.Time) (int64, error) {
+	res, err := u.db.ExecContext(ctx, purgeGuests, types.RoleGuest, before)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
 func (u *userRepository) Find(ctx context.Context, user light.User) (light.User, error) {
 	fields, err := light.GetFields(&light.User{})
 	if err != nil {
@@ -141,7 +239,28 @@ func (u *userRepository) FindByNickname(ctx context.Context, user light.User) (l
 		return light.User{}, err
 	}
 
-	query, args, err := sq.Select(fields...).From("users").Where(sq.Eq{"nickname": user.NickName}).ToSql()
+	query, args, err := sq.Select(fields...).From("users").Where(sq.Eq{"nickname": user.NickName, "deleted_at": nil}).ToSql()
+	if err != nil {
+		return light.User{}, err
+	}
+
+	if err := u.db.QueryRowxContext(ctx, query, args...).StructScan(&user); err != nil {
+		return light.User{}, err
+	}
+
+	return user, nil
+}
+
+// FindByNicknameWithDeleted same as FindByNickname, deleted users which still hold nickname are found too.
+// Nickname of deleted user may be taken by another one, active user goes first
+func (u *userRepository) FindByNicknameWithDeleted(ctx context.Context, user light.User) (light.User, error) {
+	fields, err := light.GetFields(&light.User{})
+	if err != nil {
+		return light.User{}, err
+	}
+
+	query, args, err := sq.Select(fields...).From("users").Where(sq.Eq{"nickname": user.NickName}).
+		OrderBy("deleted_at IS NULL DESC", "deleted_at DESC").Limit(1).ToSql()
 	if err != nil {
 		return light.User{}, err
 	}
@@ -159,7 +278,7 @@ func (u *userRepository) FindLikeNickname(ctx context.Context, nickname string)
 		return nil, err
 	}
 
-	query, args, err := sq.Select(fields...).From("users").Where(sq.Like{"nickname": strings.Join([]string{"%", nickname, "%"}, "")}).ToSql()
+	query, args, err := sq.Select(fields...).From("users").Where(sq.Like{"nickname": strings.Join([]string{"%", nickname, "%"}, "")}).Where(sq.Eq{"deleted_at": nil}).ToSql()
 	if err != nil {
 		return nil, err
 	}
@@ -194,6 +313,27 @@ func (u *userRepository) FindByFacebook(ctx context.Context, user light.User) (l
 	return user, nil
 }
 
+func (u *userRepository) FindByTelegram(ctx context.Context, user light.User) (light.User, error) {
+	if !user.TelegramID.Valid {
+		return light.User{}, fmt.Errorf("invalid telegram_id %d", user.TelegramID.Int64)
+	}
+	fields, err := light.GetFields(&light.User{})
+	if err != nil {
+		return light.User{}, err
+	}
+
+	query, args, err := sq.Select(fields...).From("users").Where(sq.Eq{"telegram_id": user.TelegramID}).ToSql()
+	if err != nil {
+		return light.User{}, err
+	}
+
+	if err := u.db.QueryRowxContext(ctx, query, args...).StructScan(&user); err != nil {
+		return light.User{}, err
+	}
+
+	return user, nil
+}
+
 func (u *userRepository) FindByGoogle(ctx context.Context, user light.User) (light.User, error) {
 	if !user.GoogleID.Valid {
 		return light.User{}, fmt.Errorf("invalid facebook_id %s", user.GoogleID.String)
@@ -221,7 +361,7 @@ func (u *userRepository) FindAll(ctx context.Context) ([]light.User, error) {
 		return nil, err
 	}
 
-	query, args, err := sq.Select(fields...).From("users").Where(sq.NotEq{"nickname": "null"}).ToSql()
+	query, args, err := sq.Select(fields...).From("users").Where(sq.NotEq{"nickname": "null"}).Where(sq.Eq{"deleted_at": nil}).ToSql()
 	if err != nil {
 		return nil, err
 	}
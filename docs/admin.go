@@ -0,0 +1,86 @@
+package docs
+
+import "github.com/ptflp/go-light/request"
+
+// swagger:route POST /admin/impersonate admin adminImpersonateRequest
+// Вход администратора от имени пользователя, выдается короткоживущий access token без refresh token. Причина обязательна и сохраняется в журнале аудита.
+// security:
+//   - Bearer: []
+// responses:
+//   200: adminImpersonateResponse
+
+// swagger:response adminImpersonateResponse
+type adminImpersonateResponse struct {
+	// in:body
+	Body request.Response
+}
+
+// swagger:parameters adminImpersonateRequest
+type adminImpersonateParams struct {
+	// in:body
+	Body request.ImpersonateReq
+}
+
+// swagger:route POST /admin/audit admin adminAuditRequest
+// Журнал аудита по пользователю.
+// security:
+//   - Bearer: []
+// responses:
+//   200: adminAuditResponse
+
+// swagger:response adminAuditResponse
+type adminAuditResponse struct {
+	// in:body
+	Body request.Response
+}
+
+// swagger:parameters adminAuditRequest
+type adminAuditParams struct {
+	// in:body
+	Body request.AuditReq
+}
+
+// swagger:route POST /admin/outbox admin adminOutboxRequest
+// Состояние очереди исходящих писем: количество ожидающих, повторяемых и недоставленных писем, последние ошибки доставки.
+// security:
+//   - Bearer: []
+// responses:
+//   200: adminOutboxResponse
+
+// swagger:response adminOutboxResponse
+type adminOutboxResponse struct {
+	// in:body
+	Body request.Response
+}
+
+// swagger:route POST /admin/sms admin adminSMSRequest
+// Состояние sms провайдеров с момента запуска: доступность, количество отправленных и неотправленных сообщений, стоимость, последняя ошибка и баланс аккаунта.
+// security:
+//   - Bearer: []
+// responses:
+//   200: adminSMSResponse
+
+// swagger:response adminSMSResponse
+type adminSMSResponse struct {
+	// in:body
+	Body request.Response
+}
+
+// swagger:route POST /admin/notify admin adminNotifyRequest
+// Отправка email уведомления пользователю. Уведомление не отправляется, если пользователь отписался от категории или от всех уведомлений.
+// security:
+//   - Bearer: []
+// responses:
+//   200: adminNotifyResponse
+
+// swagger:response adminNotifyResponse
+type adminNotifyResponse struct {
+	// in:body
+	Body request.Response
+}
+
+// swagger:parameters adminNotifyRequest
+type adminNotifyParams struct {
+	// in:body
+	Body request.NotifyReq
+}
@@ -5,14 +5,14 @@ import (
 )
 
 // swagger:route POST /auth/code auth sendCodeRequest
-// Отправка смс кода.
+// Отправка кода подтверждения по смс, звонком (код - последние цифры номера) или голосом. При ошибке доставки используется следующий канал.
 // responses:
 //   200: sendCodeResponse
 
 // swagger:response sendCodeResponse
 type sendCodeResponse struct {
 	// in:body
-	Body request.Response
+	Body request.PhoneCodeResponse
 }
 
 // swagger:parameters sendCodeRequest
@@ -39,7 +39,7 @@ type checkCodeParams struct {
 }
 
 // swagger:route POST /auth/email/registration auth EmailActivationRequest
-// Отправка ссылки активации на почту.
+// Отправка ссылки активации на почту. В режиме защиты от перебора ответ одинаков для занятых адресов, владельцу отправляется уведомление о попытке регистрации.
 // responses:
 //   200: emailRegistrationResponse
 
@@ -89,6 +89,23 @@ type emailLoginParams struct {
 	Body request.EmailLoginRequest
 }
 
+// swagger:route POST /auth/login auth LoginRequest
+// Вход по паролю, в качестве логина принимается почта, телефон или никнейм. После 5 неудачных попыток вход блокируется на 15 минут.
+// responses:
+//   200: LoginResponse
+
+// swagger:response LoginResponse
+type loginResponse struct {
+	// in:body
+	Body request.AuthTokenResponse
+}
+
+// swagger:parameters LoginRequest
+type loginParams struct {
+	// in:body
+	Body request.LoginRequest
+}
+
 // swagger:route POST /auth/token/refresh auth RefreshTokenRequest
 // Обновление токена.
 // responses:
@@ -106,6 +123,70 @@ type refTokenParams struct {
 	Body request.RefreshTokenRequest
 }
 
+// swagger:route POST /auth/guest auth GuestLoginRequest
+// Гостевой вход, refresh token привязан к device_id и обновляется только с ним. Гость может привязать телефон, почту или соцсеть через обычные методы авторизации с гостевым токеном, UUID и данные сохраняются.
+// responses:
+//   200: GuestLoginResponse
+
+// swagger:response GuestLoginResponse
+type guestLoginResponse struct {
+	// in:body
+	Body request.AuthTokenResponse
+}
+
+// swagger:parameters GuestLoginRequest
+type guestLoginParams struct {
+	// in:body
+	Body request.GuestLoginRequest
+}
+
+// swagger:route POST /auth/qr auth QRChallengeRequest
+// Создание челленджа для входа по QR коду, payload отображается в QR коде, poll_token используется для проверки статуса. Челлендж действует 2 минуты.
+// responses:
+//   200: QRChallengeResponse
+
+// swagger:response QRChallengeResponse
+type qrChallengeResponse struct {
+	// in:body
+	Body request.Response
+}
+
+// swagger:route POST /auth/qr/status auth QRStatusRequest
+// Статус челленджа входа по QR коду, при wait=true запрос ожидает подтверждения до 25 секунд. Токены выдаются один раз после подтверждения.
+// responses:
+//   200: QRStatusResponse
+
+// swagger:response QRStatusResponse
+type qrStatusResponse struct {
+	// in:body
+	Body request.Response
+}
+
+// swagger:parameters QRStatusRequest
+type qrStatusParams struct {
+	// in:body
+	Body request.QRStatusRequest
+}
+
+// swagger:route POST /auth/qr/approve auth QRApproveRequest
+// Подтверждение входа по QR коду с авторизованного устройства.
+// security:
+//   - Bearer: []
+// responses:
+//   200: QRApproveResponse
+
+// swagger:response QRApproveResponse
+type qrApproveResponse struct {
+	// in:body
+	Body request.Response
+}
+
+// swagger:parameters QRApproveRequest
+type qrApproveParams struct {
+	// in:body
+	Body request.QRApproveRequest
+}
+
 // swagger:route POST /auth/oauth2/state auth Oauth2StateRequest
 // Авторизация с помощью state oauth2.
 // responses:
@@ -0,0 +1,17 @@
+package docs
+
+import "github.com/ptflp/go-light/request"
+
+// swagger:route GET /challenge challenge challengeRequest
+// Получение челленджа для защищенных методов (/auth/code, /auth/email/registration, /recover/password).
+// Для proof-of-work нужно подобрать nonce, при котором sha256(challenge + nonce) начинается с difficulty нулевых бит,
+// challenge передается в заголовке X-Challenge, nonce в заголовке X-Challenge-Solution.
+// Для captcha токен передается в заголовке X-Challenge-Solution.
+// responses:
+//   200: challengeResponse
+
+// swagger:response challengeResponse
+type challengeResponse struct {
+	// in:body
+	Body request.Response
+}
@@ -0,0 +1,48 @@
+package docs
+
+import "github.com/ptflp/go-light/request"
+
+// swagger:route POST /invites/create invites inviteCreateRequest
+// Создание инвайт кода, для пользователей действует квота, администратор может задать число использований и срок действия в часах.
+// security:
+//   - Bearer: []
+// responses:
+//   200: inviteCreateResponse
+
+// swagger:response inviteCreateResponse
+type inviteCreateResponse struct {
+	// in:body
+	Body request.Response
+}
+
+// swagger:parameters inviteCreateRequest
+type inviteCreateParams struct {
+	// in:body
+	Body request.InviteCreateReq
+}
+
+// swagger:route POST /invites/list invites inviteListRequest
+// Список созданных инвайт кодов.
+// security:
+//   - Bearer: []
+// responses:
+//   200: inviteListResponse
+
+// swagger:response inviteListResponse
+type inviteListResponse struct {
+	// in:body
+	Body request.Response
+}
+
+// swagger:route POST /invites/referrals invites inviteReferralsRequest
+// Список приглашенных пользователей.
+// security:
+//   - Bearer: []
+// responses:
+//   200: inviteReferralsResponse
+
+// swagger:response inviteReferralsResponse
+type inviteReferralsResponse struct {
+	// in:body
+	Body request.Response
+}
@@ -0,0 +1,35 @@
+package docs
+
+import "github.com/ptflp/go-light/request"
+
+// swagger:route GET /dev/mail/messages mail mailListRequest
+// Список перехваченных писем, доступен только в dev режиме с email.backend: catcher.
+// Веб интерфейс входящих доступен по адресу /dev/mail.
+// responses:
+//   200: mailListResponse
+
+// swagger:response mailListResponse
+type mailListResponse struct {
+	// in:body
+	Body request.Response
+}
+
+// swagger:route GET /dev/mail/{messageID} mail mailMessageRequest
+// Просмотр перехваченного письма. По умолчанию возвращается html страница, format=json возвращает заголовки, текст, html и список вложений.
+// Части письма доступны по адресам /dev/mail/{messageID}/html, /text, /raw и /attachments/{index}.
+// responses:
+//   200: mailMessageResponse
+
+// swagger:response mailMessageResponse
+type mailMessageResponse struct {
+	// in:body
+	Body request.Response
+}
+
+// swagger:parameters mailMessageRequest
+type mailMessageParams struct {
+	// in:path
+	MessageID string `json:"messageID"`
+	// in:query
+	Format string `json:"format"`
+}
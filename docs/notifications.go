@@ -0,0 +1,151 @@
+package docs
+
+import "github.com/ptflp/go-light/request"
+
+// swagger:route GET /email/unsubscribe notifications unsubscribePageRequest
+// Страница подтверждения отписки по ссылке из письма, отписка выполняется только POST запросом.
+// responses:
+//   200: unsubscribePageResponse
+
+// swagger:response unsubscribePageResponse
+type unsubscribePageResponse struct {
+	// in:body
+	Body string
+}
+
+// swagger:parameters unsubscribePageRequest unsubscribeRequest
+type unsubscribeParams struct {
+	// in:query
+	Token string `json:"token"`
+}
+
+// swagger:route POST /email/unsubscribe notifications unsubscribeRequest
+// Отписка в один клик (RFC 8058), ссылка передается почтовым клиентам в заголовке List-Unsubscribe.
+// Категория all отключает все email уведомления. Браузерам возвращается html страница.
+// responses:
+//   200: unsubscribeResponse
+
+// swagger:response unsubscribeResponse
+type unsubscribeResponse struct {
+	// in:body
+	Body request.Response
+}
+
+// swagger:route GET /profile/notifications notifications notificationsRequest
+// Настройки уведомлений: email, telegram, push и подписка на категории.
+// security:
+//   - Bearer: []
+// responses:
+//   200: notificationsResponse
+
+// swagger:response notificationsResponse
+type notificationsResponse struct {
+	// in:body
+	Body request.Response
+}
+
+// swagger:route POST /profile/notifications/email notifications notificationsEmailRequest
+// Включение или отключение email уведомлений категории (all, activity, news).
+// security:
+//   - Bearer: []
+// responses:
+//   200: notificationsResponse
+
+// swagger:parameters notificationsEmailRequest
+type notificationsEmailParams struct {
+	// in:body
+	Body request.NotificationPreferenceReq
+}
+
+// swagger:route POST /email/bounces notifications bouncesRequest
+// Прием отчетов о недоставке и жалобах, адреса добавляются в список подавления и больше не получают писем.
+// Принимается json ({"email", "type": "bounce"|"complaint", "details"} или список), multipart/report с delivery-status (RFC 3464)
+// или feedback-report (RFC 5965). Учитываются только постоянные ошибки доставки (5.x.x). Требуется заголовок X-Bounce-Secret.
+// responses:
+//   200: bouncesResponse
+
+// swagger:response bouncesResponse
+type bouncesResponse struct {
+	// in:body
+	Body request.Response
+}
+
+// swagger:parameters bouncesRequest
+type bouncesParams struct {
+	// in:header
+	Secret string `json:"X-Bounce-Secret"`
+}
+
+// swagger:route POST /profile/notifications/telegram/link notifications telegramLinkRequest
+// Ссылка на telegram бота, при ее открытии чат привязывается к аккаунту. Ссылка действует 15 минут.
+// security:
+//   - Bearer: []
+// responses:
+//   200: telegramLinkResponse
+
+// swagger:response telegramLinkResponse
+type telegramLinkResponse struct {
+	// in:body
+	Body request.Response
+}
+
+// swagger:route POST /profile/notifications/telegram/unlink notifications telegramUnlinkRequest
+// Отвязка telegram чата.
+// security:
+//   - Bearer: []
+// responses:
+//   200: notificationsResponse
+
+// swagger:route POST /telegram/webhook notifications telegramWebhookRequest
+// Webhook telegram бота: /start с токеном ссылки привязывает чат, /stop отвязывает. Требуется заголовок X-Telegram-Bot-Api-Secret-Token.
+// responses:
+//   200: telegramWebhookResponse
+
+// swagger:response telegramWebhookResponse
+type telegramWebhookResponse struct {
+	// in:body
+	Body request.Response
+}
+
+// swagger:parameters telegramWebhookRequest
+type telegramWebhookParams struct {
+	// in:header
+	Secret string `json:"X-Telegram-Bot-Api-Secret-Token"`
+}
+
+// swagger:route GET /push/key notifications pushKeyRequest
+// Публичный VAPID ключ для PushManager.subscribe (applicationServerKey).
+// responses:
+//   200: pushKeyResponse
+
+// swagger:response pushKeyResponse
+type pushKeyResponse struct {
+	// in:body
+	Body request.Response
+}
+
+// swagger:route POST /profile/notifications/push/subscribe notifications pushSubscribeRequest
+// Сохранение push подписки устройства (результат PushSubscription.toJSON()). Подписки, отклоненные push сервисом (404, 410), удаляются.
+// security:
+//   - Bearer: []
+// responses:
+//   200: notificationsResponse
+
+// swagger:parameters pushSubscribeRequest
+type pushSubscribeParams struct {
+	// in:body
+	Body request.PushSubscriptionReq
+}
+
+// swagger:route POST /profile/notifications/push/unsubscribe notifications pushUnsubscribeRequest
+// Удаление push подписки устройства.
+// security:
+//   - Bearer: []
+// responses:
+//   200: notificationsResponse
+
+// swagger:parameters pushUnsubscribeRequest
+type pushUnsubscribeParams struct {
+	// in:body
+	Body request.PushUnsubscribeReq
+}
@@ -0,0 +1,138 @@
+package docs
+
+import "github.com/ptflp/go-light/request"
+
+// swagger:route GET /.well-known/openid-configuration oauth oidcDiscoveryRequest
+// OpenID Connect discovery документ.
+// responses:
+//   200: oidcDiscoveryResponse
+
+// swagger:response oidcDiscoveryResponse
+type oidcDiscoveryResponse struct {
+	// in:body
+	Body request.DiscoveryData
+}
+
+// swagger:route GET /oauth/jwks oauth oidcJWKSRequest
+// Публичные ключи для проверки подписи токенов.
+// responses:
+//   200: oidcJWKSResponse
+
+// swagger:response oidcJWKSResponse
+type oidcJWKSResponse struct {
+	// in:body
+	Body request.JWKSData
+}
+
+// swagger:route GET /oauth/authorize oauth oidcAuthorizeRequest
+// Начало authorization code flow, перенаправление на страницу согласия.
+// responses:
+//   302: oidcAuthorizeResponse
+
+// swagger:response oidcAuthorizeResponse
+type oidcAuthorizeResponse struct {
+	// in:header
+	Location string
+}
+
+// swagger:parameters oidcAuthorizeRequest
+type oidcAuthorizeParams struct {
+	// in:query
+	ResponseType string `json:"response_type"`
+	// in:query
+	ClientID string `json:"client_id"`
+	// in:query
+	RedirectURI string `json:"redirect_uri"`
+	// in:query
+	Scope string `json:"scope"`
+	// in:query
+	State string `json:"state"`
+	// in:query
+	Nonce string `json:"nonce"`
+	// in:query
+	CodeChallenge string `json:"code_challenge"`
+	// in:query
+	CodeChallengeMethod string `json:"code_challenge_method"`
+}
+
+// swagger:route POST /oauth/consent/data oauth oidcConsentDataRequest
+// Данные для экрана согласия.
+// security:
+//   - Bearer: []
+// responses:
+//   200: oidcConsentDataResponse
+
+// swagger:response oidcConsentDataResponse
+type oidcConsentDataResponse struct {
+	// in:body
+	Body request.ConsentData
+}
+
+// swagger:parameters oidcConsentDataRequest
+type oidcConsentDataParams struct {
+	// in:body
+	Body request.AuthorizeRequest
+}
+
+// swagger:route POST /oauth/consent oauth oidcConsentRequest
+// Согласие или отказ пользователя, возвращает адрес перенаправления с кодом авторизации.
+// security:
+//   - Bearer: []
+// responses:
+//   200: oidcConsentResponse
+
+// swagger:response oidcConsentResponse
+type oidcConsentResponse struct {
+	// in:body
+	Body request.ConsentResultData
+}
+
+// swagger:parameters oidcConsentRequest
+type oidcConsentParams struct {
+	// in:body
+	Body request.ConsentRequest
+}
+
+// swagger:route POST /oauth/token oauth oidcTokenRequest
+// Обмен кода авторизации или refresh токена на токены, application/x-www-form-urlencoded.
+// consumes:
+//   - application/x-www-form-urlencoded
+// responses:
+//   200: oidcTokenResponse
+
+// swagger:response oidcTokenResponse
+type oidcTokenResponse struct {
+	// in:body
+	Body request.TokenData
+}
+
+// swagger:route GET /oauth/userinfo oauth oidcUserInfoRequest
+// Данные пользователя по access токену клиента.
+// responses:
+//   200: oidcUserInfoResponse
+
+// swagger:response oidcUserInfoResponse
+type oidcUserInfoResponse struct {
+	// in:body
+	Body map[string]interface{}
+}
+
+// swagger:route POST /oauth/clients/create oauth oidcClientCreateRequest
+// Регистрация клиента, доступно администратору.
+// Адреса возврата: https, http только на loopback, схемы приложений из OIDC.redirectSchemes.
+// security:
+//   - Bearer: []
+// responses:
+//   200: oidcClientCreateResponse
+
+// swagger:response oidcClientCreateResponse
+type oidcClientCreateResponse struct {
+	// in:body
+	Body request.Response
+}
+
+// swagger:parameters oidcClientCreateRequest
+type oidcClientCreateParams struct {
+	// in:body
+	Body request.OAuthClientCreateReq
+}
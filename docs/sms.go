@@ -0,0 +1,20 @@
+package docs
+
+import "github.com/ptflp/go-light/request"
+
+// swagger:route POST /sms/status/{provider} sms smsStatusRequest
+// Прием статуса доставки sms от провайдера. Для smsc адрес указывается в настройках аккаунта, запрос проверяется подписью sha1.
+// responses:
+//   200: smsStatusResponse
+
+// swagger:response smsStatusResponse
+type smsStatusResponse struct {
+	// in:body
+	Body request.Response
+}
+
+// swagger:parameters smsStatusRequest
+type smsStatusParams struct {
+	// in:path
+	Provider string `json:"provider"`
+}
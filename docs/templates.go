@@ -0,0 +1,36 @@
+package docs
+
+import "github.com/ptflp/go-light/request"
+
+// swagger:route GET /dev/templates templates templatesListRequest
+// Список шаблонов писем и языков, доступен только в dev режиме.
+// responses:
+//   200: templatesListResponse
+
+// swagger:response templatesListResponse
+type templatesListResponse struct {
+	// in:body
+	Body request.Response
+}
+
+// swagger:route GET /dev/templates/{name} templates templatesPreviewRequest
+// Предпросмотр шаблона письма с тестовыми данными, доступен только в dev режиме.
+// По умолчанию возвращается html страница, format=text возвращает текстовую версию, format=json тему, html и текст.
+// responses:
+//   200: templatesPreviewResponse
+
+// swagger:response templatesPreviewResponse
+type templatesPreviewResponse struct {
+	// in:body
+	Body request.Response
+}
+
+// swagger:parameters templatesPreviewRequest
+type templatesPreviewParams struct {
+	// in:path
+	Name string `json:"name"`
+	// in:query
+	Lang string `json:"lang"`
+	// in:query
+	Format string `json:"format"`
+}
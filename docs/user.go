@@ -147,3 +147,89 @@ type peopleAutocompleteParams struct {
 	// in:body
 	Body request.UserNicknameRequest
 }
+
+// swagger:route GET /profile profile profileRequest
+// Профиль текущего пользователя, при входе администратора от имени пользователя выставлен флаг impersonated.
+// security:
+//   - Bearer: []
+// responses:
+//   200: profileResponse
+
+// swagger:response profileResponse
+type profileResponse struct {
+	// in:body
+	Body request.Response
+}
+
+// swagger:route POST /profile/password profile profilePasswordRequest
+// Установка или смена пароля, недоступно при входе администратора от имени пользователя.
+// security:
+//   - Bearer: []
+// responses:
+//   200: profilePasswordResponse
+
+// swagger:response profilePasswordResponse
+type profilePasswordResponse struct {
+	// in:body
+	Body request.Response
+}
+
+// swagger:parameters profilePasswordRequest
+type profilePasswordParams struct {
+	// in:body
+	Body request.SetPasswordReq
+}
+
+// swagger:route POST /profile/delete profile profileDeleteRequest
+// Удаление аккаунта, восстановление возможно при повторной авторизации в течение льготного периода.
+// security:
+//   - Bearer: []
+// responses:
+//   200: profileDeleteResponse
+
+// swagger:response profileDeleteResponse
+type profileDeleteResponse struct {
+	// in:body
+	Body request.Response
+}
+
+// swagger:parameters profileDeleteRequest
+type profileDeleteParams struct {
+	// in:body
+	Body request.DeleteAccountReq
+}
+
+// swagger:route POST /profile/export profile profileExportRequest
+// Запрос архива с персональными данными, ссылка на скачивание будет отправлена на почту.
+// security:
+//   - Bearer: []
+// responses:
+//   200: profileExportResponse
+
+// swagger:response profileExportResponse
+type profileExportResponse struct {
+	// in:body
+	Body request.Response
+}
+
+// swagger:route GET /export/{exportID} profile exportDownloadRequest
+// Скачивание архива с персональными данными, архив доступен только владельцу.
+// Письмо ведет на страницу фронтенда /export/{exportID}, которая скачивает архив с токеном пользователя.
+// security:
+//   - Bearer: []
+// produces:
+//   - application/zip
+// responses:
+//   200: exportDownloadResponse
+
+// swagger:response exportDownloadResponse
+type exportDownloadResponse struct {
+	// in:body
+	Body []byte
+}
+
+// swagger:parameters exportDownloadRequest
+type exportDownloadParams struct {
+	// in:path
+	ExportID string `json:"exportID"`
+}
@@ -0,0 +1,52 @@
+package email
+
+import (
+	"errors"
+	"net/smtp"
+	"strings"
+)
+
+const (
+	MechPlain   = "PLAIN"
+	MechLogin   = "LOGIN"
+	MechCRAMMD5 = "CRAM-MD5"
+)
+
+type loginAuth struct {
+	username, password string
+	host               string
+}
+
+// LoginAuth implements LOGIN mechanism, like smtp.PlainAuth credentials are sent over tls or to localhost only
+func LoginAuth(username, password, host string) smtp.Auth {
+	return &loginAuth{username: username, password: password, host: host}
+}
+
+func (a *loginAuth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	if !server.TLS && !isLocalhost(server.Name) {
+		return "", nil, errors.New("unencrypted connection")
+	}
+	if server.Name != a.host {
+		return "", nil, errors.New("wrong host name")
+	}
+
+	return MechLogin, nil, nil
+}
+
+func (a *loginAuth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if !more {
+		return nil, nil
+	}
+	switch prompt := strings.ToLower(strings.TrimSpace(string(fromServer))); {
+	case strings.HasPrefix(prompt, "username"):
+		return []byte(a.username), nil
+	case strings.HasPrefix(prompt, "password"):
+		return []byte(a.password), nil
+	default:
+		return nil, errors.New("unexpected server challenge: " + prompt)
+	}
+}
+
+func isLocalhost(name string) bool {
+	return name == "localhost" || name == "127.0.0.1" || name == "::1"
+}
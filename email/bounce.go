@@ -0,0 +1,248 @@
+package email
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"net/textproto"
+	"strings"
+
+	"github.com/ptflp/go-light/types"
+)
+
+const (
+	BounceTypeBounce    = "bounce"
+	BounceTypeComplaint = "complaint"
+
+	bounceDetailsLength = 512
+)
+
+// Bounce address to be suppressed parsed from bounce or complaint report
+type Bounce struct {
+	Email   string `json:"email"`
+	Type    string `json:"type"`
+	Details string `json:"details"`
+}
+
+// Reason suppression reason of bounce type
+func (b Bounce) Reason() int64 {
+	if b.Type == BounceTypeComplaint {
+		return types.SuppressionComplaint
+	}
+
+	return types.SuppressionHardBounce
+}
+
+// ParseReport parses json list of bounces, delivery status notification (RFC 3464) or abuse report (RFC 5965),
+// soft bounces of DSN are skipped
+func ParseReport(contentType string, body []byte) ([]Bounce, error) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, err
+	}
+
+	var bounces []Bounce
+	switch mediaType {
+	case "application/json":
+		bounces, err = parseJSONReport(body)
+	case "multipart/report":
+		bounces, err = parseMultipartReport(body, params["boundary"])
+	case "message/rfc822":
+		// report wrapped into full message with headers
+		var msg *mail.Message
+		msg, err = mail.ReadMessage(bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		var data []byte
+		data, err = ioutil.ReadAll(msg.Body)
+		if err != nil {
+			return nil, err
+		}
+		return ParseReport(msg.Header.Get("Content-Type"), data)
+	default:
+		return nil, fmt.Errorf("unsupported report type %s", mediaType)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	valid := bounces[:0]
+	for i := range bounces {
+		addr, err := mail.ParseAddress(bounces[i].Email)
+		if err != nil {
+			continue
+		}
+		bounces[i].Email = strings.ToLower(addr.Address)
+		bounces[i].Details = truncate(bounces[i].Details, bounceDetailsLength)
+		valid = append(valid, bounces[i])
+	}
+
+	return valid, nil
+}
+
+func parseJSONReport(body []byte) ([]Bounce, error) {
+	body = bytes.TrimSpace(body)
+	var bounces []Bounce
+	if bytes.HasPrefix(body, []byte("{")) {
+		var bounce Bounce
+		if err := json.Unmarshal(body, &bounce); err != nil {
+			return nil, err
+		}
+		bounces = append(bounces, bounce)
+	} else if err := json.Unmarshal(body, &bounces); err != nil {
+		return nil, err
+	}
+	for i := range bounces {
+		if bounces[i].Type != BounceTypeBounce && bounces[i].Type != BounceTypeComplaint {
+			return nil, fmt.Errorf("unknown bounce type %q", bounces[i].Type)
+		}
+	}
+
+	return bounces, nil
+}
+
+func parseMultipartReport(body []byte, boundary string) ([]Bounce, error) {
+	if boundary == "" {
+		return nil, errors.New("report boundary is missing")
+	}
+
+	var bounces []Bounce
+	var complaint bool
+	var original string
+	reader := multipart.NewReader(bytes.NewReader(body), boundary)
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		data, err := ioutil.ReadAll(part)
+		if err != nil {
+			return nil, err
+		}
+		mediaType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		switch mediaType {
+		case "message/delivery-status":
+			var parsed []Bounce
+			parsed, err = parseDeliveryStatus(data)
+			bounces = append(bounces, parsed...)
+		case "message/feedback-report":
+			complaint = true
+			var parsed []Bounce
+			parsed, err = parseFeedbackReport(data)
+			bounces = append(bounces, parsed...)
+		case "message/rfc822", "text/rfc822-headers":
+			original = originalRecipient(data)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	// Original-Rcpt-To is optional in abuse reports, recipient of original message is used
+	if complaint && len(bounces) == 0 && original != "" {
+		bounces = append(bounces, Bounce{Email: original, Type: BounceTypeComplaint, Details: "abuse"})
+	}
+
+	return bounces, nil
+}
+
+// parseDeliveryStatus reads per-recipient fields of delivery status, only permanent failures are returned
+func parseDeliveryStatus(data []byte) ([]Bounce, error) {
+	groups, err := readFieldGroups(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var bounces []Bounce
+	// first group holds per-message fields
+	for i := 1; i < len(groups); i++ {
+		fields := groups[i]
+		if !strings.EqualFold(fields.Get("Action"), "failed") || !strings.HasPrefix(fields.Get("Status"), "5") {
+			continue
+		}
+		details := fields.Get("Status")
+		if diagnostic := fields.Get("Diagnostic-Code"); diagnostic != "" {
+			details += " " + diagnostic
+		}
+		bounces = append(bounces, Bounce{
+			Email:   typedAddress(fields.Get("Final-Recipient")),
+			Type:    BounceTypeBounce,
+			Details: details,
+		})
+	}
+
+	return bounces, nil
+}
+
+func parseFeedbackReport(data []byte) ([]Bounce, error) {
+	groups, err := readFieldGroups(data)
+	if err != nil || len(groups) == 0 {
+		return nil, err
+	}
+
+	var bounces []Bounce
+	fields := groups[0]
+	for _, rcpt := range fields.Values("Original-Rcpt-To") {
+		bounces = append(bounces, Bounce{
+			Email:   typedAddress(rcpt),
+			Type:    BounceTypeComplaint,
+			Details: fields.Get("Feedback-Type"),
+		})
+	}
+
+	return bounces, nil
+}
+
+// readFieldGroups reads header-like field groups separated by blank lines
+func readFieldGroups(data []byte) ([]textproto.MIMEHeader, error) {
+	reader := textproto.NewReader(bufio.NewReader(bytes.NewReader(data)))
+	var groups []textproto.MIMEHeader
+	for {
+		fields, err := reader.ReadMIMEHeader()
+		if len(fields) > 0 {
+			groups = append(groups, fields)
+		}
+		if err == io.EOF {
+			return groups, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// typedAddress strips address type of "rfc822; user@example.com"
+func typedAddress(value string) string {
+	if i := strings.Index(value, ";"); i >= 0 {
+		value = value[i+1:]
+	}
+
+	return strings.TrimSpace(value)
+}
+
+func originalRecipient(data []byte) string {
+	msg, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		// text/rfc822-headers has no body separator
+		msg, err = mail.ReadMessage(io.MultiReader(bytes.NewReader(data), strings.NewReader("\r\n\r\n")))
+		if err != nil {
+			return ""
+		}
+	}
+	addrs, err := msg.Header.AddressList("To")
+	if err != nil || len(addrs) == 0 {
+		return ""
+	}
+
+	return addrs[0].Address
+}
@@ -0,0 +1,335 @@
+package email
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ptflp/go-light/config"
+	"go.uber.org/zap"
+)
+
+const (
+	catcherLimit     = 500
+	catcherExtension = ".eml"
+)
+
+var ErrMessageNotFound = errors.New("message not found")
+
+// Catcher development Mailer, messages are kept in memory and stored as .eml files instead of being sent
+type Catcher struct {
+	cfg    *config.Email
+	logger *zap.Logger
+
+	mu       sync.RWMutex
+	messages []*CaughtMessage
+}
+
+// CaughtMessage envelope and summary of caught message
+type CaughtMessage struct {
+	ID         string    `json:"message_id"`
+	Sender     string    `json:"sender"`
+	Recipients []string  `json:"recipients"`
+	From       string    `json:"from"`
+	To         string    `json:"to"`
+	Subject    string    `json:"subject"`
+	Date       time.Time `json:"date"`
+	Size       int       `json:"size"`
+
+	raw []byte
+}
+
+// CaughtContent decoded parts of caught message
+type CaughtContent struct {
+	Text        string             `json:"text"`
+	HTML        string             `json:"html"`
+	Attachments []CaughtAttachment `json:"attachments"`
+}
+
+type CaughtAttachment struct {
+	FileName    string `json:"file_name"`
+	ContentType string `json:"content_type"`
+	ContentID   string `json:"content_id,omitempty"`
+	Size        int    `json:"size"`
+
+	Data []byte `json:"-"`
+}
+
+// NewCatcher loads messages stored in CatcherDir, messages are kept in memory only when directory is not set
+func NewCatcher(cfg *config.Email, logger *zap.Logger) (*Catcher, error) {
+	c := &Catcher{cfg: cfg, logger: logger}
+	if cfg.CatcherDir == "" {
+		return c, nil
+	}
+	if err := os.MkdirAll(cfg.CatcherDir, 0755); err != nil {
+		return nil, err
+	}
+
+	files, err := filepath.Glob(filepath.Join(cfg.CatcherDir, "*"+catcherExtension))
+	if err != nil {
+		return nil, err
+	}
+	// ids start with timestamp, name order is arrival order
+	sort.Strings(files)
+	for _, file := range files {
+		raw, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		msg, err := newCaughtMessage(strings.TrimSuffix(filepath.Base(file), catcherExtension), raw)
+		if err != nil {
+			logger.Warn("skip caught message", zap.String("file", file), zap.Error(err))
+			continue
+		}
+		c.messages = append(c.messages, msg)
+	}
+	c.trim()
+
+	return c, nil
+}
+
+func (c *Catcher) Send(msg Messager) error {
+	if msg.GetFrom() == "" {
+		msg.SetFrom(c.cfg.From)
+	}
+	err := msg.Validate()
+	if err != nil {
+		return err
+	}
+
+	return c.SendRaw(envelopeSender(msg, c.cfg), msg.Recipients(), msg.Bytes())
+}
+
+// SendRaw stores message with envelope in Return-Path and X-Envelope-To headers
+func (c *Catcher) SendRaw(sender string, rcpts []string, data []byte) error {
+	var raw bytes.Buffer
+	writeHeader(&raw, "Return-Path", "<"+sender+">")
+	writeHeader(&raw, "X-Envelope-To", strings.Join(rcpts, ", "))
+	raw.Write(data)
+
+	id := fmt.Sprintf("%d-%s", time.Now().UnixNano(), randomID()[:8])
+	msg, err := newCaughtMessage(id, raw.Bytes())
+	if err != nil {
+		return err
+	}
+	if c.cfg.CatcherDir != "" {
+		err = ioutil.WriteFile(filepath.Join(c.cfg.CatcherDir, id+catcherExtension), msg.raw, 0644)
+		if err != nil {
+			return err
+		}
+	}
+
+	c.mu.Lock()
+	c.messages = append(c.messages, msg)
+	c.trim()
+	c.mu.Unlock()
+	c.logger.Info("email caught", zap.String("id", id), zap.Strings("to", rcpts), zap.String("subject", msg.Subject))
+
+	return nil
+}
+
+func (c *Catcher) Close() error {
+	return nil
+}
+
+// List caught messages, newest first
+func (c *Catcher) List() []CaughtMessage {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	list := make([]CaughtMessage, 0, len(c.messages))
+	for i := len(c.messages) - 1; i >= 0; i-- {
+		list = append(list, *c.messages[i])
+	}
+
+	return list
+}
+
+func (c *Catcher) Get(id string) (CaughtMessage, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for i := range c.messages {
+		if c.messages[i].ID == id {
+			return *c.messages[i], nil
+		}
+	}
+
+	return CaughtMessage{}, ErrMessageNotFound
+}
+
+// Raw message as stored in .eml file
+func (c *Catcher) Raw(id string) ([]byte, error) {
+	msg, err := c.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return msg.raw, nil
+}
+
+// Content decodes text, html and attachments of message
+func (c *Catcher) Content(id string) (CaughtContent, error) {
+	raw, err := c.Raw(id)
+	if err != nil {
+		return CaughtContent{}, err
+	}
+	m, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return CaughtContent{}, err
+	}
+
+	content := CaughtContent{Attachments: []CaughtAttachment{}}
+	err = readContent(&content, m.Header.Get("Content-Type"), m.Header.Get("Content-Transfer-Encoding"), m.Header.Get("Content-Disposition"), m.Header.Get("Content-ID"), m.Body)
+
+	return content, err
+}
+
+// Clear removes all messages
+func (c *Catcher) Clear() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i := range c.messages {
+		if err := c.remove(c.messages[i]); err != nil {
+			return err
+		}
+	}
+	c.messages = nil
+
+	return nil
+}
+
+// trim drops oldest messages over limit, must be called with lock held
+func (c *Catcher) trim() {
+	if len(c.messages) <= catcherLimit {
+		return
+	}
+	drop := len(c.messages) - catcherLimit
+	for i := 0; i < drop; i++ {
+		if err := c.remove(c.messages[i]); err != nil {
+			c.logger.Warn("remove caught message", zap.String("id", c.messages[i].ID), zap.Error(err))
+		}
+	}
+	c.messages = append([]*CaughtMessage(nil), c.messages[drop:]...)
+}
+
+func (c *Catcher) remove(msg *CaughtMessage) error {
+	if c.cfg.CatcherDir == "" {
+		return nil
+	}
+	err := os.Remove(filepath.Join(c.cfg.CatcherDir, msg.ID+catcherExtension))
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	return err
+}
+
+func newCaughtMessage(id string, raw []byte) (*CaughtMessage, error) {
+	m, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return nil, err
+	}
+	dec := new(mime.WordDecoder)
+	decode := func(name string) string {
+		value, err := dec.DecodeHeader(m.Header.Get(name))
+		if err != nil {
+			return m.Header.Get(name)
+		}
+		return value
+	}
+
+	msg := &CaughtMessage{
+		ID:      id,
+		Sender:  strings.Trim(m.Header.Get("Return-Path"), "<>"),
+		From:    decode("From"),
+		To:      decode("To"),
+		Subject: decode("Subject"),
+		Size:    len(raw),
+		raw:     raw,
+	}
+	for _, rcpt := range strings.Split(m.Header.Get("X-Envelope-To"), ",") {
+		if rcpt = strings.TrimSpace(rcpt); rcpt != "" {
+			msg.Recipients = append(msg.Recipients, rcpt)
+		}
+	}
+	msg.Date, err = m.Header.Date()
+	if err != nil {
+		msg.Date = time.Now()
+	}
+
+	return msg, nil
+}
+
+// readContent walks mime tree, first text parts are message bodies, parts with file name are attachments
+func readContent(content *CaughtContent, contentType, encoding, disposition, contentID string, body io.Reader) error {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = TypePlain
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := mr.NextRawPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			h := part.Header
+			err = readContent(content, h.Get("Content-Type"), h.Get("Content-Transfer-Encoding"), h.Get("Content-Disposition"), h.Get("Content-ID"), part)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	switch strings.ToLower(encoding) {
+	case "base64":
+		body = base64.NewDecoder(base64.StdEncoding, body)
+	case "quoted-printable":
+		body = quotedprintable.NewReader(body)
+	}
+	data, err := ioutil.ReadAll(body)
+	if err != nil {
+		return err
+	}
+
+	_, dispositionParams, _ := mime.ParseMediaType(disposition)
+	fileName := dispositionParams["filename"]
+	if fileName == "" {
+		fileName = params["name"]
+	}
+	switch {
+	case fileName == "" && mediaType == TypePlain && content.Text == "":
+		content.Text = string(data)
+	case fileName == "" && mediaType == TypeHtml && content.HTML == "":
+		content.HTML = string(data)
+	default:
+		content.Attachments = append(content.Attachments, CaughtAttachment{
+			FileName:    fileName,
+			ContentType: mediaType,
+			ContentID:   strings.Trim(contentID, "<>"),
+			Size:        len(data),
+			Data:        data,
+		})
+	}
+
+	return nil
+}
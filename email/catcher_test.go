@@ -0,0 +1,86 @@
+package email
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ptflp/go-light/config"
+	"go.uber.org/zap"
+)
+
+func TestCatcher(t *testing.T) {
+	dir, err := ioutil.TempDir("", "catcher")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	cfg := &config.Email{From: "noreply@example.com", CatcherDir: dir}
+
+	c, err := NewCatcher(cfg, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := testMsg("user@example.com")
+	msg.AddBCC("hidden@example.com")
+	msg.SetSubject("Восстановление пароля")
+	msg.SetType(TypeHtml)
+	cid := msg.Embed(*bytes.NewBuffer([]byte("\x89PNG\r\n\x1a\n")), "logo.png")
+	msg.SetBody(*bytes.NewBufferString(`<p>Привет</p><img src="cid:` + cid + `">`))
+	msg.Attach(*bytes.NewBufferString("a,b\n1,2\n"), "report.csv")
+	if err = c.Send(msg); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if err = c.Send(testMsg("second@example.com")); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if len(files) != 2 {
+		t.Fatalf("eml files = %d, want 2", len(files))
+	}
+
+	// messages are restored from directory
+	c, err = NewCatcher(cfg, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	list := c.List()
+	if len(list) != 2 || list[0].To != "<second@example.com>" {
+		t.Fatalf("List() = %+v", list)
+	}
+	caught := list[1]
+	if caught.Subject != "Восстановление пароля" || caught.Sender != "noreply@example.com" || len(caught.Recipients) != 2 || caught.Recipients[1] != "hidden@example.com" {
+		t.Errorf("caught message = %+v", caught)
+	}
+
+	content, err := c.Content(caught.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content.Text != "Привет" || content.HTML != `<p>Привет</p><img src="cid:`+cid+`">` {
+		t.Errorf("content text = %q, html = %q", content.Text, content.HTML)
+	}
+	if len(content.Attachments) != 2 {
+		t.Fatalf("attachments = %+v", content.Attachments)
+	}
+	if a := content.Attachments[0]; a.ContentID != cid || a.ContentType != "image/png" || a.FileName != "logo.png" {
+		t.Errorf("inline = %+v", a)
+	}
+	if a := content.Attachments[1]; a.FileName != "report.csv" || string(a.Data) != "a,b\n1,2\n" {
+		t.Errorf("attachment = %+v, data %q", a, a.Data)
+	}
+
+	if err = c.Clear(); err != nil {
+		t.Fatal(err)
+	}
+	files, _ = filepath.Glob(filepath.Join(dir, "*.eml"))
+	if len(c.List()) != 0 || len(files) != 0 {
+		t.Errorf("after Clear() messages = %d, files = %d", len(c.List()), len(files))
+	}
+	if _, err = c.Get(caught.ID); err != ErrMessageNotFound {
+		t.Errorf("Get() error = %v, want %v", err, ErrMessageNotFound)
+	}
+}
@@ -2,96 +2,394 @@ package email
 
 import (
 	"crypto/tls"
+	"errors"
 	"fmt"
+	"net"
+	"net/mail"
 	"net/smtp"
+	"net/textproto"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/ptflp/go-light/config"
 
 	"go.uber.org/zap"
 )
 
+const dialTimeout = 15 * time.Second
+
+var ErrClientClosed = errors.New("smtp client closed")
+
+// Client smtp client with bounded pool of connections, connection is used by single Send at a time
 type Client struct {
-	smtpc  *smtp.Client
 	logger *zap.Logger
 	cfg    *config.Email
+	signer *DKIMSigner
+
+	// slots limits number of open connections
+	slots chan struct{}
+	mu    sync.Mutex
+	idle  []*conn
+	done  chan struct{}
+	once  sync.Once
+}
+
+type conn struct {
+	*smtp.Client
+	lastUsed time.Time
 }
 
 func NewClient(cfg *config.Email, logger *zap.Logger) *Client {
 	c := &Client{
 		logger: logger,
 		cfg:    cfg,
+		slots:  make(chan struct{}, cfg.Connections()),
+		done:   make(chan struct{}),
 	}
 
 	return c
 }
 
+// RecipientsError reports recipients rejected by server, message is delivered to the rest unless all are rejected
+type RecipientsError struct {
+	Rejected map[string]error
+	// Delivered false when every recipient is rejected
+	Delivered bool
+}
+
+func (e *RecipientsError) Error() string {
+	rcpts := make([]string, 0, len(e.Rejected))
+	for rcpt, err := range e.Rejected {
+		rcpts = append(rcpts, fmt.Sprintf("%s: %s", rcpt, err))
+	}
+	sort.Strings(rcpts)
+
+	return "smtp recipients rejected: " + strings.Join(rcpts, "; ")
+}
+
+// MessageError server reply to MAIL or DATA of a single message, errors of dial, TLS and AUTH are not wrapped
+// as they are not caused by message
+type MessageError struct {
+	Err error
+}
+
+func (e *MessageError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *MessageError) Unwrap() error {
+	return e.Err
+}
+
+// UseDKIM signs every outgoing message
+func (c *Client) UseDKIM(signer *DKIMSigner) {
+	c.signer = signer
+}
+
 func (c *Client) Send(msg Messager) error {
-	msg.SetFrom(c.cfg.From)
+	if msg.GetFrom() == "" {
+		msg.SetFrom(c.cfg.From)
+	}
 	err := msg.Validate()
 	if err != nil {
 		return err
 	}
-	err = c.connect()
+
+	return c.SendRaw(envelopeSender(msg, c.cfg), msg.Recipients(), msg.Bytes())
+}
+
+// SendRaw delivers already serialized message
+func (c *Client) SendRaw(sender string, rcpts []string, data []byte) error {
+	if c.signer != nil {
+		signed, err := c.signer.Sign(data)
+		if err != nil {
+			c.logger.Error("dkim sign", zap.Error(err))
+			return err
+		}
+		data = signed
+	}
+
+	cn, err := c.acquire()
 	if err != nil {
 		return err
 	}
-	rclient := c.smtpc
 
-	if err = rclient.Rcpt(msg.GetReceiver()); err != nil {
-		c.logger.Error("set email receiver", zap.String("To:", msg.GetReceiver()), zap.Error(err))
-		return err
+	err = c.send(cn, sender, rcpts, data)
+	c.release(cn, err)
+
+	return err
+}
+
+func (c *Client) send(cn *conn, sender string, rcpts []string, data []byte) error {
+	if err := cn.Mail(sender); err != nil {
+		c.logger.Error("start mail transaction", zap.Error(err))
+		return &MessageError{Err: err}
+	}
+
+	var rcptErr *RecipientsError
+	for _, rcpt := range rcpts {
+		err := cn.Rcpt(rcpt)
+		if err == nil {
+			continue
+		}
+		var protoErr *textproto.Error
+		if !errors.As(err, &protoErr) {
+			c.logger.Error("set email receiver", zap.String("To:", rcpt), zap.Error(err))
+			return err
+		}
+		c.logger.Warn("email receiver rejected", zap.String("To:", rcpt), zap.Error(err))
+		if rcptErr == nil {
+			rcptErr = &RecipientsError{Rejected: make(map[string]error)}
+		}
+		rcptErr.Rejected[rcpt] = err
 	}
-	writer, err := rclient.Data()
+	if rcptErr != nil && len(rcptErr.Rejected) == len(rcpts) {
+		return rcptErr
+	}
+
+	writer, err := cn.Data()
 	if err != nil {
 		c.logger.Error("smpt client Data()", zap.Error(err))
-		return err
+		return &MessageError{Err: err}
 	}
 
 	//write into email client stream writter
-	if _, err = writer.Write(msg.Bytes()); err != nil {
+	if _, err = writer.Write(data); err != nil {
 		c.logger.Error("write content into client writter I/O", zap.Error(err))
 		return err
 	}
 
 	if err = writer.Close(); err != nil {
 		c.logger.Error("smtp writer close", zap.Error(err))
+		return &MessageError{Err: err}
+	}
+	if rcptErr != nil {
+		rcptErr.Delivered = true
+		return rcptErr
 	}
 
-	return err
+	return nil
 }
 
-func (c *Client) connect() error {
-	tlsConfig := tls.Config{
-		ServerName:         c.cfg.ServerAddress,
-		InsecureSkipVerify: true,
+// envelopeSender message sender overrides configured one, falls back to From header and login
+func envelopeSender(msg Messager, cfg *config.Email) string {
+	candidates := []string{msg.GetSender(), cfg.Sender, msg.GetFrom(), cfg.From, cfg.Login}
+	for _, sender := range candidates {
+		if sender == "" {
+			continue
+		}
+		if addr, err := mail.ParseAddress(sender); err == nil {
+			return addr.Address
+		}
+
+		return sender
+	}
+
+	return ""
+}
+
+// Close quits idle connections, connections in use are closed on release
+func (c *Client) Close() error {
+	c.once.Do(func() {
+		close(c.done)
+	})
+
+	c.mu.Lock()
+	idle := c.idle
+	c.idle = nil
+	c.mu.Unlock()
+
+	for i := range idle {
+		_ = idle[i].Quit()
 	}
 
-	conn, err := tls.Dial("tcp", fmt.Sprintf("%s:%s", c.cfg.ServerAddress, c.cfg.Port), &tlsConfig)
+	return nil
+}
+
+// acquire takes idle connection or dials new one when pool is not exhausted, blocks otherwise
+func (c *Client) acquire() (*conn, error) {
+	select {
+	case <-c.done:
+		return nil, ErrClientClosed
+	default:
+	}
+	select {
+	case <-c.done:
+		return nil, ErrClientClosed
+	case c.slots <- struct{}{}:
+	}
+
+	for {
+		cn := c.popIdle()
+		if cn == nil {
+			break
+		}
+		if time.Since(cn.lastUsed) > c.cfg.IdleLifetime() {
+			_ = cn.Quit()
+			continue
+		}
+		// reset drops state of previous transaction and checks connection is alive
+		if err := cn.Reset(); err != nil {
+			_ = cn.Close()
+			continue
+		}
+
+		return cn, nil
+	}
+
+	cn, err := c.dial()
 	if err != nil {
-		c.logger.Error("TLS connection", zap.Error(err))
-		return err
+		<-c.slots
+		return nil, err
+	}
+
+	return cn, nil
+}
+
+func (c *Client) release(cn *conn, err error) {
+	defer func() { <-c.slots }()
+
+	select {
+	case <-c.done:
+		_ = cn.Quit()
+		return
+	default:
+	}
+
+	// connection stays usable after server rejection, reset on next acquire, io errors leave it in unknown state
+	var protoErr *textproto.Error
+	var rcptErr *RecipientsError
+	if err != nil && !errors.As(err, &protoErr) && !errors.As(err, &rcptErr) {
+		_ = cn.Close()
+		return
 	}
 
-	rclient, err := smtp.NewClient(conn, c.cfg.ServerAddress)
+	cn.lastUsed = time.Now()
+	c.mu.Lock()
+	c.idle = append(c.idle, cn)
+	c.mu.Unlock()
+}
+
+func (c *Client) popIdle() *conn {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.idle) == 0 {
+		return nil
+	}
+	// most recently used connection is the most likely alive
+	cn := c.idle[len(c.idle)-1]
+	c.idle = c.idle[:len(c.idle)-1]
+
+	return cn
+}
+
+func (c *Client) dial() (*conn, error) {
+	addr := net.JoinHostPort(c.cfg.ServerAddress, c.cfg.Port)
+	tlsConfig := &tls.Config{
+		ServerName:         c.cfg.ServerAddress,
+		InsecureSkipVerify: c.cfg.InsecureSkipVerify,
+	}
+	dialer := &net.Dialer{Timeout: dialTimeout}
+
+	var netConn net.Conn
+	var err error
+	switch mode := c.cfg.SecurityMode(); mode {
+	case config.EmailSecurityTLS:
+		netConn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	case config.EmailSecurityStartTLS, config.EmailSecurityPlain:
+		netConn, err = dialer.Dial("tcp", addr)
+	default:
+		return nil, fmt.Errorf("unknown smtp security mode %s", mode)
+	}
+	if err != nil {
+		c.logger.Error("smtp connection", zap.Error(err))
+		return nil, err
+	}
 
+	client, err := smtp.NewClient(netConn, c.cfg.ServerAddress)
 	if err != nil {
+		_ = netConn.Close()
 		c.logger.Error("smtp client creation", zap.Error(err))
-		return err
+		return nil, err
 	}
 
-	auth := smtp.PlainAuth("", c.cfg.Login, c.cfg.Password, c.cfg.ServerAddress)
+	err = c.handshake(client, tlsConfig)
+	if err != nil {
+		_ = client.Close()
+		return nil, err
+	}
 
-	if err = rclient.Auth(auth); err != nil {
+	return &conn{Client: client, lastUsed: time.Now()}, nil
+}
+
+func (c *Client) handshake(client *smtp.Client, tlsConfig *tls.Config) error {
+	if c.cfg.LocalName != "" {
+		if err := client.Hello(c.cfg.LocalName); err != nil {
+			c.logger.Error("smtp hello", zap.Error(err))
+			return err
+		}
+	}
+
+	if c.cfg.SecurityMode() == config.EmailSecurityStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return errors.New("smtp server does not support STARTTLS")
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			c.logger.Error("smtp starttls", zap.Error(err))
+			return err
+		}
+	}
+
+	if c.cfg.Login == "" {
+		return nil
+	}
+	auth, err := c.negotiateAuth(client)
+	if err != nil {
+		return err
+	}
+	if err = client.Auth(auth); err != nil {
 		c.logger.Error("smtp auth", zap.Error(err))
 		return err
 	}
 
-	if err = rclient.Mail(c.cfg.Login); err != nil {
-		c.logger.Error("start mail transaction", zap.Error(err))
-		return err
+	return nil
+}
+
+// negotiateAuth picks mechanism supported by server, plaintext mechanisms are preferred over encrypted connection only
+func (c *Client) negotiateAuth(client *smtp.Client) (smtp.Auth, error) {
+	ok, params := client.Extension("AUTH")
+	if !ok {
+		return nil, errors.New("smtp server does not support AUTH")
+	}
+	supported := make(map[string]bool)
+	for _, mech := range strings.Fields(strings.ToUpper(params)) {
+		supported[mech] = true
+	}
+
+	preference := []string{MechPlain, MechLogin, MechCRAMMD5}
+	if _, encrypted := client.TLSConnectionState(); !encrypted {
+		preference = []string{MechCRAMMD5, MechPlain, MechLogin}
+	}
+	if c.cfg.AuthMechanism != "" {
+		preference = []string{strings.ToUpper(c.cfg.AuthMechanism)}
 	}
 
-	c.smtpc = rclient
+	for _, mech := range preference {
+		if !supported[mech] {
+			continue
+		}
+		switch mech {
+		case MechPlain:
+			return smtp.PlainAuth("", c.cfg.Login, c.cfg.Password, c.cfg.ServerAddress), nil
+		case MechLogin:
+			return LoginAuth(c.cfg.Login, c.cfg.Password, c.cfg.ServerAddress), nil
+		case MechCRAMMD5:
+			return smtp.CRAMMD5Auth(c.cfg.Login, c.cfg.Password), nil
+		}
+	}
 
-	return nil
+	return nil, fmt.Errorf("no supported smtp auth mechanism in %q", params)
 }
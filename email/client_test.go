@@ -0,0 +1,263 @@
+package email
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ptflp/go-light/config"
+	"go.uber.org/zap"
+)
+
+func testConfig(s *testServer, security string) *config.Email {
+	return &config.Email{
+		ServerAddress: "127.0.0.1",
+		Port:          s.Port(),
+		Login:         testLogin,
+		Password:      testPassword,
+		From:          "noreply@example.com",
+		Security:      security,
+	}
+}
+
+func testMsg(to string) *Message {
+	msg := NewMessage()
+	msg.SetReceiver(to)
+	msg.SetSubject("test")
+	msg.SetBody(*bytes.NewBufferString("hello"))
+
+	return msg
+}
+
+func TestClient_Auth(t *testing.T) {
+	tests := []struct {
+		name     string
+		mechs    []string
+		force    string
+		wantMech string
+		wantErr  bool
+	}{
+		{name: "plain", mechs: []string{MechPlain}, wantMech: MechPlain},
+		{name: "login", mechs: []string{MechLogin}, wantMech: MechLogin},
+		{name: "cram-md5", mechs: []string{MechCRAMMD5}, wantMech: MechCRAMMD5},
+		{name: "cram-md5 preferred over unencrypted connection", mechs: []string{MechPlain, MechLogin, MechCRAMMD5}, wantMech: MechCRAMMD5},
+		{name: "forced mechanism", mechs: []string{MechPlain, MechLogin, MechCRAMMD5}, force: "login", wantMech: MechLogin},
+		{name: "unsupported mechanism", mechs: []string{"XOAUTH2"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t, false, false, tt.mechs...)
+			cfg := testConfig(s, config.EmailSecurityPlain)
+			cfg.AuthMechanism = tt.force
+			c := NewClient(cfg, zap.NewNop())
+			defer c.Close()
+
+			err := c.Send(testMsg("user@example.com"))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Send() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(s.auths) != 1 || s.auths[0] != tt.wantMech {
+				t.Errorf("auth mechanisms = %v, want %s", s.auths, tt.wantMech)
+			}
+			msgs := s.Messages()
+			if len(msgs) != 1 || msgs[0].From != cfg.From || msgs[0].To[0] != "user@example.com" {
+				t.Errorf("messages = %+v", msgs)
+			}
+		})
+	}
+}
+
+func TestClient_Security(t *testing.T) {
+	tests := []struct {
+		name        string
+		implicitTLS bool
+		startTLS    bool
+		security    string
+		skipVerify  bool
+		wantErr     string
+	}{
+		{name: "implicit tls", implicitTLS: true, security: config.EmailSecurityTLS, skipVerify: true},
+		{name: "implicit tls verifies certificate", implicitTLS: true, security: config.EmailSecurityTLS, wantErr: "certificate"},
+		{name: "starttls", startTLS: true, security: config.EmailSecurityStartTLS, skipVerify: true},
+		{name: "starttls verifies certificate", startTLS: true, security: config.EmailSecurityStartTLS, wantErr: "certificate"},
+		{name: "starttls not supported", security: config.EmailSecurityStartTLS, wantErr: "STARTTLS"},
+		{name: "unknown mode", security: "ssl", wantErr: "unknown smtp security mode"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t, tt.implicitTLS, tt.startTLS, MechPlain, MechLogin)
+			cfg := testConfig(s, tt.security)
+			cfg.InsecureSkipVerify = tt.skipVerify
+			c := NewClient(cfg, zap.NewNop())
+			defer c.Close()
+
+			err := c.Send(testMsg("user@example.com"))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Send() error = %v", err)
+				}
+				// PLAIN is preferred over encrypted connection
+				if len(s.auths) != 1 || s.auths[0] != MechPlain {
+					t.Errorf("auth mechanisms = %v, want PLAIN", s.auths)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Send() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestClient_Reuse(t *testing.T) {
+	s := newTestServer(t, false, false, MechPlain)
+	c := NewClient(testConfig(s, config.EmailSecurityPlain), zap.NewNop())
+
+	for i := 0; i < 3; i++ {
+		if err := c.Send(testMsg("user@example.com")); err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+	}
+	// rejected recipient keeps connection usable
+	if err := c.Send(testMsg("reject@example.com")); err == nil {
+		t.Fatal("Send() to rejected recipient succeeded")
+	}
+	if err := c.Send(testMsg("user@example.com")); err != nil {
+		t.Fatalf("Send() after rejection error = %v", err)
+	}
+
+	// expired idle connection is replaced
+	c.idle[0].lastUsed = time.Now().Add(-time.Hour)
+	if err := c.Send(testMsg("user@example.com")); err != nil {
+		t.Fatalf("Send() after idle timeout error = %v", err)
+	}
+	_ = c.Close()
+	if err := c.Send(testMsg("user@example.com")); err != ErrClientClosed {
+		t.Errorf("Send() after Close error = %v, want %v", err, ErrClientClosed)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conns != 2 {
+		t.Errorf("connections = %d, want 2", s.conns)
+	}
+	if s.resets != 4 {
+		t.Errorf("resets = %d, want 4", s.resets)
+	}
+	if s.quits != 2 {
+		t.Errorf("quits = %d, want 2", s.quits)
+	}
+	if len(s.messages) != 5 {
+		t.Errorf("messages = %d, want 5", len(s.messages))
+	}
+}
+
+func TestClient_Recipients(t *testing.T) {
+	s := newTestServer(t, false, false, MechPlain)
+	cfg := testConfig(s, config.EmailSecurityPlain)
+	cfg.Sender = "bounces@example.com"
+	c := NewClient(cfg, zap.NewNop())
+	defer c.Close()
+
+	msg := testMsg("Пользователь <user@example.com>")
+	msg.AddTo("second@example.com")
+	msg.AddCC("copy@example.com", "reject@example.com")
+	msg.AddBCC("hidden@example.com", "USER@example.com")
+	msg.SetReplyTo("support@example.com")
+	msg.SetHeader("List-Unsubscribe", "<https://example.com/unsubscribe>")
+	msg.SetHeader("x-entity-ref", "ref-1")
+
+	err := c.Send(msg)
+	rcptErr, ok := err.(*RecipientsError)
+	if !ok {
+		t.Fatalf("Send() error = %v, want *RecipientsError", err)
+	}
+	if !rcptErr.Delivered || len(rcptErr.Rejected) != 1 || rcptErr.Rejected["reject@example.com"] == nil {
+		t.Errorf("RecipientsError = %+v", rcptErr)
+	}
+
+	msgs := s.Messages()
+	if len(msgs) != 1 {
+		t.Fatalf("messages = %d, want 1", len(msgs))
+	}
+	if msgs[0].From != cfg.Sender {
+		t.Errorf("envelope sender = %s, want %s", msgs[0].From, cfg.Sender)
+	}
+	wantTo := "user@example.com,second@example.com,copy@example.com,hidden@example.com"
+	if got := strings.Join(msgs[0].To, ","); got != wantTo {
+		t.Errorf("envelope recipients = %s, want %s", got, wantTo)
+	}
+	data := msgs[0].Data
+	for _, want := range []string{
+		"From: <noreply@example.com>",
+		"Cc: <copy@example.com>, <reject@example.com>",
+		"Reply-To: <support@example.com>",
+		"List-Unsubscribe: <https://example.com/unsubscribe>",
+		"X-Entity-Ref: ref-1",
+	} {
+		if !strings.Contains(data, want) {
+			t.Errorf("message has no %q header", want)
+		}
+	}
+	if !strings.Contains(data, "To: =?utf-8?") || strings.Contains(data, "hidden@example.com") {
+		t.Errorf("unexpected recipients headers:\n%s", data)
+	}
+
+	// all recipients rejected, nothing is delivered
+	msg = testMsg("reject@example.com")
+	msg.AddBCC("reject@example.org")
+	err = c.Send(msg)
+	if rcptErr, ok = err.(*RecipientsError); !ok || rcptErr.Delivered || len(rcptErr.Rejected) != 2 {
+		t.Errorf("Send() error = %v, want undelivered *RecipientsError", err)
+	}
+	if len(s.Messages()) != 1 {
+		t.Errorf("messages = %d, want 1", len(s.Messages()))
+	}
+
+	// reserved headers can't be overridden
+	msg = testMsg("user@example.com")
+	msg.SetHeader("Bcc", "user@example.com")
+	if err = c.Send(msg); err == nil {
+		t.Error("Send() with reserved header succeeded")
+	}
+}
+
+func TestClient_Pool(t *testing.T) {
+	s := newTestServer(t, false, false, MechPlain)
+	cfg := testConfig(s, config.EmailSecurityPlain)
+	cfg.PoolSize = 2
+	c := NewClient(cfg, zap.NewNop())
+	defer c.Close()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- c.Send(testMsg("user@example.com"))
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.peak > 2 {
+		t.Errorf("peak connections = %d, want <= 2", s.peak)
+	}
+	if len(s.messages) != 20 {
+		t.Errorf("messages = %d, want 20", len(s.messages))
+	}
+}
@@ -0,0 +1,263 @@
+package email
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ptflp/go-light/config"
+)
+
+const (
+	DKIMRSASHA256     = "rsa-sha256"
+	DKIMEd25519SHA256 = "ed25519-sha256"
+)
+
+// dkimHeaders signed when present in message
+var dkimHeaders = []string{
+	"From", "Reply-To", "Subject", "Date", "To", "Cc", "Message-ID", "MIME-Version",
+	"Content-Type", "Content-Transfer-Encoding", "List-Unsubscribe", "List-Unsubscribe-Post",
+}
+
+// DKIMSigner signs serialized messages with relaxed/relaxed canonicalization (RFC 6376, RFC 8463)
+type DKIMSigner struct {
+	domain    string
+	selector  string
+	algorithm string
+	key       crypto.Signer
+}
+
+// NewDKIMSigner loads PEM encoded RSA (PKCS#1 or PKCS#8) or Ed25519 (PKCS#8) private key
+func NewDKIMSigner(cfg *config.Email) (*DKIMSigner, error) {
+	b, err := ioutil.ReadFile(cfg.DKIMKeyPath)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, errors.New("dkim key is not pem encoded")
+	}
+
+	var key interface{}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return newDKIMSigner(cfg.DKIMDomain, cfg.DKIMSelector, key)
+}
+
+func newDKIMSigner(domain, selector string, key interface{}) (*DKIMSigner, error) {
+	s := &DKIMSigner{domain: domain, selector: selector}
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		if k.N.BitLen() < 1024 {
+			return nil, errors.New("dkim rsa key must be at least 1024 bits")
+		}
+		s.algorithm, s.key = DKIMRSASHA256, k
+	case ed25519.PrivateKey:
+		s.algorithm, s.key = DKIMEd25519SHA256, k
+	default:
+		return nil, fmt.Errorf("unsupported dkim key type %T", key)
+	}
+
+	return s, nil
+}
+
+// Sign prepends DKIM-Signature header, line endings of message are normalized to CRLF
+func (s *DKIMSigner) Sign(data []byte) ([]byte, error) {
+	data = normalizeCRLF(data)
+	headerEnd := bytes.Index(data, []byte("\r\n\r\n"))
+	if headerEnd < 0 {
+		return nil, errors.New("message has no header and body separator")
+	}
+	headers := splitHeaders(data[:headerEnd+2])
+	body := data[headerEnd+4:]
+
+	bodyHash := sha256.Sum256(relaxedBody(body))
+
+	var signed []string
+	var hash bytes.Buffer
+	for _, name := range dkimHeaders {
+		// single instance of every header is written by Message, the last one is signed
+		for i := len(headers) - 1; i >= 0; i-- {
+			if strings.EqualFold(headerName(headers[i]), name) {
+				hash.WriteString(relaxedHeader(headers[i]))
+				signed = append(signed, strings.ToLower(name))
+				break
+			}
+		}
+	}
+	if len(signed) == 0 || signed[0] != "from" {
+		return nil, errors.New("message has no From header")
+	}
+
+	tags := []string{
+		"v=1",
+		"a=" + s.algorithm,
+		"c=relaxed/relaxed",
+		"d=" + s.domain,
+		"s=" + s.selector,
+		"t=" + strconv.FormatInt(time.Now().Unix(), 10),
+		"h=" + strings.Join(signed, ":"),
+		"bh=" + base64.StdEncoding.EncodeToString(bodyHash[:]),
+		"b=",
+	}
+	header := foldTags("DKIM-Signature: ", tags)
+	// signature header is hashed without trailing CRLF
+	hash.WriteString(strings.TrimSuffix(relaxedHeader(header), "\r\n"))
+
+	digest := sha256.Sum256(hash.Bytes())
+	var signature []byte
+	var err error
+	switch s.algorithm {
+	case DKIMRSASHA256:
+		signature, err = s.key.Sign(rand.Reader, digest[:], crypto.SHA256)
+	case DKIMEd25519SHA256:
+		signature, err = s.key.Sign(rand.Reader, digest[:], crypto.Hash(0))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	header = strings.TrimSuffix(header, "\r\n")
+	lastLine := len(header) - strings.LastIndex(header, "\n") - 1
+	header += foldValue(base64.StdEncoding.EncodeToString(signature), lastLine) + "\r\n"
+
+	return append([]byte(header), data...), nil
+}
+
+func normalizeCRLF(data []byte) []byte {
+	data = bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+
+	return bytes.ReplaceAll(data, []byte("\n"), []byte("\r\n"))
+}
+
+// splitHeaders header fields including continuation lines and trailing CRLF
+func splitHeaders(b []byte) []string {
+	var headers []string
+	for _, line := range strings.SplitAfter(string(b), "\r\n") {
+		if line == "" {
+			continue
+		}
+		if (line[0] == ' ' || line[0] == '\t') && len(headers) > 0 {
+			headers[len(headers)-1] += line
+			continue
+		}
+		headers = append(headers, line)
+	}
+
+	return headers
+}
+
+func headerName(field string) string {
+	if i := strings.IndexByte(field, ':'); i >= 0 {
+		return strings.TrimSpace(field[:i])
+	}
+
+	return field
+}
+
+// relaxedHeader lowercases name, unfolds value and compresses whitespace
+func relaxedHeader(field string) string {
+	i := strings.IndexByte(field, ':')
+	if i < 0 {
+		return field
+	}
+	name := strings.ToLower(strings.TrimSpace(field[:i]))
+	value := strings.ReplaceAll(field[i+1:], "\r\n", "")
+
+	return name + ":" + strings.Join(strings.Fields(value), " ") + "\r\n"
+}
+
+// relaxedBody compresses whitespace, strips trailing whitespace of lines and trailing empty lines
+func relaxedBody(body []byte) []byte {
+	lines := strings.Split(string(body), "\r\n")
+	var b strings.Builder
+	empty := 0
+	for _, line := range lines {
+		line = strings.TrimRight(line, " \t")
+		line = collapseWSP(line)
+		if line == "" {
+			empty++
+			continue
+		}
+		for ; empty > 0; empty-- {
+			b.WriteString("\r\n")
+		}
+		b.WriteString(line + "\r\n")
+	}
+
+	return []byte(b.String())
+}
+
+func collapseWSP(line string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range line {
+		if r == ' ' || r == '\t' {
+			space = true
+			continue
+		}
+		if space {
+			b.WriteByte(' ')
+			space = false
+		}
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+// foldTags joins tags on lines of at most lineLength characters
+func foldTags(prefix string, tags []string) string {
+	var b strings.Builder
+	line := prefix
+	for i, tag := range tags {
+		if i < len(tags)-1 {
+			tag += ";"
+		}
+		if len(line)+len(tag)+1 > lineLength && line != prefix {
+			b.WriteString(strings.TrimRight(line, " ") + "\r\n")
+			line = "\t"
+		}
+		line += tag + " "
+	}
+	b.WriteString(strings.TrimRight(line, " ") + "\r\n")
+
+	return b.String()
+}
+
+// foldValue splits value to continuation lines, first line already has used characters
+func foldValue(value string, used int) string {
+	var b strings.Builder
+	n := lineLength - used
+	if n < 0 {
+		n = 0
+	}
+	for len(value) > n {
+		b.WriteString(value[:n] + "\r\n\t")
+		value = value[n:]
+		n = lineLength - 1
+	}
+	b.WriteString(value)
+
+	return b.String()
+}
@@ -0,0 +1,251 @@
+package email
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/ptflp/go-light/config"
+	"go.uber.org/zap"
+)
+
+var (
+	wspRegexp    = regexp.MustCompile(`[ \t]+`)
+	sigValRegexp = regexp.MustCompile(`(^|;)(\s*b\s*=)[^;]*`)
+)
+
+// verifyDKIM local verifier of relaxed/relaxed signatures, written against RFC 6376 independently of signer
+func verifyDKIM(message []byte, pub crypto.PublicKey) error {
+	message = bytes.ReplaceAll(message, []byte("\r\n"), []byte("\n"))
+	parts := strings.SplitN(string(message), "\n\n", 2)
+	if len(parts) != 2 {
+		return errors.New("no body")
+	}
+
+	var fields []string
+	for _, line := range strings.Split(parts[0], "\n") {
+		if strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") {
+			fields[len(fields)-1] += "\r\n" + line
+			continue
+		}
+		fields = append(fields, line)
+	}
+	canonHeader := func(field string) string {
+		i := strings.Index(field, ":")
+		value := strings.ReplaceAll(field[i+1:], "\r\n", "")
+		value = strings.TrimSpace(wspRegexp.ReplaceAllString(value, " "))
+		return strings.ToLower(strings.TrimSpace(field[:i])) + ":" + value
+	}
+
+	var sigField string
+	for _, f := range fields {
+		if strings.HasPrefix(strings.ToLower(f), "dkim-signature:") {
+			sigField = f
+			break
+		}
+	}
+	if sigField == "" {
+		return errors.New("no signature")
+	}
+	tags := make(map[string]string)
+	for _, tag := range strings.Split(canonHeader(sigField)[len("dkim-signature:"):], ";") {
+		kv := strings.SplitN(tag, "=", 2)
+		if len(kv) == 2 {
+			tags[strings.TrimSpace(kv[0])] = strings.Join(strings.Fields(kv[1]), "")
+		}
+	}
+	if tags["c"] != "relaxed/relaxed" || tags["v"] != "1" {
+		return fmt.Errorf("unexpected tags %v", tags)
+	}
+
+	// body: trailing whitespace removed, whitespace runs reduced, trailing empty lines removed
+	lines := strings.Split(parts[1], "\n")
+	for i := range lines {
+		lines[i] = strings.TrimRight(wspRegexp.ReplaceAllString(lines[i], " "), " ")
+	}
+	body := strings.TrimRight(strings.Join(lines, "\r\n"), "\r\n")
+	if body != "" {
+		body += "\r\n"
+	}
+	bh := sha256.Sum256([]byte(body))
+	if base64.StdEncoding.EncodeToString(bh[:]) != tags["bh"] {
+		return errors.New("body hash mismatch")
+	}
+
+	var hashed strings.Builder
+	used := make(map[int]bool)
+	for _, name := range strings.Split(tags["h"], ":") {
+		for i := len(fields) - 1; i >= 0; i-- {
+			if used[i] || !strings.EqualFold(strings.TrimSpace(fields[i][:strings.Index(fields[i], ":")]), name) {
+				continue
+			}
+			used[i] = true
+			hashed.WriteString(canonHeader(fields[i]) + "\r\n")
+			break
+		}
+	}
+	hashed.WriteString(sigValRegexp.ReplaceAllString(canonHeader(sigField), "$1$2"))
+	digest := sha256.Sum256([]byte(hashed.String()))
+
+	sig, err := base64.StdEncoding.DecodeString(tags["b"])
+	if err != nil {
+		return err
+	}
+	switch key := pub.(type) {
+	case *rsa.PublicKey:
+		if tags["a"] != DKIMRSASHA256 {
+			return errors.New("algorithm mismatch")
+		}
+		return rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig)
+	case ed25519.PublicKey:
+		if tags["a"] != DKIMEd25519SHA256 {
+			return errors.New("algorithm mismatch")
+		}
+		if !ed25519.Verify(key, digest[:], sig) {
+			return errors.New("ed25519 signature mismatch")
+		}
+		return nil
+	}
+
+	return errors.New("unknown key")
+}
+
+func testDKIMConfig(t *testing.T, key interface{}) *config.Email {
+	t.Helper()
+	var block *pem.Block
+	if k, ok := key.(*rsa.PrivateKey); ok {
+		block = &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(k)}
+	} else {
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		block = &pem.Block{Type: "PRIVATE KEY", Bytes: der}
+	}
+	dir, err := ioutil.TempDir("", "dkim")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+	path := filepath.Join(dir, "dkim.pem")
+	if err = ioutil.WriteFile(path, pem.EncodeToMemory(block), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	return &config.Email{DKIMDomain: "example.com", DKIMSelector: "mail", DKIMKeyPath: path}
+}
+
+func TestDKIMSigner_Sign(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edPub, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		key  interface{}
+		pub  crypto.PublicKey
+		alg  string
+	}{
+		{name: "rsa", key: rsaKey, pub: &rsaKey.PublicKey, alg: DKIMRSASHA256},
+		{name: "ed25519", key: edKey, pub: edPub, alg: DKIMEd25519SHA256},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signer, err := NewDKIMSigner(testDKIMConfig(t, tt.key))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			msg := testMsg("user@example.com")
+			msg.SetFrom("noreply@example.com")
+			msg.SetSubject("Восстановление пароля для очень длинной темы письма, которая будет свернута")
+			msg.SetType(TypeHtml)
+			msg.SetBody(*bytes.NewBufferString("<p>Привет,   мир</p>  \n\n\n"))
+			msg.SetHeader("List-Unsubscribe", "<https://example.com/unsubscribe>")
+			signed, err := signer.Sign(msg.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			unfolded := strings.ReplaceAll(string(signed), "\r\n\t", " ")
+			if !strings.HasPrefix(unfolded, "DKIM-Signature: v=1; a="+tt.alg+"; c=relaxed/relaxed; d=example.com; s=mail;") {
+				t.Fatalf("signed message:\n%s", signed)
+			}
+			for _, line := range strings.Split(string(signed), "\r\n") {
+				dkimLine := strings.HasPrefix(line, "DKIM-Signature:") || strings.HasPrefix(line, "\t")
+				if dkimLine && len(line) > 78 {
+					t.Errorf("signature line exceeds 78 characters: %s", line)
+				}
+			}
+			if err = verifyDKIM(signed, tt.pub); err != nil {
+				t.Fatalf("verify: %v\n%s", err, signed)
+			}
+			h := regexp.MustCompile(`h=([a-z:-]+)`).FindSubmatch(signed)
+			if len(h) < 2 || !strings.HasPrefix(string(h[1]), "from:subject:date:to:message-id:mime-version:content-type") || !strings.Contains(string(h[1]), "list-unsubscribe") {
+				t.Errorf("signed headers = %s", h)
+			}
+
+			// relaxed canonicalization tolerates whitespace changes in transit
+			relaxed := bytes.Replace(signed, []byte("Subject:"), []byte("subject:  "), 1)
+			relaxed = append(relaxed, []byte("\r\n\r\n")...)
+			if err = verifyDKIM(relaxed, tt.pub); err != nil {
+				t.Errorf("verify relaxed: %v", err)
+			}
+
+			tamperedHeader := bytes.Replace(signed, []byte("https://example.com/unsubscribe"), []byte("https://evil.example/unsubscribe"), 1)
+			if verifyDKIM(tamperedHeader, tt.pub) == nil {
+				t.Error("tampered header verified")
+			}
+			tampered := append([]byte(nil), signed...)
+			tampered[len(tampered)-5] ^= 1
+			if verifyDKIM(tampered, tt.pub) == nil {
+				t.Error("tampered body verified")
+			}
+		})
+	}
+}
+
+func TestDKIMSigner_Client(t *testing.T) {
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := NewDKIMSigner(testDKIMConfig(t, edKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := newTestServer(t, false, false, MechPlain)
+	c := NewClient(testConfig(s, config.EmailSecurityPlain), zap.NewNop())
+	defer c.Close()
+	c.UseDKIM(signer)
+
+	if err = c.Send(testMsg("user@example.com")); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	msgs := s.Messages()
+	if len(msgs) != 1 {
+		t.Fatalf("messages = %d, want 1", len(msgs))
+	}
+	if err = verifyDKIM([]byte(msgs[0].Data), edKey.Public()); err != nil {
+		t.Errorf("verify delivered message: %v", err)
+	}
+}
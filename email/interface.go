@@ -6,22 +6,44 @@ import (
 
 type Mailer interface {
 	Send(msg Messager) error
+	Close() error
+}
+
+// Transport delivers serialized message to envelope recipients
+type Transport interface {
+	SendRaw(sender string, rcpts []string, data []byte) error
+	Close() error
 }
 
 type Messager interface {
 	SetFrom(from string)
+	GetFrom() string
+	SetSender(sender string)
+	GetSender() string
 
 	SetReceiver(rcpt string)
 	GetReceiver() string
+	AddTo(rcpt ...string)
+	AddCC(rcpt ...string)
+	AddBCC(rcpt ...string)
+	RemoveRecipient(rcpt string)
+	Recipients() []string
+	SetReplyTo(addr string)
+	SetHeader(name, value string)
 
 	SetType(t string)
 
 	SetSubject(sub string)
+	GetSubject() string
+	SetIdempotencyKey(key string)
+	GetIdempotencyKey() string
 
 	SetBody(msg bytes.Buffer)
+	SetTextBody(msg bytes.Buffer)
 
 	OpenFile(path string) error
 	Attach(src bytes.Buffer, fileName string)
+	Embed(src bytes.Buffer, fileName string) string
 	Bytes() []byte
 
 	Validate() error
@@ -2,139 +2,288 @@ package email
 
 import (
 	"bytes"
+	"crypto/rand"
 	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"html"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/http"
+	"net/mail"
+	"net/textproto"
 	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/ptflp/go-light/validators"
 )
 
 const (
-	Delimiter = "**=myohmy689407924327"
-
 	TypeHtml  = "text/html"
 	TypePlain = "text/plain"
+
+	lineLength = 76
+)
+
+var (
+	hiddenRegexp = regexp.MustCompile(`(?is)<(script|style|head)[^>]*>.*?</(script|style|head)>`)
+	linkRegexp   = regexp.MustCompile(`(?is)<a\s[^>]*href="([^"]*)"[^>]*>(.*?)</a>`)
+	blockRegexp  = regexp.MustCompile(`(?i)<br\s*/?>|</?(p|div|li|tr|h[1-6]|table|ul|ol)(\s[^>]*)?>`)
+	tagsRegexp   = regexp.MustCompile(`<[^>]+>`)
+	spacesRegexp = regexp.MustCompile(`[ \t]*\n[ \t\n]*`)
 )
 
+// reservedHeaders are managed by Message and can't be set with SetHeader
+var reservedHeaders = map[string]bool{
+	"From": true, "To": true, "Cc": true, "Bcc": true, "Reply-To": true, "Subject": true, "Date": true,
+	"Message-Id": true, "Mime-Version": true, "Content-Type": true, "Content-Transfer-Encoding": true,
+}
+
 type Message struct {
-	typeRaw     string
-	fromRaw     string
-	toRaw       string
-	from        string
-	to          string
-	subject     string
-	mimeVersion string
+	typeRaw   string
+	from      string
+	sender    string
+	to        []string
+	cc        []string
+	bcc       []string
+	replyTo   string
+	headers   []header
+	subject   string
+	body      string
+	text      string
+	date      time.Time
+	messageID string
+	// idempotencyKey deduplicates messages in outbox, not sent to server
+	idempotencyKey string
+
+	attachments []attachment
+	inlines     []attachment
+}
+
+type header struct {
+	name  string
+	value string
+}
+
+type attachment struct {
+	fileName    string
 	contentType string
-	delimiter   string
-	body        body
+	cid         string
+	src         []byte
 }
 
 func NewMessage() *Message {
 	return &Message{
-		typeRaw:     TypePlain,
-		mimeVersion: "MIME-Version: 1.0\r\n",
-		contentType: fmt.Sprintf("Content-Type: multipart/mixed; boundary=\"%s\"\r\n", Delimiter),
-		delimiter:   fmt.Sprintf("\r\n--%s\r\n", Delimiter),
-		body: body{
-			contentType:             fmt.Sprintf("Content-Type: %s; charset=\"utf-8\"\r\n", TypePlain),
-			contentTransferEncoding: "Content-Transfer-Encoding: 7bit\r\n",
-		},
+		typeRaw: TypePlain,
+		date:    time.Now(),
 	}
 }
 
-type body struct {
-	contentType             string
-	contentTransferEncoding string
-	body                    string
-	files                   files
+// mimePart header and encoded content of single mime entity
+type mimePart struct {
+	header textproto.MIMEHeader
+	body   []byte
 }
 
-func (b *body) String() string {
-	filesString := ""
-	if b.files != nil {
-		filesString = b.files.String()
-	}
-	return concat(b.contentType, b.contentTransferEncoding, b.body, "\r\n", filesString)
+func (m *Message) SetFrom(from string) {
+	m.from = from
+}
+
+func (m *Message) GetFrom() string {
+	return m.from
 }
 
-func concat(args ...string) string {
-	d := strings.Join(args, "")
-	return d
+// SetSender envelope sender (MAIL FROM), bounces are returned to it, From header is used when not set
+func (m *Message) SetSender(sender string) {
+	m.sender = sender
 }
 
-type files []file
+func (m *Message) GetSender() string {
+	return m.sender
+}
 
-func (f *files) String() string {
-	s := make([]string, 0, len(*f))
-	for _, fl := range *f {
-		s = append(s, fl.String())
+// SetReceiver replaces To recipients with single address
+func (m *Message) SetReceiver(rcpt string) {
+	m.to = []string{rcpt}
+}
+
+func (m *Message) GetReceiver() string {
+	if len(m.to) == 0 {
+		return ""
 	}
 
-	return strings.Join(s, "")
+	return m.to[0]
 }
 
-type file struct {
-	fileName string
-	src      bytes.Buffer
+func (m *Message) AddTo(rcpt ...string) {
+	m.to = append(m.to, rcpt...)
 }
 
-func newFile(fileName string) *file {
-	return &file{
-		fileName: fileName,
-	}
+func (m *Message) AddCC(rcpt ...string) {
+	m.cc = append(m.cc, rcpt...)
 }
 
-func (f *file) String() string {
-	return strings.Join([]string{
-		fmt.Sprintf("\r\n--%s\r\n", Delimiter),
-		"Content-Type: text/plain; charset=\"utf-8\"\r\n",
-		"Content-Transfer-Encoding: base64\r\n",
-		"Content-Disposition: attachment;filename=\"" + f.fileName + "\"\r\n",
-		"\r\n" + base64.StdEncoding.EncodeToString(f.src.Bytes()),
-	}, "")
+// AddBCC adds recipients which are not listed in headers
+func (m *Message) AddBCC(rcpt ...string) {
+	m.bcc = append(m.bcc, rcpt...)
 }
 
-func (m *Message) SetFrom(from string) {
-	m.fromRaw = from
-	m.from = fmt.Sprintf("From: %s\r\n", from)
+func (m *Message) SetReplyTo(addr string) {
+	m.replyTo = addr
 }
 
-func (m *Message) SetReceiver(rcpt string) {
-	m.toRaw = rcpt
-	m.to = fmt.Sprintf("To: %s\r\n", rcpt)
+// SetHeader sets custom header like List-Unsubscribe or X-Entity-Ref
+func (m *Message) SetHeader(name, value string) {
+	name = textproto.CanonicalMIMEHeaderKey(name)
+	for i := range m.headers {
+		if m.headers[i].name == name {
+			m.headers[i].value = value
+			return
+		}
+	}
+	m.headers = append(m.headers, header{name: name, value: value})
 }
 
-func (m *Message) GetReceiver() string {
-	return m.toRaw
+// RemoveRecipient removes address from to, cc and bcc
+func (m *Message) RemoveRecipient(rcpt string) {
+	m.to = removeAddress(m.to, rcpt)
+	m.cc = removeAddress(m.cc, rcpt)
+	m.bcc = removeAddress(m.bcc, rcpt)
+}
+
+func removeAddress(list []string, rcpt string) []string {
+	kept := list[:0]
+	for _, addr := range list {
+		if parsed, err := mail.ParseAddress(addr); err == nil {
+			if strings.EqualFold(parsed.Address, rcpt) {
+				continue
+			}
+		}
+		if strings.EqualFold(addr, rcpt) {
+			continue
+		}
+		kept = append(kept, addr)
+	}
+
+	return kept
+}
+
+// Recipients envelope recipients including bcc, duplicates removed
+func (m *Message) Recipients() []string {
+	seen := make(map[string]bool)
+	var rcpts []string
+	for _, list := range [][]string{m.to, m.cc, m.bcc} {
+		for i := range list {
+			addr := list[i]
+			if parsed, err := mail.ParseAddress(addr); err == nil {
+				addr = parsed.Address
+			}
+			if seen[strings.ToLower(addr)] {
+				continue
+			}
+			seen[strings.ToLower(addr)] = true
+			rcpts = append(rcpts, addr)
+		}
+	}
+
+	return rcpts
 }
 
 func (m *Message) SetType(t string) {
 	m.typeRaw = t
-	m.body.contentType = fmt.Sprintf("Content-Type: %s; charset=\"utf-8\"\r\n", t)
 }
 
 func (m *Message) SetSubject(sub string) {
-	m.subject = fmt.Sprintf("Subject: %s\r\n", sub)
+	m.subject = sub
+}
+
+func (m *Message) GetSubject() string {
+	return m.subject
+}
+
+// SetIdempotencyKey message with same key is enqueued once
+func (m *Message) SetIdempotencyKey(key string) {
+	m.idempotencyKey = key
+}
+
+func (m *Message) GetIdempotencyKey() string {
+	return m.idempotencyKey
+}
+
+func (m *Message) SetBody(msg bytes.Buffer) {
+	m.body = msg.String()
+}
+
+// SetTextBody plain text alternative of html body, generated from html when not set
+func (m *Message) SetTextBody(msg bytes.Buffer) {
+	m.text = msg.String()
 }
 
 func (m *Message) Attach(src bytes.Buffer, fileName string) {
-	f := newFile(fileName)
-	f.src.Write(src.Bytes())
-	m.body.files = append(m.body.files, *f)
+	m.attachments = append(m.attachments, newAttachment(src.Bytes(), fileName))
 }
 
-func (m *Message) Validate() error {
-	err := validators.CheckEmailFormat(m.fromRaw)
+// Embed adds inline file and returns content id to reference it from html as cid:<id>
+func (m *Message) Embed(src bytes.Buffer, fileName string) string {
+	a := newAttachment(src.Bytes(), fileName)
+	a.cid = fmt.Sprintf("%s@%s", randomID(), m.domain())
+	m.inlines = append(m.inlines, a)
+
+	return a.cid
+}
+
+func (m *Message) OpenFile(path string) error {
+	fb, err := ioutil.ReadFile(path)
 	if err != nil {
-		return err
+		return fmt.Errorf("path: %s error: %s", func() string { s, _ := os.Getwd(); return s }(), err)
 	}
-	err = validators.CheckEmailFormat(m.toRaw)
+	m.Attach(*bytes.NewBuffer(fb), filepath.Base(path))
+
+	return nil
+}
+
+func (m *Message) Validate() error {
+	err := checkAddress(m.from)
 	if err != nil {
 		return err
 	}
-	if m.body.body == "" {
+	if m.sender != "" {
+		if err = checkAddress(m.sender); err != nil {
+			return err
+		}
+	}
+	if m.replyTo != "" {
+		if err = checkAddress(m.replyTo); err != nil {
+			return err
+		}
+	}
+	if len(m.to)+len(m.cc)+len(m.bcc) == 0 {
+		return errors.New("recipients not set")
+	}
+	for _, list := range [][]string{m.to, m.cc, m.bcc} {
+		for i := range list {
+			if err = checkAddress(list[i]); err != nil {
+				return err
+			}
+		}
+	}
+	for i := range m.headers {
+		if reservedHeaders[m.headers[i].name] {
+			return fmt.Errorf("header %s can't be set", m.headers[i].name)
+		}
+		if strings.ContainsAny(m.headers[i].name+m.headers[i].value, "\r\n") {
+			return fmt.Errorf("header %s contains line break", m.headers[i].name)
+		}
+	}
+	if m.body == "" {
 		return errors.New("body not set")
 	}
 	if m.typeRaw != TypePlain && m.typeRaw != TypeHtml {
@@ -145,40 +294,210 @@ func (m *Message) Validate() error {
 }
 
 func (m *Message) String() string {
-	return concat(
-		m.from,
-		m.to,
-		m.subject,
-		m.mimeVersion,
-		m.contentType,
-		m.delimiter,
-		m.body.String())
+	return string(m.Bytes())
 }
 
 func (m *Message) Bytes() []byte {
-	return []byte(m.String())
+	part := m.content()
+
+	var b bytes.Buffer
+	writeHeader(&b, "From", formatAddresses(m.from))
+	if len(m.to) > 0 {
+		writeHeader(&b, "To", formatAddresses(m.to...))
+	}
+	if len(m.cc) > 0 {
+		writeHeader(&b, "Cc", formatAddresses(m.cc...))
+	}
+	if m.replyTo != "" {
+		writeHeader(&b, "Reply-To", formatAddresses(m.replyTo))
+	}
+	writeHeader(&b, "Subject", mime.QEncoding.Encode("utf-8", m.subject))
+	writeHeader(&b, "Date", m.date.Format(time.RFC1123Z))
+	writeHeader(&b, "Message-ID", m.MessageID())
+	writeHeader(&b, "MIME-Version", "1.0")
+	for i := range m.headers {
+		writeHeader(&b, m.headers[i].name, mime.QEncoding.Encode("utf-8", m.headers[i].value))
+	}
+	writePart(&b, part)
+
+	return b.Bytes()
 }
 
-func (m *Message) SetBody(msg bytes.Buffer) {
-	m.body.body = msg.String()
+// MessageID generated once, so retries of the same message keep its id
+func (m *Message) MessageID() string {
+	if m.messageID == "" {
+		m.messageID = fmt.Sprintf("<%s@%s>", randomID(), m.domain())
+	}
+
+	return m.messageID
 }
 
-func (m *Message) OpenFile(path string) error {
-	fl, err := os.Open(path)
-	if err != nil {
-		return fmt.Errorf("path: %s error: %s", func() string { s, _ := os.Getwd(); return s }(), err)
+// content builds mime tree: mixed(related(alternative(text, html), inlines), attachments)
+func (m *Message) content() mimePart {
+	part := textPart(m.typeRaw, m.body)
+	if m.typeRaw == TypeHtml {
+		text := m.text
+		if text == "" {
+			text = htmlToText(m.body)
+		}
+		part = multipartOf("alternative", textPart(TypePlain, text), part)
+	}
+
+	if len(m.inlines) > 0 {
+		parts := []mimePart{part}
+		for i := range m.inlines {
+			parts = append(parts, m.inlines[i].part("inline"))
+		}
+		part = multipartOf("related", parts...)
+	}
+
+	if len(m.attachments) > 0 {
+		parts := []mimePart{part}
+		for i := range m.attachments {
+			parts = append(parts, m.attachments[i].part("attachment"))
+		}
+		part = multipartOf("mixed", parts...)
 	}
-	defer fl.Close()
-	fileInfo, err := fl.Stat()
+
+	return part
+}
+
+func (m *Message) domain() string {
+	if addr, err := mail.ParseAddress(m.from); err == nil {
+		if i := strings.LastIndexByte(addr.Address, '@'); i >= 0 {
+			return addr.Address[i+1:]
+		}
+	}
+
+	return "localhost"
+}
+
+func checkAddress(addr string) error {
+	parsed, err := mail.ParseAddress(addr)
 	if err != nil {
-		return fmt.Errorf("path: %s error: %s", func() string { s, _ := os.Getwd(); return s }(), err)
+		return err
 	}
 
-	fb := make([]byte, fileInfo.Size())
-	_, err = fl.Read(fb)
-	b := bytes.NewBuffer(fb)
+	return validators.CheckEmailFormat(parsed.Address)
+}
+
+// formatAddresses encodes display names, addresses are validated before
+func formatAddresses(addrs ...string) string {
+	formatted := make([]string, 0, len(addrs))
+	for i := range addrs {
+		parsed, err := mail.ParseAddress(addrs[i])
+		if err != nil {
+			formatted = append(formatted, addrs[i])
+			continue
+		}
+		formatted = append(formatted, parsed.String())
+	}
 
-	m.Attach(*b, fl.Name())
+	return strings.Join(formatted, ", ")
+}
+
+func newAttachment(src []byte, fileName string) attachment {
+	contentType := mime.TypeByExtension(filepath.Ext(fileName))
+	if contentType == "" {
+		contentType = http.DetectContentType(src)
+	}
+
+	return attachment{
+		fileName:    fileName,
+		contentType: contentType,
+		src:         src,
+	}
+}
+
+func (a attachment) part(disposition string) mimePart {
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Type", mime.FormatMediaType(a.contentType, map[string]string{"name": a.fileName}))
+	header.Set("Content-Transfer-Encoding", "base64")
+	header.Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": a.fileName}))
+	if a.cid != "" {
+		header.Set("Content-ID", "<"+a.cid+">")
+	}
+
+	encoded := base64.StdEncoding.EncodeToString(a.src)
+	var body bytes.Buffer
+	for len(encoded) > lineLength {
+		body.WriteString(encoded[:lineLength] + "\r\n")
+		encoded = encoded[lineLength:]
+	}
+	body.WriteString(encoded)
+
+	return mimePart{header: header, body: body.Bytes()}
+}
+
+func textPart(contentType, content string) mimePart {
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Type", mime.FormatMediaType(contentType, map[string]string{"charset": "utf-8"}))
+	header.Set("Content-Transfer-Encoding", "quoted-printable")
+
+	var body bytes.Buffer
+	w := quotedprintable.NewWriter(&body)
+	_, _ = io.WriteString(w, content)
+	_ = w.Close()
+
+	return mimePart{header: header, body: body.Bytes()}
+}
+
+func multipartOf(subtype string, parts ...mimePart) mimePart {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for i := range parts {
+		w, _ := mw.CreatePart(parts[i].header)
+		_, _ = w.Write(parts[i].body)
+	}
+	_ = mw.Close()
+
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Type", mime.FormatMediaType("multipart/"+subtype, map[string]string{"boundary": mw.Boundary()}))
+
+	return mimePart{header: header, body: body.Bytes()}
+}
+
+func writePart(b *bytes.Buffer, part mimePart) {
+	keys := make([]string, 0, len(part.header))
+	for k := range part.header {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		writeHeader(b, k, part.header.Get(k))
+	}
+	b.WriteString("\r\n")
+	b.Write(part.body)
+}
+
+// writeHeader folds long header values on spaces
+func writeHeader(b *bytes.Buffer, name, value string) {
+	line := name + ":"
+	for _, word := range strings.Fields(value) {
+		if len(line)+1+len(word) > lineLength && len(line) > len(name)+1 {
+			b.WriteString(line + "\r\n")
+			line = ""
+		}
+		line += " " + word
+	}
+	b.WriteString(line + "\r\n")
+}
+
+func htmlToText(s string) string {
+	s = hiddenRegexp.ReplaceAllString(s, "")
+	s = linkRegexp.ReplaceAllString(s, "$2 ($1)")
+	s = blockRegexp.ReplaceAllString(s, "\n")
+	s = tagsRegexp.ReplaceAllString(s, "")
+	s = spacesRegexp.ReplaceAllString(html.UnescapeString(s), "\n")
+
+	return strings.TrimSpace(s)
+}
+
+func randomID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%d", time.Now().UnixNano())
+	}
 
-	return err
+	return hex.EncodeToString(b)
 }
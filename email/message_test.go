@@ -0,0 +1,134 @@
+package email
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"strings"
+	"testing"
+)
+
+func TestMessage_Bytes(t *testing.T) {
+	msg := NewMessage()
+	msg.SetFrom("noreply@example.com")
+	msg.SetReceiver("user@example.com")
+	msg.SetSubject("Восстановление пароля")
+	msg.SetType(TypeHtml)
+	cid := msg.Embed(*bytes.NewBuffer([]byte("\x89PNG\r\n\x1a\n")), "logo.png")
+	msg.SetBody(*bytes.NewBufferString(`<p>Привет, <a href="https://example.com/recover">восстановить</a></p><img src="cid:` + cid + `">`))
+	msg.Attach(*bytes.NewBufferString("%PDF-1.4"), "report.pdf")
+
+	parsed, err := mail.ReadMessage(bytes.NewReader(msg.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+	if err != nil || subject != "Восстановление пароля" {
+		t.Errorf("Subject = %q, err %v", subject, err)
+	}
+	if _, err = parsed.Header.Date(); err != nil {
+		t.Errorf("Date header: %v", err)
+	}
+	if id := parsed.Header.Get("Message-ID"); !strings.HasSuffix(id, "@example.com>") {
+		t.Errorf("Message-ID = %q", id)
+	}
+
+	// mixed(related(alternative(text, html), inline), attachment)
+	mixed := readParts(t, parsed.Header.Get("Content-Type"), parsed.Body, "multipart/mixed")
+	if len(mixed) != 2 {
+		t.Fatalf("mixed parts = %d, want 2", len(mixed))
+	}
+	if ct := mixed[1].header.Get("Content-Type"); !strings.HasPrefix(ct, "application/pdf") {
+		t.Errorf("attachment Content-Type = %q", ct)
+	}
+	if string(mixed[1].body) != "%PDF-1.4" {
+		t.Errorf("attachment body = %q", mixed[1].body)
+	}
+
+	related := readParts(t, mixed[0].header.Get("Content-Type"), bytes.NewReader(mixed[0].body), "multipart/related")
+	if len(related) != 2 {
+		t.Fatalf("related parts = %d, want 2", len(related))
+	}
+	if id := related[1].header.Get("Content-ID"); id != "<"+cid+">" {
+		t.Errorf("Content-ID = %q, want <%s>", id, cid)
+	}
+	if ct := related[1].header.Get("Content-Type"); !strings.HasPrefix(ct, "image/png") {
+		t.Errorf("inline Content-Type = %q", ct)
+	}
+
+	alternative := readParts(t, related[0].header.Get("Content-Type"), bytes.NewReader(related[0].body), "multipart/alternative")
+	if len(alternative) != 2 {
+		t.Fatalf("alternative parts = %d, want 2", len(alternative))
+	}
+	if text := string(alternative[0].body); text != "Привет, восстановить (https://example.com/recover)" {
+		t.Errorf("text body = %q", text)
+	}
+	if html := string(alternative[1].body); !strings.Contains(html, "cid:"+cid) {
+		t.Errorf("html body = %q", html)
+	}
+}
+
+func TestMessage_Boundaries(t *testing.T) {
+	build := func() string {
+		msg := NewMessage()
+		msg.SetFrom("noreply@example.com")
+		msg.SetReceiver("user@example.com")
+		msg.SetType(TypeHtml)
+		msg.SetBody(*bytes.NewBufferString("<p>test</p>"))
+		parsed, err := mail.ReadMessage(bytes.NewReader(msg.Bytes()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, params, _ := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+
+		return params["boundary"]
+	}
+
+	if a, b := build(), build(); a == "" || a == b {
+		t.Errorf("boundaries %q and %q must be random", a, b)
+	}
+}
+
+type testPart struct {
+	header mail.Header
+	body   []byte
+}
+
+// readParts decodes parts of multipart entity, quoted-printable is decoded by multipart reader
+func readParts(t *testing.T, contentType string, body io.Reader, want string) []testPart {
+	t.Helper()
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType != want {
+		t.Fatalf("Content-Type = %q, want %s", contentType, want)
+	}
+
+	var parts []testPart
+	mr := multipart.NewReader(body, params["boundary"])
+	for {
+		p, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := ioutil.ReadAll(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.Header.Get("Content-Transfer-Encoding") == "base64" {
+			b, err = base64.StdEncoding.DecodeString(strings.ReplaceAll(string(b), "\r\n", ""))
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		parts = append(parts, testPart{header: mail.Header(p.Header), body: b})
+	}
+
+	return parts
+}
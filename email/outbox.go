@@ -0,0 +1,225 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"net/textproto"
+	"strings"
+	"time"
+
+	light "github.com/ptflp/go-light"
+	"github.com/ptflp/go-light/config"
+	"github.com/ptflp/go-light/types"
+	"go.uber.org/zap"
+)
+
+const (
+	outboxBatch   = 20
+	outboxPoll    = 5 * time.Second
+	outboxLease   = 5 * time.Minute
+	outboxTimeout = 10 * time.Second
+	// outboxPurge interval of removing sent and dead messages older than retention
+	outboxPurge = time.Hour
+)
+
+// Outbox Mailer which enqueues messages into persistent outbox, delivered by Run worker with retries
+type Outbox struct {
+	transport Transport
+	repo      light.OutboxRepository
+	cfg       *config.Email
+	logger    *zap.Logger
+	wake      chan struct{}
+	purgedAt  time.Time
+}
+
+func NewOutbox(transport Transport, repo light.OutboxRepository, cfg *config.Email, logger *zap.Logger) *Outbox {
+	return &Outbox{
+		transport: transport,
+		repo:      repo,
+		cfg:       cfg,
+		logger:    logger,
+		wake:      make(chan struct{}, 1),
+	}
+}
+
+func (o *Outbox) Send(msg Messager) error {
+	if msg.GetFrom() == "" {
+		msg.SetFrom(o.cfg.From)
+	}
+	err := msg.Validate()
+	if err != nil {
+		return err
+	}
+
+	outboxMsg := light.OutboxMessage{
+		UUID:          types.NewNullUUID(),
+		Sender:        types.NewNullString(envelopeSender(msg, o.cfg)),
+		Recipients:    types.NewNullString(strings.Join(msg.Recipients(), ",")),
+		Subject:       types.NewNullString(truncate(msg.GetSubject(), 255)),
+		Data:          types.NewNullString(string(msg.Bytes())),
+		Status:        types.NewNullInt64(types.OutboxPending),
+		NextAttemptAt: types.NullTime{},
+	}
+	outboxMsg.NextAttemptAt.SetValid(time.Now())
+	if key := msg.GetIdempotencyKey(); key != "" {
+		outboxMsg.IdempotencyKey = types.NewNullString(key)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), outboxTimeout)
+	defer cancel()
+	created, err := o.repo.Create(ctx, outboxMsg)
+	if err != nil {
+		o.logger.Error("outbox enqueue", zap.Error(err))
+		return err
+	}
+	if !created {
+		o.logger.Info("outbox duplicate message skipped", zap.String("key", msg.GetIdempotencyKey()))
+		return nil
+	}
+
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// Run delivers due messages until context is done
+func (o *Outbox) Run(ctx context.Context) {
+	ticker := time.NewTicker(outboxPoll)
+	defer ticker.Stop()
+
+	for {
+		o.deliver(ctx)
+		o.purge(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-o.wake:
+		}
+	}
+}
+
+func (o *Outbox) purge(ctx context.Context) {
+	if time.Since(o.purgedAt) < outboxPurge {
+		return
+	}
+	o.purgedAt = time.Now()
+	purged, err := o.repo.Purge(ctx, time.Now().Add(-o.cfg.Retention()))
+	if err != nil {
+		o.logger.Error("outbox purge", zap.Error(err))
+		return
+	}
+	if purged > 0 {
+		o.logger.Info("outbox purged", zap.Int64("messages", purged))
+	}
+}
+
+// Close closes smtp connections, undelivered messages stay in outbox
+func (o *Outbox) Close() error {
+	return o.transport.Close()
+}
+
+func (o *Outbox) deliver(ctx context.Context) {
+	for ctx.Err() == nil {
+		messages, err := o.repo.Claim(ctx, outboxBatch, outboxLease)
+		if err != nil {
+			o.logger.Error("outbox claim", zap.Error(err))
+			return
+		}
+		for i := range messages {
+			o.deliverMessage(messages[i])
+		}
+		if len(messages) < outboxBatch {
+			return
+		}
+	}
+}
+
+func (o *Outbox) deliverMessage(msg light.OutboxMessage) {
+	err := o.transport.SendRaw(msg.Sender.String, strings.Split(msg.Recipients.String, ","), []byte(msg.Data.String))
+	if errors.Is(err, ErrClientClosed) {
+		// shutdown, message is delivered after restart
+		msg.Status = types.NewNullInt64(types.OutboxPending)
+		msg.NextAttemptAt.SetValid(time.Now())
+		o.update(msg)
+		return
+	}
+
+	attempts := msg.Attempts.Int64 + 1
+	msg.Attempts = types.NewNullInt64(attempts)
+	if err != nil {
+		msg.LastError = types.NewNullString(truncate(err.Error(), 512))
+	}
+	logger := o.logger.With(zap.String("message_id", msg.UUID.String), zap.Int64("attempt", attempts))
+
+	var rcptErr *RecipientsError
+	switch {
+	case err == nil || errors.As(err, &rcptErr) && rcptErr.Delivered:
+		msg.Status = types.NewNullInt64(types.OutboxSent)
+		msg.SentAt.SetValid(time.Now())
+		// message may contain links with tokens, it's not kept after delivery
+		msg.Data = types.NewNullString("")
+		if err != nil {
+			logger.Warn("outbox message partially delivered", zap.Error(err))
+		}
+	case permanentError(err) || attempts >= int64(o.cfg.MaxAttempts()):
+		msg.Status = types.NewNullInt64(types.OutboxDead)
+		logger.Error("outbox message dead-lettered", zap.Error(err))
+	default:
+		msg.Status = types.NewNullInt64(types.OutboxPending)
+		msg.NextAttemptAt.SetValid(time.Now().Add(o.cfg.RetryDelay(int(attempts))))
+		logger.Warn("outbox delivery failed", zap.Error(err), zap.Time("next_attempt_at", msg.NextAttemptAt.Time.Time))
+	}
+
+	o.update(msg)
+}
+
+// update result is stored even on shutdown, otherwise message is delivered again after lease expires
+func (o *Outbox) update(msg light.OutboxMessage) {
+	ctx, cancel := context.WithTimeout(context.Background(), outboxTimeout)
+	defer cancel()
+	if err := o.repo.Update(ctx, msg); err != nil {
+		o.logger.Error("outbox update", zap.String("message_id", msg.UUID.String), zap.Error(err))
+	}
+}
+
+// permanentError 5xx replies to MAIL, RCPT and DATA of message are not retried.
+// Connection and authentication failures affect every message and are retried
+func permanentError(err error) bool {
+	var rcptErr *RecipientsError
+	if errors.As(err, &rcptErr) {
+		for _, e := range rcptErr.Rejected {
+			if !rejected(e) {
+				return false
+			}
+		}
+		return true
+	}
+	var msgErr *MessageError
+	if errors.As(err, &msgErr) {
+		return rejected(msgErr.Err)
+	}
+
+	return false
+}
+
+func rejected(err error) bool {
+	var protoErr *textproto.Error
+	if errors.As(err, &protoErr) {
+		return protoErr.Code >= 500
+	}
+
+	return false
+}
+
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+
+	return string(runes[:n])
+}
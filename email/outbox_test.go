@@ -0,0 +1,216 @@
+package email
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	light "github.com/ptflp/go-light"
+	"github.com/ptflp/go-light/config"
+	"github.com/ptflp/go-light/types"
+	"go.uber.org/zap"
+)
+
+// testOutboxRepository in-memory outbox storage
+type testOutboxRepository struct {
+	mu       sync.Mutex
+	messages []light.OutboxMessage
+}
+
+func (r *testOutboxRepository) Create(ctx context.Context, msg light.OutboxMessage) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.messages {
+		if msg.IdempotencyKey.Valid && r.messages[i].IdempotencyKey.String == msg.IdempotencyKey.String {
+			return false, nil
+		}
+	}
+	r.messages = append(r.messages, msg)
+
+	return true, nil
+}
+
+func (r *testOutboxRepository) Claim(ctx context.Context, limit uint64, lease time.Duration) ([]light.OutboxMessage, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var claimed []light.OutboxMessage
+	for i := range r.messages {
+		msg := &r.messages[i]
+		due := !msg.NextAttemptAt.Time.Time.After(time.Now())
+		if (msg.Status.Int64 == types.OutboxPending || msg.Status.Int64 == types.OutboxSending) && due && uint64(len(claimed)) < limit {
+			msg.Status = types.NewNullInt64(types.OutboxSending)
+			msg.NextAttemptAt.SetValid(time.Now().Add(lease))
+			claimed = append(claimed, *msg)
+		}
+	}
+
+	return claimed, nil
+}
+
+func (r *testOutboxRepository) Update(ctx context.Context, msg light.OutboxMessage) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// updated_at is set on update by db
+	msg.UpdatedAt = time.Now()
+	for i := range r.messages {
+		if r.messages[i].UUID.String == msg.UUID.String {
+			r.messages[i] = msg
+		}
+	}
+
+	return nil
+}
+
+func (r *testOutboxRepository) Stats(ctx context.Context) (light.OutboxStats, error) {
+	return light.OutboxStats{}, nil
+}
+
+func (r *testOutboxRepository) ListDead(ctx context.Context, limit uint64) ([]light.OutboxMessage, error) {
+	return nil, nil
+}
+
+func (r *testOutboxRepository) Purge(ctx context.Context, before time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	kept := r.messages[:0]
+	for _, msg := range r.messages {
+		done := msg.Status.Int64 == types.OutboxSent || msg.Status.Int64 == types.OutboxDead
+		if done && msg.UpdatedAt.Before(before) {
+			continue
+		}
+		kept = append(kept, msg)
+	}
+	purged := int64(len(r.messages) - len(kept))
+	r.messages = kept
+
+	return purged, nil
+}
+
+func (r *testOutboxRepository) get(i int) light.OutboxMessage {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.messages[i]
+}
+
+func TestOutbox_Deliver(t *testing.T) {
+	s := newTestServer(t, false, false, MechPlain)
+	cfg := testConfig(s, config.EmailSecurityPlain)
+	repo := &testOutboxRepository{}
+	outbox := NewOutbox(NewClient(cfg, zap.NewNop()), repo, cfg, zap.NewNop())
+	defer outbox.Close()
+
+	for i := 0; i < 2; i++ {
+		msg := testMsg("user@example.com")
+		msg.SetIdempotencyKey("export:1")
+		if err := outbox.Send(msg); err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+	}
+	if err := outbox.Send(testMsg("reject@example.com")); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if len(repo.messages) != 2 {
+		t.Fatalf("outbox messages = %d, want 2", len(repo.messages))
+	}
+	if len(s.Messages()) != 0 {
+		t.Fatal("message delivered before worker run")
+	}
+
+	outbox.deliver(context.Background())
+
+	// body of delivered message is not kept
+	if msg := repo.get(0); msg.Status.Int64 != types.OutboxSent || !msg.SentAt.Valid || msg.Attempts.Int64 != 1 || msg.Data.String != "" {
+		t.Errorf("delivered message = %+v", msg)
+	}
+	// permanent rejection is not retried
+	if msg := repo.get(1); msg.Status.Int64 != types.OutboxDead || !msg.LastError.Valid || msg.Data.String == "" {
+		t.Errorf("rejected message = %+v", msg)
+	}
+	if msgs := s.Messages(); len(msgs) != 1 || msgs[0].To[0] != "user@example.com" {
+		t.Errorf("server messages = %+v", msgs)
+	}
+
+	// messages are removed after retention
+	outbox.purge(context.Background())
+	if len(repo.messages) != 2 {
+		t.Fatalf("messages purged before retention, left %d", len(repo.messages))
+	}
+	repo.messages[0].UpdatedAt = time.Now().Add(-cfg.Retention() - time.Hour)
+	repo.messages[1].UpdatedAt = time.Now().Add(-cfg.Retention() - time.Hour)
+	outbox.purgedAt = time.Time{}
+	outbox.purge(context.Background())
+	if len(repo.messages) != 0 {
+		t.Errorf("messages after purge = %d", len(repo.messages))
+	}
+}
+
+func TestOutbox_Retry(t *testing.T) {
+	// closed port, every attempt fails with temporary error
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+	_ = ln.Close()
+
+	cfg := &config.Email{ServerAddress: "127.0.0.1", Port: port, From: "noreply@example.com", Security: config.EmailSecurityPlain, OutboxAttempts: 3, OutboxBackoff: 10}
+	repo := &testOutboxRepository{}
+	outbox := NewOutbox(NewClient(cfg, zap.NewNop()), repo, cfg, zap.NewNop())
+	defer outbox.Close()
+
+	if err = outbox.Send(testMsg("user@example.com")); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	for attempt := 1; attempt <= 3; attempt++ {
+		start := time.Now()
+		outbox.deliver(context.Background())
+		msg := repo.get(0)
+		if msg.Attempts.Int64 != int64(attempt) {
+			t.Fatalf("attempts = %d, want %d", msg.Attempts.Int64, attempt)
+		}
+		if attempt == 3 {
+			if msg.Status.Int64 != types.OutboxDead {
+				t.Errorf("status after %d attempts = %d, want dead", attempt, msg.Status.Int64)
+			}
+			break
+		}
+		if msg.Status.Int64 != types.OutboxPending {
+			t.Fatalf("status = %d, want pending", msg.Status.Int64)
+		}
+		delay := msg.NextAttemptAt.Time.Time.Sub(start)
+		want := cfg.RetryDelay(attempt)
+		if delay < want || delay > want+time.Second {
+			t.Errorf("retry delay = %s, want %s", delay, want)
+		}
+		// not due yet
+		outbox.deliver(context.Background())
+		if repo.get(0).Attempts.Int64 != int64(attempt) {
+			t.Fatal("message delivered before backoff expired")
+		}
+		msg.NextAttemptAt.SetValid(time.Now())
+		_ = repo.Update(context.Background(), msg)
+	}
+}
+
+func TestOutbox_AuthFailure(t *testing.T) {
+	s := newTestServer(t, false, false, MechPlain)
+	cfg := testConfig(s, config.EmailSecurityPlain)
+	cfg.Password = "wrong"
+	repo := &testOutboxRepository{}
+	outbox := NewOutbox(NewClient(cfg, zap.NewNop()), repo, cfg, zap.NewNop())
+	defer outbox.Close()
+
+	if err := outbox.Send(testMsg("user@example.com")); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	outbox.deliver(context.Background())
+
+	// 535 reply to AUTH is not caused by message, it is retried after credentials are fixed
+	if msg := repo.get(0); msg.Status.Int64 != types.OutboxPending || msg.Attempts.Int64 != 1 || !msg.LastError.Valid {
+		t.Errorf("message after auth failure = %+v", msg)
+	}
+}
@@ -0,0 +1,275 @@
+package email
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"net"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	testLogin    = "robot@example.com"
+	testPassword = "secret"
+)
+
+type testMessage struct {
+	From string
+	To   []string
+	Data string
+}
+
+// testServer local smtp stand-in, supports subset of ESMTP used by Client
+type testServer struct {
+	t         *testing.T
+	ln        net.Listener
+	tlsConfig *tls.Config
+	startTLS  bool
+	mechs     []string
+
+	mu       sync.Mutex
+	conns    int
+	active   int
+	peak     int
+	resets   int
+	quits    int
+	auths    []string
+	messages []testMessage
+}
+
+func newTestServer(t *testing.T, implicitTLS, startTLS bool, mechs ...string) *testServer {
+	t.Helper()
+	s := &testServer{t: t, startTLS: startTLS, mechs: mechs, tlsConfig: testTLSConfig(t)}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if implicitTLS {
+		ln = tls.NewListener(ln, s.tlsConfig)
+	}
+	s.ln = ln
+	go s.serve()
+	t.Cleanup(func() { _ = ln.Close() })
+
+	return s
+}
+
+func (s *testServer) Port() string {
+	_, port, _ := net.SplitHostPort(s.ln.Addr().String())
+
+	return port
+}
+
+func (s *testServer) Messages() []testMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]testMessage(nil), s.messages...)
+}
+
+func (s *testServer) serve() {
+	for {
+		c, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.conns++
+		s.active++
+		if s.active > s.peak {
+			s.peak = s.active
+		}
+		s.mu.Unlock()
+		go func() {
+			s.handle(c)
+			s.mu.Lock()
+			s.active--
+			s.mu.Unlock()
+		}()
+	}
+}
+
+func (s *testServer) handle(c net.Conn) {
+	defer c.Close()
+	_, encrypted := c.(*tls.Conn)
+	tp := textproto.NewConn(c)
+	reply := func(format string, args ...interface{}) {
+		_ = tp.PrintfLine(format, args...)
+	}
+	reply("220 localhost ESMTP stand-in")
+
+	var msg testMessage
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		cmd, arg := line, ""
+		if i := strings.IndexByte(line, ' '); i > 0 {
+			cmd, arg = line[:i], line[i+1:]
+		}
+
+		switch strings.ToUpper(cmd) {
+		case "EHLO", "HELO":
+			ext := []string{"localhost"}
+			if s.startTLS && !encrypted {
+				ext = append(ext, "STARTTLS")
+			}
+			if len(s.mechs) > 0 {
+				ext = append(ext, "AUTH "+strings.Join(s.mechs, " "))
+			}
+			ext = append(ext, "8BITMIME")
+			for i := range ext {
+				sep := "-"
+				if i == len(ext)-1 {
+					sep = " "
+				}
+				reply("250%s%s", sep, ext[i])
+			}
+		case "STARTTLS":
+			reply("220 ready to start TLS")
+			tc := tls.Server(c, s.tlsConfig)
+			if err = tc.Handshake(); err != nil {
+				return
+			}
+			c, encrypted = tc, true
+			tp = textproto.NewConn(c)
+		case "AUTH":
+			mech, ok := s.auth(tp, arg)
+			if !ok {
+				reply("535 authentication failed")
+				continue
+			}
+			s.mu.Lock()
+			s.auths = append(s.auths, mech)
+			s.mu.Unlock()
+			reply("235 authenticated")
+		case "MAIL":
+			msg = testMessage{From: address(arg)}
+			reply("250 ok")
+		case "RCPT":
+			to := address(arg)
+			if strings.HasPrefix(to, "reject@") {
+				reply("550 mailbox unavailable")
+				continue
+			}
+			msg.To = append(msg.To, to)
+			reply("250 ok")
+		case "DATA":
+			reply("354 end data with <CR><LF>.<CR><LF>")
+			data, err := tp.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			msg.Data = string(data)
+			s.mu.Lock()
+			s.messages = append(s.messages, msg)
+			s.mu.Unlock()
+			reply("250 queued")
+		case "RSET":
+			msg = testMessage{}
+			s.mu.Lock()
+			s.resets++
+			s.mu.Unlock()
+			reply("250 ok")
+		case "NOOP":
+			reply("250 ok")
+		case "QUIT":
+			s.mu.Lock()
+			s.quits++
+			s.mu.Unlock()
+			reply("221 bye")
+			return
+		default:
+			reply("502 command not implemented")
+		}
+	}
+}
+
+func (s *testServer) auth(tp *textproto.Conn, arg string) (string, bool) {
+	fields := strings.Fields(arg)
+	if len(fields) == 0 {
+		return "", false
+	}
+	mech := strings.ToUpper(fields[0])
+	read := func(challenge string) string {
+		_ = tp.PrintfLine("334 %s", base64.StdEncoding.EncodeToString([]byte(challenge)))
+		line, _ := tp.ReadLine()
+		b, _ := base64.StdEncoding.DecodeString(line)
+		return string(b)
+	}
+
+	switch mech {
+	case MechPlain:
+		var resp string
+		if len(fields) > 1 {
+			b, _ := base64.StdEncoding.DecodeString(fields[1])
+			resp = string(b)
+		} else {
+			resp = read("")
+		}
+		parts := strings.Split(resp, "\x00")
+		return mech, len(parts) == 3 && parts[1] == testLogin && parts[2] == testPassword
+	case MechLogin:
+		user := read("Username:")
+		pass := read("Password:")
+		return mech, user == testLogin && pass == testPassword
+	case MechCRAMMD5:
+		challenge := fmt.Sprintf("<%d.%d@localhost>", time.Now().UnixNano(), 1)
+		resp := strings.Fields(read(challenge))
+		mac := hmac.New(md5.New, []byte(testPassword))
+		mac.Write([]byte(challenge))
+		return mech, len(resp) == 2 && resp[0] == testLogin && resp[1] == hex.EncodeToString(mac.Sum(nil))
+	}
+
+	return mech, false
+}
+
+func address(arg string) string {
+	if i := strings.IndexByte(arg, '<'); i >= 0 {
+		arg = arg[i+1:]
+	}
+	if i := strings.IndexByte(arg, '>'); i >= 0 {
+		arg = arg[:i]
+	}
+
+	return arg
+}
+
+func testTLSConfig(t *testing.T) *tls.Config {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		DNSNames:     []string{"localhost"},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}}
+}
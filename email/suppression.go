@@ -0,0 +1,118 @@
+package email
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+
+	light "github.com/ptflp/go-light"
+	"go.uber.org/zap"
+)
+
+const (
+	suppressionTimeout = outboxTimeout
+
+	unsubscribeHeader     = "List-Unsubscribe"
+	unsubscribePostHeader = "List-Unsubscribe-Post"
+	unsubscribeOneClick   = "List-Unsubscribe=One-Click"
+)
+
+var (
+	ErrSuppressed       = errors.New("all recipients are suppressed")
+	ErrUnsubscribeToken = errors.New("wrong unsubscribe token")
+)
+
+// Suppressor Mailer which drops recipients from suppression list before message is sent
+type Suppressor struct {
+	Mailer
+	repo   light.SuppressionRepository
+	logger *zap.Logger
+}
+
+func NewSuppressor(mailer Mailer, repo light.SuppressionRepository, logger *zap.Logger) *Suppressor {
+	return &Suppressor{Mailer: mailer, repo: repo, logger: logger}
+}
+
+func (s *Suppressor) Send(msg Messager) error {
+	rcpts := msg.Recipients()
+	emails := make([]string, 0, len(rcpts))
+	for i := range rcpts {
+		emails = append(emails, strings.ToLower(rcpts[i]))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), suppressionTimeout)
+	defer cancel()
+	suppressed, err := s.repo.Suppressed(ctx, emails)
+	if err != nil {
+		s.logger.Error("suppression list lookup", zap.Error(err))
+		return err
+	}
+	for i := range suppressed {
+		s.logger.Info("suppressed recipient skipped", zap.String("email", suppressed[i]))
+		msg.RemoveRecipient(suppressed[i])
+	}
+	if len(suppressed) > 0 && len(msg.Recipients()) == 0 {
+		return ErrSuppressed
+	}
+
+	return s.Mailer.Send(msg)
+}
+
+// UnsubscribeSigner issues and verifies unsubscribe tokens, token has no expiration as links in old emails must keep working
+type UnsubscribeSigner struct {
+	secret []byte
+}
+
+// NewUnsubscribeSigner random secret is used when empty, tokens are valid for current instance only then
+func NewUnsubscribeSigner(secret string) (*UnsubscribeSigner, error) {
+	key := []byte(secret)
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+	}
+
+	return &UnsubscribeSigner{secret: key}, nil
+}
+
+func (u *UnsubscribeSigner) Token(userID, category string) string {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(userID + ":" + category))
+
+	return payload + "." + u.sign(payload)
+}
+
+// Parse returns user id and category of valid token
+func (u *UnsubscribeSigner) Parse(token string) (string, string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 || !hmac.Equal([]byte(parts[1]), []byte(u.sign(parts[0]))) {
+		return "", "", ErrUnsubscribeToken
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return "", "", ErrUnsubscribeToken
+	}
+	i := strings.LastIndex(string(payload), ":")
+	if i < 0 {
+		return "", "", ErrUnsubscribeToken
+	}
+
+	return string(payload[:i]), string(payload[i+1:]), nil
+}
+
+func (u *UnsubscribeSigner) sign(payload string) string {
+	mac := hmac.New(sha256.New, u.secret)
+	mac.Write([]byte(payload))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// SetUnsubscribe adds one-click unsubscribe headers (RFC 8058), link must accept POST
+func SetUnsubscribe(msg Messager, link string) {
+	msg.SetHeader(unsubscribeHeader, "<"+link+">")
+	msg.SetHeader(unsubscribePostHeader, unsubscribeOneClick)
+}
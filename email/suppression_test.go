@@ -0,0 +1,150 @@
+package email
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	light "github.com/ptflp/go-light"
+	"github.com/ptflp/go-light/config"
+	"github.com/ptflp/go-light/types"
+	"go.uber.org/zap"
+)
+
+type testSuppressionRepository struct {
+	light.SuppressionRepository
+	suppressed map[string]bool
+}
+
+func (r *testSuppressionRepository) Suppressed(ctx context.Context, emails []string) ([]string, error) {
+	var suppressed []string
+	for _, addr := range emails {
+		if r.suppressed[addr] {
+			suppressed = append(suppressed, addr)
+		}
+	}
+
+	return suppressed, nil
+}
+
+func TestSuppressor_Send(t *testing.T) {
+	catcher, err := NewCatcher(&config.Email{From: "noreply@example.com"}, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := &testSuppressionRepository{suppressed: map[string]bool{"bounced@example.com": true}}
+	s := NewSuppressor(catcher, repo, zap.NewNop())
+
+	msg := testMsg("User <user@example.com>")
+	msg.AddCC("Bounced@Example.com")
+	if err = s.Send(msg); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	caught := catcher.List()
+	if len(caught) != 1 || !reflect.DeepEqual(caught[0].Recipients, []string{"user@example.com"}) {
+		t.Fatalf("caught = %+v", caught)
+	}
+
+	if err = s.Send(testMsg("bounced@example.com")); err != ErrSuppressed {
+		t.Fatalf("Send() error = %v, want %v", err, ErrSuppressed)
+	}
+	if len(catcher.List()) != 1 {
+		t.Error("message to suppressed recipient is sent")
+	}
+}
+
+func TestUnsubscribeSigner(t *testing.T) {
+	signer, err := NewUnsubscribeSigner("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := signer.Token("8d5e4a2c-1f3b-4c6d-9e7f-0a1b2c3d4e5f", types.NotifyNews)
+	userID, category, err := signer.Parse(token)
+	if err != nil || userID != "8d5e4a2c-1f3b-4c6d-9e7f-0a1b2c3d4e5f" || category != types.NotifyNews {
+		t.Fatalf("Parse() = %q, %q, %v", userID, category, err)
+	}
+
+	other, _ := NewUnsubscribeSigner("other")
+	forged := signer.Token("8d5e4a2c-1f3b-4c6d-9e7f-0a1b2c3d4e5f", types.NotifyAll)
+	for _, token := range []string{"", "abc", forged[:strings.Index(forged, ".")] + token[strings.Index(token, "."):], other.Token("id", types.NotifyAll)} {
+		if _, _, err = signer.Parse(token); err != ErrUnsubscribeToken {
+			t.Errorf("Parse(%q) error = %v", token, err)
+		}
+	}
+
+	msg := testMsg("user@example.com")
+	SetUnsubscribe(msg, "https://api.example.com/email/unsubscribe?token="+token)
+	data := string(msg.Bytes())
+	if !strings.Contains(data, "List-Unsubscribe: <https://api.example.com/email/unsubscribe?token="+token+">\r\n") ||
+		!strings.Contains(data, "List-Unsubscribe-Post: List-Unsubscribe=One-Click\r\n") {
+		t.Errorf("message headers:\n%s", data)
+	}
+}
+
+func TestParseReport(t *testing.T) {
+	dsn := "--b\r\n" +
+		"Content-Type: text/plain\r\n\r\n" +
+		"Delivery failed.\r\n" +
+		"--b\r\n" +
+		"Content-Type: message/delivery-status\r\n\r\n" +
+		"Reporting-MTA: dns; mx.example.com\r\n\r\n" +
+		"Final-Recipient: rfc822; Gone@Example.com\r\n" +
+		"Action: failed\r\n" +
+		"Status: 5.1.1\r\n" +
+		"Diagnostic-Code: smtp; 550 user unknown\r\n\r\n" +
+		"Final-Recipient: rfc822; full@example.com\r\n" +
+		"Action: delayed\r\n" +
+		"Status: 4.2.2\r\n\r\n" +
+		"--b--\r\n"
+	arf := "--b\r\n" +
+		"Content-Type: message/feedback-report\r\n\r\n" +
+		"Feedback-Type: abuse\r\n" +
+		"Version: 1\r\n\r\n" +
+		"--b\r\n" +
+		"Content-Type: text/rfc822-headers\r\n\r\n" +
+		"From: noreply@example.com\r\n" +
+		"To: User <spam@example.com>\r\n" +
+		"--b--\r\n"
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        []Bounce
+	}{
+		{
+			name:        "dsn",
+			contentType: `multipart/report; report-type=delivery-status; boundary="b"`,
+			body:        dsn,
+			want:        []Bounce{{Email: "gone@example.com", Type: BounceTypeBounce, Details: "5.1.1 smtp; 550 user unknown"}},
+		},
+		{
+			name:        "arf",
+			contentType: `multipart/report; report-type=feedback-report; boundary="b"`,
+			body:        arf,
+			want:        []Bounce{{Email: "spam@example.com", Type: BounceTypeComplaint, Details: "abuse"}},
+		},
+		{
+			name:        "json",
+			contentType: "application/json",
+			body:        `[{"email": "a@example.com", "type": "bounce"}, {"email": "wrong", "type": "complaint"}]`,
+			want:        []Bounce{{Email: "a@example.com", Type: BounceTypeBounce}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseReport(tt.contentType, []byte(tt.body))
+			if err != nil {
+				t.Fatalf("ParseReport() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseReport() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := ParseReport("application/json", []byte(`{"email": "a@example.com", "type": "soft"}`)); err == nil {
+		t.Error("unknown bounce type is accepted")
+	}
+}
@@ -0,0 +1,259 @@
+package email
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"html"
+	"html/template"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/ptflp/go-light/types"
+)
+
+const (
+	TemplateActivation          = "activation"
+	TemplatePasswordRecovery    = "password_recovery"
+	TemplateRegistrationAttempt = "registration_attempt"
+	TemplateExport              = "export"
+	TemplateNotification        = "notification"
+
+	defaultLanguage = "ru"
+	layoutFile      = "layout.html"
+	previewFile     = "preview.json"
+)
+
+// Languages template directory of User.Language
+var Languages = map[int64]string{
+	types.LanguageRu: "ru",
+	types.LanguageEn: "en",
+}
+
+//go:embed templates templates/*/_*.html
+var embedded embed.FS // partials starting with "_" are not matched by directory pattern
+
+// Rendered email content, text is generated from html when template has no "text" block
+type Rendered struct {
+	Subject string `json:"subject"`
+	HTML    string `json:"html"`
+	Text    string `json:"text"`
+}
+
+// Templates registry of email templates parsed on start.
+// Layout is shared, every language directory contains templates and partials (files starting with "_"),
+// every template defines "subject" and "content" blocks. Files of override directory replace embedded defaults.
+type Templates struct {
+	templates map[string]*template.Template
+	previews  map[string]json.RawMessage
+}
+
+func NewTemplates(dir string) (*Templates, error) {
+	root, err := fs.Sub(embedded, "templates")
+	if err != nil {
+		return nil, err
+	}
+	var files fs.FS = root
+	if dir != "" {
+		files = overlay{upper: os.DirFS(dir), lower: root}
+	}
+
+	t := &Templates{templates: make(map[string]*template.Template)}
+	if b, err := fs.ReadFile(files, previewFile); err == nil {
+		if err = json.Unmarshal(b, &t.previews); err != nil {
+			return nil, fmt.Errorf("parse %s: %w", previewFile, err)
+		}
+	}
+
+	layout, err := fs.ReadFile(files, layoutFile)
+	if err != nil {
+		return nil, err
+	}
+	base, err := template.New(layoutFile).Parse(string(layout))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, lang := range Languages {
+		names, err := glob(files, lang+"/*.html")
+		if err != nil {
+			return nil, err
+		}
+		partials, err := base.Clone()
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			if !strings.HasPrefix(path.Base(name), "_") {
+				continue
+			}
+			if err = parse(partials, files, name); err != nil {
+				return nil, err
+			}
+		}
+		for _, name := range names {
+			if strings.HasPrefix(path.Base(name), "_") {
+				continue
+			}
+			tmpl, err := partials.Clone()
+			if err != nil {
+				return nil, err
+			}
+			if err = parse(tmpl, files, name); err != nil {
+				return nil, err
+			}
+			for _, block := range []string{"subject", "content"} {
+				if tmpl.Lookup(block) == nil {
+					return nil, fmt.Errorf("template %s has no %q block", name, block)
+				}
+			}
+			t.templates[strings.TrimSuffix(name, ".html")] = tmpl
+		}
+	}
+
+	return t, nil
+}
+
+// Render executes template in user language, default language is used when translation is missing
+func (t *Templates) Render(name string, language int64, data interface{}) (Rendered, error) {
+	return t.render(name, Languages[language], data)
+}
+
+// Message builds html message with subject and text alternative from template
+func (t *Templates) Message(name string, language int64, data interface{}) (*Message, error) {
+	rendered, err := t.Render(name, language, data)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := NewMessage()
+	msg.SetSubject(rendered.Subject)
+	msg.SetType(TypeHtml)
+	msg.SetBody(*bytes.NewBufferString(rendered.HTML))
+	msg.SetTextBody(*bytes.NewBufferString(rendered.Text))
+
+	return msg, nil
+}
+
+// Preview renders template with sample data of preview.json
+func (t *Templates) Preview(name, lang string) (Rendered, error) {
+	var data map[string]interface{}
+	if raw, ok := t.previews[name]; ok {
+		if err := json.Unmarshal(raw, &data); err != nil {
+			return Rendered{}, err
+		}
+	}
+
+	return t.render(name, lang, data)
+}
+
+// Names template names available in default language
+func (t *Templates) Names() []string {
+	var names []string
+	for key := range t.templates {
+		if lang, name := path.Split(key); lang == defaultLanguage+"/" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func (t *Templates) render(name, lang string, data interface{}) (Rendered, error) {
+	tmpl, ok := t.templates[lang+"/"+name]
+	if !ok {
+		lang = defaultLanguage
+		tmpl, ok = t.templates[lang+"/"+name]
+	}
+	if !ok {
+		return Rendered{}, fmt.Errorf("email template %s not found", name)
+	}
+
+	var subject, body bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return Rendered{}, err
+	}
+	err := tmpl.ExecuteTemplate(&body, "layout", struct {
+		Lang string
+		Data interface{}
+	}{Lang: lang, Data: data})
+	if err != nil {
+		return Rendered{}, err
+	}
+
+	rendered := Rendered{
+		Subject: strings.TrimSpace(html.UnescapeString(subject.String())),
+		HTML:    body.String(),
+	}
+	if tmpl.Lookup("text") != nil {
+		var text bytes.Buffer
+		if err = tmpl.ExecuteTemplate(&text, "text", data); err != nil {
+			return Rendered{}, err
+		}
+		rendered.Text = strings.TrimSpace(html.UnescapeString(text.String()))
+	} else {
+		rendered.Text = htmlToText(rendered.HTML)
+	}
+
+	return rendered, nil
+}
+
+func parse(tmpl *template.Template, files fs.FS, name string) error {
+	b, err := fs.ReadFile(files, name)
+	if err != nil {
+		return err
+	}
+	_, err = tmpl.New(name).Parse(string(b))
+	if err != nil {
+		return fmt.Errorf("parse email template %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// glob matches files of both overlay layers
+func glob(files fs.FS, pattern string) ([]string, error) {
+	o, ok := files.(overlay)
+	if !ok {
+		return fs.Glob(files, pattern)
+	}
+	upper, err := fs.Glob(o.upper, pattern)
+	if err != nil {
+		return nil, err
+	}
+	lower, err := fs.Glob(o.lower, pattern)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	var names []string
+	for _, name := range append(upper, lower...) {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// overlay file system, files of upper layer replace files of lower one
+type overlay struct {
+	upper fs.FS
+	lower fs.FS
+}
+
+func (o overlay) Open(name string) (fs.File, error) {
+	f, err := o.upper.Open(name)
+	if err == nil {
+		return f, nil
+	}
+
+	return o.lower.Open(name)
+}
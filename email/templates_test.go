@@ -0,0 +1,98 @@
+package email
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ptflp/go-light/types"
+)
+
+func TestTemplates_Render(t *testing.T) {
+	tmpls, err := NewTemplates("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := tmpls.Names()
+	if len(names) != 5 {
+		t.Fatalf("templates = %v", names)
+	}
+
+	for _, name := range names {
+		for _, lang := range Languages {
+			rendered, err := tmpls.Preview(name, lang)
+			if err != nil {
+				t.Fatalf("Preview(%s, %s) error = %v", name, lang, err)
+			}
+			if rendered.Subject == "" || strings.ContainsAny(rendered.Subject, "<>\n") {
+				t.Errorf("%s/%s subject = %q", lang, name, rendered.Subject)
+			}
+			if !strings.Contains(rendered.HTML, `<html lang="`+lang+`">`) || !strings.Contains(rendered.HTML, "https://example.com/") {
+				t.Errorf("%s/%s html:\n%s", lang, name, rendered.HTML)
+			}
+			if strings.Contains(rendered.Text, "<") || !strings.Contains(rendered.Text, "(https://example.com/") {
+				t.Errorf("%s/%s text:\n%s", lang, name, rendered.Text)
+			}
+		}
+	}
+
+	data := map[string]string{"Link": "https://example.com/?a=1&b=2"}
+	en, err := tmpls.Render(TemplatePasswordRecovery, types.LanguageEn, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if en.Subject != "Password recovery" || !strings.Contains(en.HTML, "a=1&amp;b=2") {
+		t.Errorf("english rendering = %+v", en)
+	}
+	// unknown language falls back to default
+	ru, err := tmpls.Render(TemplatePasswordRecovery, 0, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ru.Subject != "Восстановление пароля" {
+		t.Errorf("default language subject = %q", ru.Subject)
+	}
+	if _, err = tmpls.Render("missing", types.LanguageRu, data); err == nil {
+		t.Error("Render() of missing template succeeded")
+	}
+}
+
+func TestTemplates_Override(t *testing.T) {
+	dir, err := ioutil.TempDir("", "templates")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err = os.Mkdir(filepath.Join(dir, "en"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	override := `{{define "subject"}}Reset {{.Name}} & more{{end}}
+{{define "content"}}<p>Custom {{.Link}}</p>{{end}}
+{{define "text"}}Custom text {{.Link}}{{end}}`
+	if err = ioutil.WriteFile(filepath.Join(dir, "en", "password_recovery.html"), []byte(override), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tmpls, err := NewTemplates(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := map[string]string{"Name": "Ann", "Link": "https://example.com/reset"}
+	msg, err := tmpls.Message(TemplatePasswordRecovery, types.LanguageEn, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.GetSubject() != "Reset Ann & more" || msg.text != "Custom text https://example.com/reset" {
+		t.Errorf("overridden message subject = %q, text = %q", msg.GetSubject(), msg.text)
+	}
+	// shared layout and footer are kept
+	if !strings.Contains(msg.body, "<p>Custom https://example.com/reset</p>") || !strings.Contains(msg.body, "sent automatically") {
+		t.Errorf("overridden message body:\n%s", msg.body)
+	}
+	// other templates are embedded defaults
+	if rendered, err := tmpls.Render(TemplateExport, types.LanguageEn, data); err != nil || rendered.Subject != "Personal data export" {
+		t.Errorf("Render() = %+v, %v", rendered, err)
+	}
+}
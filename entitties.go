@@ -54,6 +54,16 @@ type Constraints struct {
 func init() {
 	RegisterEntities(
 		User{},
+		Invite{},
+		InviteUse{},
+		OAuthClient{},
+		OAuthConsent{},
+		AuditEvent{},
+		OutboxMessage{},
+		EmailSuppression{},
+		EmailUnsubscribe{},
+		SMSMessage{},
+		PushSubscription{},
 	)
 }
 
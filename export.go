@@ -0,0 +1,9 @@
+package light
+
+import "context"
+
+// ExportRepository retrieves rows of registered entities which reference user by user_uuid field
+type ExportRepository interface {
+	// FindByUser selects only specified fields of table, secrets and data of other users are never exported
+	FindByUser(ctx context.Context, table Table, fields []string, user User) ([]map[string]interface{}, error)
+}
@@ -0,0 +1,71 @@
+package light
+
+import (
+	"context"
+	"time"
+
+	"github.com/ptflp/go-light/request"
+	"github.com/ptflp/go-light/types"
+)
+
+type Invite struct {
+	UUID      types.NullUUID   `json:"invite_id" db:"uuid" ops:"create" orm_type:"binary(16)" orm_default:"not null primary key"`
+	Code      types.NullString `json:"code" db:"code" ops:"create" orm_type:"varchar(34)" orm_default:"not null" orm_index:"index,unique"`
+	UserUUID  types.NullUUID   `json:"user_id" db:"user_uuid" ops:"create" orm_type:"binary(16)" orm_default:"null" orm_index:"index"`
+	MaxUses   types.NullInt64  `json:"max_uses" db:"max_uses" ops:"create" orm_type:"int" orm_default:"not null"`
+	Uses      types.NullInt64  `json:"uses" db:"uses" orm_type:"int" orm_default:"default 0 not null"`
+	ExpiresAt types.NullTime   `json:"expires_at" db:"expires_at" ops:"create" orm_type:"timestamp" orm_default:"null"`
+	CreatedAt time.Time        `json:"created_at" db:"created_at" orm_type:"timestamp" orm_default:"default (now()) not null" orm_index:"index"`
+	UpdatedAt time.Time        `json:"updated_at" db:"updated_at" orm_type:"timestamp" orm_default:"default (now()) null on update CURRENT_TIMESTAMP"`
+	DeletedAt types.NullTime   `json:"deleted_at" db:"deleted_at" orm_type:"timestamp" orm_default:"null" orm_index:"index"`
+}
+
+func (i Invite) OnCreate() string {
+	return ""
+}
+
+func (i Invite) TableName() string {
+	return "invites"
+}
+
+// InviteUse inviter and invitee relation
+type InviteUse struct {
+	UUID        types.NullUUID `json:"invite_use_id" db:"uuid" ops:"create" orm_type:"binary(16)" orm_default:"not null primary key"`
+	InviteUUID  types.NullUUID `json:"invite_id" db:"invite_uuid" ops:"create" orm_type:"binary(16)" orm_default:"not null" orm_index:"index"`
+	InviterUUID types.NullUUID `json:"inviter_id" db:"inviter_uuid" ops:"create" orm_type:"binary(16)" orm_default:"null" orm_index:"index"`
+	UserUUID    types.NullUUID `json:"user_id" db:"user_uuid" ops:"create" orm_type:"binary(16)" orm_default:"not null" orm_index:"index,unique"`
+	CreatedAt   time.Time      `json:"created_at" db:"created_at" orm_type:"timestamp" orm_default:"default (now()) not null" orm_index:"index"`
+}
+
+func (i InviteUse) OnCreate() string {
+	return ""
+}
+
+func (i InviteUse) TableName() string {
+	return "invite_uses"
+}
+
+type InviteRepository interface {
+	Create(ctx context.Context, invite Invite) error
+	FindByCode(ctx context.Context, invite Invite) (Invite, error)
+	CountByUser(ctx context.Context, user User) (int64, error)
+	ListByUser(ctx context.Context, user User) ([]Invite, error)
+	// Redeem creates invitee in transaction of invite usage
+	Redeem(ctx context.Context, invite Invite, inviteUse InviteUse, invitee User) error
+	// RedeemGuest upgrades guest in transaction of invite usage
+	RedeemGuest(ctx context.Context, invite Invite, inviteUse InviteUse, guest User) error
+	Referrals(ctx context.Context, user User) ([]InviteUse, error)
+}
+
+type InviteService interface {
+	Required() bool
+	Check(ctx context.Context, code string) error
+	// Redeem creates user, invite is spent only when user is created
+	Redeem(ctx context.Context, code string, invitee User) error
+	// RedeemGuest upgrades guest with identity, invite is spent only when guest is upgraded
+	RedeemGuest(ctx context.Context, code string, identity User) error
+
+	Create(ctx context.Context, req request.InviteCreateReq) (request.InviteData, error)
+	List(ctx context.Context) ([]request.InviteData, error)
+	Referrals(ctx context.Context) ([]request.ReferralData, error)
+}
@@ -1,3 +1,4 @@
 #!/bin/sh
 openssl genrsa -out private 2048
-openssl rsa -in private -pubout -out public
\ No newline at end of file
+openssl rsa -in private -pubout -out public
+openssl ecparam -name prime256v1 -genkey -noout -out vapid
@@ -0,0 +1,58 @@
+package middlewares
+
+import (
+	"net"
+	"net/http"
+
+	"github.com/ptflp/go-light/challenge"
+	"github.com/ptflp/go-light/respond"
+)
+
+const (
+	ChallengeHeader         = "X-Challenge"
+	ChallengeSolutionHeader = "X-Challenge-Solution"
+)
+
+type Challenge struct {
+	respond.Responder
+	verifier challenge.Verifier
+	routes   map[string]struct{}
+}
+
+func NewChallenge(responder respond.Responder, verifier challenge.Verifier, routes []string) *Challenge {
+	c := &Challenge{
+		Responder: responder,
+		verifier:  verifier,
+		routes:    make(map[string]struct{}, len(routes)),
+	}
+	for i := range routes {
+		c.routes[routes[i]] = struct{}{}
+	}
+
+	return c
+}
+
+// Check requires solved challenge on configured routes, disabled when verifier is not set
+func (c *Challenge) Check(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := c.routes[r.URL.Path]; !ok || c.verifier == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ip, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			ip = r.RemoteAddr
+		}
+		err = c.verifier.Verify(r.Context(), challenge.Solution{
+			Challenge: r.Header.Get(ChallengeHeader),
+			Response:  r.Header.Get(ChallengeSolutionHeader),
+			RemoteIP:  ip,
+		})
+		if err != nil {
+			c.ErrorForbidden(w, err)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,81 @@
+package middlewares
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	light "github.com/ptflp/go-light"
+	"github.com/ptflp/go-light/respond"
+	"github.com/ptflp/go-light/types"
+)
+
+type RateLimit struct {
+	respond.Responder
+	limit  int
+	window time.Duration
+
+	mu          sync.Mutex
+	windowStart time.Time
+	hits        map[string]int
+}
+
+func NewRateLimit(responder respond.Responder, limit int, window time.Duration) *RateLimit {
+	return &RateLimit{
+		Responder: responder,
+		limit:     limit,
+		window:    window,
+		hits:      make(map[string]int),
+	}
+}
+
+// Limit counts requests per user, per ip for anonymous requests
+func (l *RateLimit) Limit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := remoteIP(r)
+		if u, ok := r.Context().Value(types.User{}).(*light.User); ok && u.UUID.Valid {
+			key = u.UUID.String
+		}
+
+		if !l.allow(key) {
+			l.ErrorTooManyRequests(w, errors.New("too many requests"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// LimitIP counts requests per ip even when authenticated, e.g. for account creation
+func (l *RateLimit) LimitIP(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !l.allow(remoteIP(r)) {
+			l.ErrorTooManyRequests(w, errors.New("too many requests"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (l *RateLimit) allow(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if time.Since(l.windowStart) > l.window {
+		l.windowStart = time.Now()
+		l.hits = make(map[string]int)
+	}
+	l.hits[key]++
+
+	return l.hits[key] <= l.limit
+}
+
+func remoteIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return ip
+}
@@ -0,0 +1,81 @@
+package middlewares
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	light "github.com/ptflp/go-light"
+	"github.com/ptflp/go-light/respond"
+	"github.com/ptflp/go-light/types"
+	"go.uber.org/zap"
+)
+
+func newTestRateLimit(t *testing.T, limit int, window time.Duration) *RateLimit {
+	t.Helper()
+	responder, err := respond.NewResponder(zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return NewRateLimit(responder, limit, window)
+}
+
+// limited serves request of remote ip authorized by user and returns status code
+func limited(middleware func(http.Handler) http.Handler, ip string, user *light.User) int {
+	r := httptest.NewRequest("POST", "/auth/guest", nil)
+	r.RemoteAddr = ip + ":1234"
+	if user != nil {
+		r = r.WithContext(context.WithValue(r.Context(), types.User{}, user))
+	}
+	w := httptest.NewRecorder()
+	middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).ServeHTTP(w, r)
+
+	return w.Code
+}
+
+func TestRateLimit_Limit(t *testing.T) {
+	l := newTestRateLimit(t, 1, 50*time.Millisecond)
+	user := &light.User{UUID: types.NewNullUUID()}
+
+	if code := limited(l.Limit, "192.0.2.1", user); code != http.StatusOK {
+		t.Fatalf("first request status = %d", code)
+	}
+	if code := limited(l.Limit, "192.0.2.1", user); code != http.StatusTooManyRequests {
+		t.Errorf("request over limit status = %d, want %d", code, http.StatusTooManyRequests)
+	}
+	// authenticated requests are counted per user, anonymous per ip
+	if code := limited(l.Limit, "192.0.2.1", &light.User{UUID: types.NewNullUUID()}); code != http.StatusOK {
+		t.Errorf("request of another user status = %d", code)
+	}
+	if code := limited(l.Limit, "192.0.2.1", nil); code != http.StatusOK {
+		t.Errorf("anonymous request status = %d", code)
+	}
+	if code := limited(l.Limit, "192.0.2.1", nil); code != http.StatusTooManyRequests {
+		t.Errorf("anonymous request over limit status = %d, want %d", code, http.StatusTooManyRequests)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if code := limited(l.Limit, "192.0.2.1", user); code != http.StatusOK {
+		t.Errorf("request in next window status = %d", code)
+	}
+}
+
+func TestRateLimit_LimitIP(t *testing.T) {
+	l := newTestRateLimit(t, 2, time.Minute)
+
+	// tokens of new accounts don't reset limit of ip
+	for i := 0; i < 2; i++ {
+		if code := limited(l.LimitIP, "192.0.2.1", &light.User{UUID: types.NewNullUUID()}); code != http.StatusOK {
+			t.Fatalf("request %d status = %d", i+1, code)
+		}
+	}
+	if code := limited(l.LimitIP, "192.0.2.1", &light.User{UUID: types.NewNullUUID()}); code != http.StatusTooManyRequests {
+		t.Errorf("request over limit status = %d, want %d", code, http.StatusTooManyRequests)
+	}
+	if code := limited(l.LimitIP, "192.0.2.2", nil); code != http.StatusOK {
+		t.Errorf("request of another ip status = %d", code)
+	}
+}
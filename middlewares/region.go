@@ -0,0 +1,26 @@
+package middlewares
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/ptflp/go-light/types"
+)
+
+type Region struct {
+	header string
+}
+
+func NewRegion(header string) *Region {
+	return &Region{header: header}
+}
+
+// Detect puts country code of client from header set by proxy (e.g. CF-IPCountry) into context
+func (rg *Region) Detect(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if region := r.Header.Get(rg.header); region != "" {
+			r = r.WithContext(context.WithValue(r.Context(), types.Region{}, region))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,46 @@
+package middlewares
+
+import (
+	"bytes"
+	"net/http"
+	"time"
+)
+
+type UniformTiming struct {
+	duration time.Duration
+}
+
+func NewUniformTiming(duration time.Duration) *UniformTiming {
+	return &UniformTiming{duration: duration}
+}
+
+// Pad holds response until minimal duration passed, so response time does not depend on handler branch
+func (u *UniformTiming) Pad(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		bw := &bufferedWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(bw, r)
+
+		select {
+		case <-r.Context().Done():
+		case <-time.After(u.duration - time.Since(start)):
+		}
+
+		w.WriteHeader(bw.status)
+		_, _ = w.Write(bw.body.Bytes())
+	})
+}
+
+type bufferedWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (b *bufferedWriter) WriteHeader(status int) {
+	b.status = status
+}
+
+func (b *bufferedWriter) Write(p []byte) (int, error) {
+	return b.body.Write(p)
+}
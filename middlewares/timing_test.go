@@ -0,0 +1,42 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestUniformTiming_Pad(t *testing.T) {
+	const duration = 100 * time.Millisecond
+	u := NewUniformTiming(duration)
+
+	handler := func(delay time.Duration, status int) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(delay)
+			w.WriteHeader(status)
+			_, _ = w.Write([]byte("body"))
+		})
+	}
+	tests := []struct {
+		name   string
+		delay  time.Duration
+		status int
+	}{
+		{name: "fast", status: http.StatusBadRequest},
+		{name: "slow", delay: duration / 2, status: http.StatusOK},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		start := time.Now()
+		u.Pad(handler(tt.delay, tt.status)).ServeHTTP(w, httptest.NewRequest("POST", "/auth/email/registration", nil))
+		elapsed := time.Since(start)
+
+		if elapsed < duration || elapsed > duration+50*time.Millisecond {
+			t.Errorf("Pad() %s handler response time = %s, want %s", tt.name, elapsed, duration)
+		}
+		if w.Code != tt.status || w.Body.String() != "body" {
+			t.Errorf("Pad() %s handler response = %d %q", tt.name, w.Code, w.Body.String())
+		}
+	}
+}
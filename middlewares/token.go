@@ -52,3 +52,27 @@ func (t *Token) Check(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
+
+// DenyGuests restricts route to registered users, must follow CheckStrict
+func (t *Token) DenyGuests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		u, ok := r.Context().Value(types.User{}).(*light.User)
+		if !ok || u.IsGuest() {
+			t.ErrorForbidden(w, errors.New("registration required"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// DenyImpersonation restricts route to tokens issued to user itself, must follow CheckStrict
+func (t *Token) DenyImpersonation(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		u, ok := r.Context().Value(types.User{}).(*light.User)
+		if !ok || u.Impersonated() {
+			t.ErrorForbidden(w, light.ErrImpersonated)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
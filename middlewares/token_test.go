@@ -0,0 +1,101 @@
+package middlewares
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	light "github.com/ptflp/go-light"
+	"github.com/ptflp/go-light/cache"
+	"github.com/ptflp/go-light/respond"
+	"github.com/ptflp/go-light/session"
+	"github.com/ptflp/go-light/types"
+	"go.uber.org/zap"
+)
+
+func newTestToken(t *testing.T) *Token {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	responder, err := respond.NewResponder(zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return NewCheckToken(responder, session.NewJWTKeysWithKey(zap.NewNop(), cache.NewMemory(), key))
+}
+
+// serve passes request authorized by access token through CheckStrict and middleware, returns status code
+func serve(t *testing.T, tk *Token, middleware func(http.Handler) http.Handler, accessToken string) int {
+	t.Helper()
+	handler := tk.CheckStrict(middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})))
+	r := httptest.NewRequest("POST", "/profile", nil)
+	r.Header.Set("Authorization", "Bearer "+accessToken)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	return w.Code
+}
+
+func TestToken_DenyGuests(t *testing.T) {
+	tk := newTestToken(t)
+	tests := []struct {
+		role int64
+		want int
+	}{
+		{role: types.RoleGuest, want: http.StatusForbidden},
+		{role: types.RoleUser, want: http.StatusOK},
+	}
+	for _, tt := range tests {
+		token, err := tk.jwt.CreateAccessToken(light.User{UUID: types.NewNullUUID(), Role: types.NewNullInt64(tt.role)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := serve(t, tk, tk.DenyGuests, token); got != tt.want {
+			t.Errorf("DenyGuests() role %d status = %d, want %d", tt.role, got, tt.want)
+		}
+	}
+}
+
+func TestToken_DenyImpersonation(t *testing.T) {
+	tk := newTestToken(t)
+	u := light.User{UUID: types.NewNullUUID(), Role: types.NewNullInt64(types.RoleUser)}
+	admin := light.User{UUID: types.NewNullUUID(), Role: types.NewNullInt64(types.RoleAdmin)}
+
+	token, err := tk.jwt.CreateAccessToken(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := serve(t, tk, tk.DenyImpersonation, token); got != http.StatusOK {
+		t.Errorf("DenyImpersonation() of own token status = %d, want %d", got, http.StatusOK)
+	}
+
+	token, err = tk.jwt.CreateImpersonationToken(u, admin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := serve(t, tk, tk.DenyImpersonation, token); got != http.StatusForbidden {
+		t.Errorf("DenyImpersonation() of impersonation token status = %d, want %d", got, http.StatusForbidden)
+	}
+}
+
+func TestToken_ImpersonatedGuest(t *testing.T) {
+	tk := newTestToken(t)
+	guest := light.User{UUID: types.NewNullUUID(), Role: types.NewNullInt64(types.RoleGuest)}
+	admin := light.User{UUID: types.NewNullUUID(), Role: types.NewNullInt64(types.RoleAdmin)}
+
+	token, err := tk.jwt.CreateImpersonationToken(guest, admin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// admin acting as guest is restricted as guest
+	if got := serve(t, tk, tk.DenyGuests, token); got != http.StatusForbidden {
+		t.Errorf("DenyGuests() of impersonated guest status = %d, want %d", got, http.StatusForbidden)
+	}
+}
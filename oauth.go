@@ -0,0 +1,83 @@
+package light
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/ptflp/go-light/request"
+	"github.com/ptflp/go-light/types"
+)
+
+type OAuthClient struct {
+	UUID         types.NullUUID   `json:"uuid" db:"uuid" ops:"create" orm_type:"binary(16)" orm_default:"not null primary key"`
+	ClientID     types.NullString `json:"client_id" db:"client_id" ops:"create" orm_type:"varchar(64)" orm_default:"not null" orm_index:"index,unique"`
+	SecretHash   types.NullString `json:"-" db:"secret_hash" ops:"create" orm_type:"varchar(64)"`
+	Name         types.NullString `json:"name" db:"name" ops:"create,update" orm_type:"varchar(89)"`
+	RedirectURIs types.NullString `json:"redirect_uris" db:"redirect_uris" ops:"create,update" orm_type:"varchar(1024)"`
+	Public       types.NullBool   `json:"public" db:"public" ops:"create" orm_type:"boolean"`
+	UserUUID     types.NullUUID   `json:"user_id" db:"user_uuid" ops:"create" orm_type:"binary(16)" orm_default:"null" orm_index:"index"`
+	CreatedAt    time.Time        `json:"created_at" db:"created_at" orm_type:"timestamp" orm_default:"default (now()) not null" orm_index:"index"`
+	UpdatedAt    time.Time        `json:"updated_at" db:"updated_at" orm_type:"timestamp" orm_default:"default (now()) null on update CURRENT_TIMESTAMP"`
+	DeletedAt    types.NullTime   `json:"deleted_at" db:"deleted_at" orm_type:"timestamp" orm_default:"null" orm_index:"index"`
+}
+
+func (o OAuthClient) OnCreate() string {
+	return ""
+}
+
+func (o OAuthClient) TableName() string {
+	return "oauth_clients"
+}
+
+func (o OAuthClient) HasRedirectURI(uri string) bool {
+	for _, registered := range strings.Fields(o.RedirectURIs.String) {
+		if registered == uri {
+			return true
+		}
+	}
+
+	return false
+}
+
+// OAuthConsent scopes granted by user to client
+type OAuthConsent struct {
+	UUID       types.NullUUID   `json:"uuid" db:"uuid" ops:"create" orm_type:"binary(16)" orm_default:"not null primary key"`
+	UserUUID   types.NullUUID   `json:"user_id" db:"user_uuid" ops:"create" orm_type:"binary(16)" orm_default:"not null" orm_index:"index"`
+	ClientUUID types.NullUUID   `json:"client_uuid" db:"client_uuid" ops:"create" orm_type:"binary(16)" orm_default:"not null" orm_index:"index"`
+	Scope      types.NullString `json:"scope" db:"scope" ops:"create,update" orm_type:"varchar(255)"`
+	CreatedAt  time.Time        `json:"created_at" db:"created_at" orm_type:"timestamp" orm_default:"default (now()) not null"`
+	UpdatedAt  time.Time        `json:"updated_at" db:"updated_at" orm_type:"timestamp" orm_default:"default (now()) null on update CURRENT_TIMESTAMP"`
+}
+
+func (o OAuthConsent) OnCreate() string {
+	return ""
+}
+
+func (o OAuthConsent) TableName() string {
+	return "oauth_consents"
+}
+
+type OAuthRepository interface {
+	CreateClient(ctx context.Context, client OAuthClient) error
+	FindClient(ctx context.Context, client OAuthClient) (OAuthClient, error)
+	ListClients(ctx context.Context) ([]OAuthClient, error)
+
+	FindConsent(ctx context.Context, consent OAuthConsent) (OAuthConsent, error)
+	SaveConsent(ctx context.Context, consent OAuthConsent) error
+}
+
+type OIDCService interface {
+	Discovery() request.DiscoveryData
+	JWKS() (request.JWKSData, error)
+
+	RegisterClient(ctx context.Context, req request.OAuthClientCreateReq) (request.OAuthClientData, error)
+	ListClients(ctx context.Context) ([]request.OAuthClientData, error)
+
+	Authorize(ctx context.Context, req request.AuthorizeRequest) (string, error)
+	ConsentData(ctx context.Context, req request.AuthorizeRequest) (request.ConsentData, error)
+	Consent(ctx context.Context, req request.ConsentRequest) (request.ConsentResultData, error)
+
+	Token(ctx context.Context, req request.TokenRequest) (request.TokenData, error)
+	UserInfo(ctx context.Context, accessToken string) (map[string]interface{}, error)
+}
@@ -0,0 +1,634 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	light "github.com/ptflp/go-light"
+	"github.com/ptflp/go-light/components"
+	"github.com/ptflp/go-light/decoder"
+	"github.com/ptflp/go-light/hasher"
+	"github.com/ptflp/go-light/request"
+	"github.com/ptflp/go-light/types"
+	"go.uber.org/zap"
+)
+
+const (
+	CodeKey    = "oidc:code:%s"
+	RefreshKey = "oidc:refresh:%s"
+
+	ScopeOpenID  = "openid"
+	ScopeProfile = "profile"
+	ScopeEmail   = "email"
+	ScopePhone   = "phone"
+	ScopeOffline = "offline_access"
+
+	GrantAuthorizationCode = "authorization_code"
+	GrantRefreshToken      = "refresh_token"
+
+	ChallengeS256 = "S256"
+)
+
+var supportedScopes = []string{ScopeOpenID, ScopeProfile, ScopeEmail, ScopePhone, ScopeOffline}
+
+// Error oauth2 error response, RFC 6749 section 5.2
+type Error struct {
+	Code        string
+	Description string
+	Status      int
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.Description)
+}
+
+func newError(status int, code, description string) *Error {
+	return &Error{Code: code, Description: description, Status: status}
+}
+
+type authorization struct {
+	ClientID      string `json:"client_id"`
+	RedirectURI   string `json:"redirect_uri"`
+	UserUUID      string `json:"user_uuid"`
+	Scope         string `json:"scope"`
+	Nonce         string `json:"nonce"`
+	CodeChallenge string `json:"code_challenge"`
+}
+
+type refresh struct {
+	ClientID string `json:"client_id"`
+	UserUUID string `json:"user_uuid"`
+	Scope    string `json:"scope"`
+}
+
+type service struct {
+	*decoder.Decoder
+	userRepository  light.UserRepository
+	oauthRepository light.OAuthRepository
+	components.Componenter
+}
+
+func NewOIDCService(repositories light.Repositories, cmps components.Componenter) *service {
+	return &service{Componenter: cmps, userRepository: repositories.Users, oauthRepository: repositories.OAuth, Decoder: decoder.NewDecoder()}
+}
+
+func (s *service) Discovery() request.DiscoveryData {
+	issuer := strings.TrimRight(s.Config().OIDC.Issuer, "/")
+
+	return request.DiscoveryData{
+		Issuer:                            issuer,
+		AuthorizationEndpoint:             issuer + "/oauth/authorize",
+		TokenEndpoint:                     issuer + "/oauth/token",
+		UserinfoEndpoint:                  issuer + "/oauth/userinfo",
+		JwksURI:                           issuer + "/oauth/jwks",
+		ResponseTypesSupported:            []string{"code"},
+		GrantTypesSupported:               []string{GrantAuthorizationCode, GrantRefreshToken},
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  []string{"RS256"},
+		ScopesSupported:                   supportedScopes,
+		ClaimsSupported:                   []string{"sub", "iss", "aud", "exp", "iat", "nonce", "name", "family_name", "nickname", "picture", "updated_at", "email", "email_verified", "phone_number"},
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "none"},
+		CodeChallengeMethodsSupported:     []string{ChallengeS256},
+	}
+}
+
+func (s *service) JWKS() (request.JWKSData, error) {
+	key, err := s.JWTKeys().GetVerifyKey()
+	if err != nil {
+		return request.JWKSData{}, err
+	}
+
+	return request.JWKSData{
+		Keys: []request.JWKData{
+			{
+				Kty: "RSA",
+				Use: "sig",
+				Alg: "RS256",
+				Kid: s.JWTKeys().KeyID(),
+				N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			},
+		},
+	}, nil
+}
+
+func (s *service) RegisterClient(ctx context.Context, req request.OAuthClientCreateReq) (request.OAuthClientData, error) {
+	owner, err := s.admin(ctx)
+	if err != nil {
+		return request.OAuthClientData{}, err
+	}
+	if strings.TrimSpace(req.Name) == "" {
+		return request.OAuthClientData{}, errors.New("client name is required")
+	}
+	if len(req.RedirectURIs) < 1 {
+		return request.OAuthClientData{}, errors.New("at least one redirect uri is required")
+	}
+	for _, uri := range req.RedirectURIs {
+		if !validRedirectURI(uri, s.Config().OIDC.RedirectSchemes) {
+			return request.OAuthClientData{}, fmt.Errorf("invalid redirect uri %s", uri)
+		}
+	}
+
+	clientID, err := randomString(16)
+	if err != nil {
+		return request.OAuthClientData{}, err
+	}
+	client := light.OAuthClient{
+		UUID:         types.NewNullUUID(),
+		ClientID:     types.NewNullString(clientID),
+		Name:         types.NewNullString(req.Name),
+		RedirectURIs: types.NewNullString(strings.Join(req.RedirectURIs, " ")),
+		Public:       types.NewNullBool(req.Public),
+		UserUUID:     owner.UUID,
+	}
+
+	var secret string
+	if !req.Public {
+		secret, err = randomString(32)
+		if err != nil {
+			return request.OAuthClientData{}, err
+		}
+		client.SecretHash = types.NewNullString(hasher.NewSHA256([]byte(secret)))
+	}
+
+	err = s.oauthRepository.CreateClient(ctx, client)
+	if err != nil {
+		return request.OAuthClientData{}, err
+	}
+
+	clientData := clientToData(client)
+	clientData.ClientSecret = secret
+
+	return clientData, nil
+}
+
+// validRedirectURI https uri, http uri on loopback interface or uri of custom scheme allowed by config
+func validRedirectURI(uri string, schemes []string) bool {
+	u, err := url.Parse(uri)
+	if err != nil || !u.IsAbs() || u.Fragment != "" {
+		return false
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "https":
+		return u.Host != ""
+	case "http":
+		// native apps receive code by local server
+		host := u.Hostname()
+		ip := net.ParseIP(host)
+		return host == "localhost" || ip != nil && ip.IsLoopback()
+	}
+	for _, scheme := range schemes {
+		if strings.EqualFold(u.Scheme, scheme) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *service) ListClients(ctx context.Context) ([]request.OAuthClientData, error) {
+	_, err := s.admin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	clients, err := s.oauthRepository.ListClients(ctx)
+	if err != nil {
+		return nil, err
+	}
+	clientsData := make([]request.OAuthClientData, 0, len(clients))
+	for i := range clients {
+		clientsData = append(clientsData, clientToData(clients[i]))
+	}
+
+	return clientsData, nil
+}
+
+// Authorize validates authorization request and returns location of consent screen,
+// errors not related to client or redirect uri are returned to client by redirect
+func (s *service) Authorize(ctx context.Context, req request.AuthorizeRequest) (string, error) {
+	_, _, err := s.validateAuthorize(ctx, req)
+	if err != nil {
+		var oauthErr *Error
+		if errors.As(err, &oauthErr) && oauthErr.Status == http.StatusFound {
+			return errorRedirect(req, oauthErr), nil
+		}
+		return "", err
+	}
+
+	uri, err := url.Parse(s.Config().OIDC.ConsentURL)
+	if err != nil {
+		return "", err
+	}
+	uri.RawQuery = authorizeQuery(req).Encode()
+
+	return uri.String(), nil
+}
+
+func (s *service) ConsentData(ctx context.Context, req request.AuthorizeRequest) (request.ConsentData, error) {
+	client, scopes, err := s.validateAuthorize(ctx, req)
+	if err != nil {
+		return request.ConsentData{}, err
+	}
+	user, err := extractUser(ctx)
+	if err != nil {
+		return request.ConsentData{}, err
+	}
+
+	consent, err := s.oauthRepository.FindConsent(ctx, light.OAuthConsent{UserUUID: user.UUID, ClientUUID: client.UUID})
+
+	return request.ConsentData{
+		ClientID:   client.ClientID.String,
+		ClientName: client.Name.String,
+		Scopes:     scopes,
+		Consented:  err == nil && containsAll(strings.Fields(consent.Scope.String), scopes),
+	}, nil
+}
+
+func (s *service) Consent(ctx context.Context, req request.ConsentRequest) (request.ConsentResultData, error) {
+	client, scopes, err := s.validateAuthorize(ctx, req.AuthorizeRequest)
+	if err != nil {
+		var oauthErr *Error
+		if errors.As(err, &oauthErr) && oauthErr.Status == http.StatusFound {
+			return request.ConsentResultData{RedirectTo: errorRedirect(req.AuthorizeRequest, oauthErr)}, nil
+		}
+		return request.ConsentResultData{}, err
+	}
+	if !req.Approve {
+		return request.ConsentResultData{
+			RedirectTo: errorRedirect(req.AuthorizeRequest, newError(http.StatusFound, "access_denied", "user denied access")),
+		}, nil
+	}
+
+	user, err := extractUser(ctx)
+	if err != nil {
+		return request.ConsentResultData{}, err
+	}
+	// grants to third party clients are not issued on behalf of user
+	if user.Impersonated() {
+		return request.ConsentResultData{}, light.ErrImpersonated
+	}
+	user, err = s.userRepository.Find(ctx, user)
+	if err != nil {
+		return request.ConsentResultData{}, err
+	}
+
+	scope := strings.Join(scopes, " ")
+	err = s.oauthRepository.SaveConsent(ctx, light.OAuthConsent{
+		UUID:       types.NewNullUUID(),
+		UserUUID:   user.UUID,
+		ClientUUID: client.UUID,
+		Scope:      types.NewNullString(scope),
+	})
+	if err != nil {
+		return request.ConsentResultData{}, err
+	}
+
+	code, err := randomString(32)
+	if err != nil {
+		return request.ConsentResultData{}, err
+	}
+	s.Cache().Set(fmt.Sprintf(CodeKey, code), &authorization{
+		ClientID:      client.ClientID.String,
+		RedirectURI:   req.RedirectURI,
+		UserUUID:      user.UUID.String,
+		Scope:         scope,
+		Nonce:         req.Nonce,
+		CodeChallenge: req.CodeChallenge,
+	}, s.Config().OIDC.CodeLifetime())
+
+	uri, err := url.Parse(req.RedirectURI)
+	if err != nil {
+		return request.ConsentResultData{}, err
+	}
+	q := uri.Query()
+	q.Set("code", code)
+	if req.State != "" {
+		q.Set("state", req.State)
+	}
+	uri.RawQuery = q.Encode()
+
+	return request.ConsentResultData{RedirectTo: uri.String()}, nil
+}
+
+func (s *service) Token(ctx context.Context, req request.TokenRequest) (request.TokenData, error) {
+	client, err := s.authenticateClient(ctx, req)
+	if err != nil {
+		return request.TokenData{}, err
+	}
+
+	switch req.GrantType {
+	case GrantAuthorizationCode:
+		var auth authorization
+		key := fmt.Sprintf(CodeKey, req.Code)
+		if err = s.Cache().Get(key, &auth); err != nil {
+			return request.TokenData{}, newError(http.StatusBadRequest, "invalid_grant", "authorization code invalid or expired")
+		}
+		// authorization code is one-time
+		if err = s.Cache().Del(key); err != nil {
+			s.Logger().Error("oidc authorization code deletion", zap.Error(err))
+		}
+		if auth.ClientID != client.ClientID.String || auth.RedirectURI != req.RedirectURI {
+			return request.TokenData{}, newError(http.StatusBadRequest, "invalid_grant", "client or redirect uri mismatch")
+		}
+		if auth.CodeChallenge != "" && !verifyChallenge(auth.CodeChallenge, req.CodeVerifier) {
+			return request.TokenData{}, newError(http.StatusBadRequest, "invalid_grant", "code verifier mismatch")
+		}
+
+		return s.issue(ctx, client, auth.UserUUID, auth.Scope, auth.Nonce)
+	case GrantRefreshToken:
+		var ref refresh
+		key := fmt.Sprintf(RefreshKey, req.RefreshToken)
+		if err = s.Cache().Get(key, &ref); err != nil {
+			return request.TokenData{}, newError(http.StatusBadRequest, "invalid_grant", "refresh token invalid or expired")
+		}
+		if ref.ClientID != client.ClientID.String {
+			return request.TokenData{}, newError(http.StatusBadRequest, "invalid_grant", "client mismatch")
+		}
+		scope := ref.Scope
+		if req.Scope != "" {
+			if !containsAll(strings.Fields(ref.Scope), strings.Fields(req.Scope)) {
+				return request.TokenData{}, newError(http.StatusBadRequest, "invalid_scope", "requested scope exceeds granted scope")
+			}
+			scope = req.Scope
+		}
+		// refresh tokens are rotated on use
+		if err = s.Cache().Del(key); err != nil {
+			s.Logger().Error("oidc refresh token deletion", zap.Error(err))
+		}
+
+		return s.issue(ctx, client, ref.UserUUID, scope, "")
+	default:
+		return request.TokenData{}, newError(http.StatusBadRequest, "unsupported_grant_type", req.GrantType)
+	}
+}
+
+func (s *service) UserInfo(ctx context.Context, accessToken string) (map[string]interface{}, error) {
+	c, err := s.JWTKeys().ParseToken(accessToken)
+	if err != nil {
+		return nil, newError(http.StatusUnauthorized, "invalid_token", err.Error())
+	}
+	scope, _ := c["scope"].(string)
+	sub, _ := c["sub"].(string)
+	if _, ok := c["aud"]; !ok || !containsAll(strings.Fields(scope), []string{ScopeOpenID}) {
+		return nil, newError(http.StatusUnauthorized, "invalid_token", "token not issued for userinfo")
+	}
+
+	user, err := s.userRepository.Find(ctx, light.User{UUID: types.NewNullUUID(sub)})
+	if err != nil || user.DeletedAt.Valid {
+		return nil, newError(http.StatusUnauthorized, "invalid_token", "user not found")
+	}
+
+	return claims(user, strings.Fields(scope)), nil
+}
+
+func (s *service) issue(ctx context.Context, client light.OAuthClient, userUUID, scope, nonce string) (request.TokenData, error) {
+	user, err := s.userRepository.Find(ctx, light.User{UUID: types.NewNullUUID(userUUID)})
+	if err != nil || user.DeletedAt.Valid {
+		return request.TokenData{}, newError(http.StatusBadRequest, "invalid_grant", "user not found")
+	}
+
+	cfg := s.Config().OIDC
+	issuer := strings.TrimRight(cfg.Issuer, "/")
+	now := time.Now().UTC()
+	exp := now.Add(cfg.AccessTokenLifetime())
+
+	accessToken, err := s.JWTKeys().GenerateToken(jwt.MapClaims{
+		"iss":       issuer,
+		"sub":       user.UUID.String,
+		"aud":       client.ClientID.String,
+		"client_id": client.ClientID.String,
+		"scope":     scope,
+		"iat":       now.Unix(),
+		"exp":       exp.Unix(),
+	})
+	if err != nil {
+		return request.TokenData{}, err
+	}
+
+	tokenData := request.TokenData{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(cfg.AccessTokenLifetime().Seconds()),
+		Scope:       scope,
+	}
+
+	scopes := strings.Fields(scope)
+	// client acts on behalf of user after access token expires only with offline access granted
+	if containsAll(scopes, []string{ScopeOffline}) {
+		tokenData.RefreshToken, err = randomString(32)
+		if err != nil {
+			return request.TokenData{}, err
+		}
+		s.Cache().Set(fmt.Sprintf(RefreshKey, tokenData.RefreshToken), &refresh{
+			ClientID: client.ClientID.String,
+			UserUUID: user.UUID.String,
+			Scope:    scope,
+		}, cfg.RefreshTokenLifetime())
+	}
+	if containsAll(scopes, []string{ScopeOpenID}) {
+		idClaims := jwt.MapClaims{}
+		for k, v := range claims(user, scopes) {
+			idClaims[k] = v
+		}
+		idClaims["iss"] = issuer
+		idClaims["aud"] = client.ClientID.String
+		idClaims["iat"] = now.Unix()
+		idClaims["exp"] = exp.Unix()
+		if nonce != "" {
+			idClaims["nonce"] = nonce
+		}
+		tokenData.IDToken, err = s.JWTKeys().GenerateToken(idClaims)
+		if err != nil {
+			return request.TokenData{}, err
+		}
+	}
+
+	return tokenData, nil
+}
+
+func (s *service) validateAuthorize(ctx context.Context, req request.AuthorizeRequest) (light.OAuthClient, []string, error) {
+	client, err := s.oauthRepository.FindClient(ctx, light.OAuthClient{ClientID: types.NewNullString(req.ClientID)})
+	if err != nil {
+		return light.OAuthClient{}, nil, newError(http.StatusBadRequest, "invalid_client", "unknown client")
+	}
+	if !client.HasRedirectURI(req.RedirectURI) {
+		return light.OAuthClient{}, nil, newError(http.StatusBadRequest, "invalid_request", "redirect uri not registered")
+	}
+	if req.ResponseType != "code" {
+		return light.OAuthClient{}, nil, newError(http.StatusFound, "unsupported_response_type", "only code response type supported")
+	}
+
+	scopes := strings.Fields(req.Scope)
+	if !containsAll(supportedScopes, scopes) {
+		return light.OAuthClient{}, nil, newError(http.StatusFound, "invalid_scope", "unsupported scope requested")
+	}
+
+	if req.CodeChallenge != "" && req.CodeChallengeMethod != ChallengeS256 {
+		return light.OAuthClient{}, nil, newError(http.StatusFound, "invalid_request", "only S256 code challenge method supported")
+	}
+	if client.Public.Bool && req.CodeChallenge == "" {
+		return light.OAuthClient{}, nil, newError(http.StatusFound, "invalid_request", "code challenge required for public client")
+	}
+
+	return client, scopes, nil
+}
+
+func (s *service) authenticateClient(ctx context.Context, req request.TokenRequest) (light.OAuthClient, error) {
+	client, err := s.oauthRepository.FindClient(ctx, light.OAuthClient{ClientID: types.NewNullString(req.ClientID)})
+	if err != nil {
+		return light.OAuthClient{}, newError(http.StatusUnauthorized, "invalid_client", "unknown client")
+	}
+	if client.Public.Bool {
+		if req.GrantType == GrantAuthorizationCode && req.CodeVerifier == "" {
+			return light.OAuthClient{}, newError(http.StatusBadRequest, "invalid_request", "code verifier required for public client")
+		}
+		return client, nil
+	}
+
+	secretHash := hasher.NewSHA256([]byte(req.ClientSecret))
+	if req.ClientSecret == "" || subtle.ConstantTimeCompare([]byte(secretHash), []byte(client.SecretHash.String)) != 1 {
+		return light.OAuthClient{}, newError(http.StatusUnauthorized, "invalid_client", "client authentication failed")
+	}
+
+	return client, nil
+}
+
+func (s *service) admin(ctx context.Context) (light.User, error) {
+	user, err := extractUser(ctx)
+	if err != nil {
+		return light.User{}, err
+	}
+	user, err = s.userRepository.Find(ctx, user)
+	if err != nil {
+		return light.User{}, err
+	}
+	if !user.IsAdmin() {
+		return light.User{}, errors.New("admin role required")
+	}
+
+	return user, nil
+}
+
+func claims(user light.User, scopes []string) map[string]interface{} {
+	c := map[string]interface{}{
+		"sub": user.UUID.String,
+	}
+	for _, scope := range scopes {
+		switch scope {
+		case ScopeProfile:
+			c["name"] = user.Name.String
+			c["family_name"] = user.SecondName.String
+			c["nickname"] = user.NickName.String
+			c["picture"] = user.Avatar.String
+			c["updated_at"] = user.UpdatedAt.Unix()
+		case ScopeEmail:
+			c["email"] = user.Email.String
+			c["email_verified"] = user.EmailVerified.Bool
+		case ScopePhone:
+			c["phone_number"] = user.Phone.String
+		}
+	}
+
+	return c
+}
+
+func clientToData(client light.OAuthClient) request.OAuthClientData {
+	return request.OAuthClientData{
+		ClientID:     client.ClientID,
+		Name:         client.Name,
+		RedirectURIs: strings.Fields(client.RedirectURIs.String),
+		Public:       client.Public.Bool,
+	}
+}
+
+func authorizeQuery(req request.AuthorizeRequest) url.Values {
+	q := url.Values{}
+	q.Set("response_type", req.ResponseType)
+	q.Set("client_id", req.ClientID)
+	q.Set("redirect_uri", req.RedirectURI)
+	q.Set("scope", req.Scope)
+	if req.State != "" {
+		q.Set("state", req.State)
+	}
+	if req.Nonce != "" {
+		q.Set("nonce", req.Nonce)
+	}
+	if req.CodeChallenge != "" {
+		q.Set("code_challenge", req.CodeChallenge)
+		q.Set("code_challenge_method", req.CodeChallengeMethod)
+	}
+
+	return q
+}
+
+func errorRedirect(req request.AuthorizeRequest, oauthErr *Error) string {
+	uri, err := url.Parse(req.RedirectURI)
+	if err != nil {
+		return ""
+	}
+	q := uri.Query()
+	q.Set("error", oauthErr.Code)
+	q.Set("error_description", oauthErr.Description)
+	if req.State != "" {
+		q.Set("state", req.State)
+	}
+	uri.RawQuery = q.Encode()
+
+	return uri.String()
+}
+
+func verifyChallenge(challenge, verifier string) bool {
+	sum := sha256.Sum256([]byte(verifier))
+	expected := base64.RawURLEncoding.EncodeToString(sum[:])
+
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(challenge)) == 1
+}
+
+func containsAll(set, values []string) bool {
+	for _, v := range values {
+		found := false
+		for _, s := range set {
+			if s == v {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+func randomString(n int) (string, error) {
+	b := make([]byte, n)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+func extractUser(ctx context.Context) (light.User, error) {
+	u, ok := ctx.Value(types.User{}).(*light.User)
+	if !ok {
+		return light.User{}, errors.New("type assertion to user err")
+	}
+
+	return *u, nil
+}
@@ -0,0 +1,194 @@
+package oidc
+
+import (
+	"context"
+	"database/sql"
+	"net/url"
+	"testing"
+
+	light "github.com/ptflp/go-light"
+	"github.com/ptflp/go-light/components/componentstest"
+	"github.com/ptflp/go-light/hasher"
+	"github.com/ptflp/go-light/request"
+	"github.com/ptflp/go-light/types"
+)
+
+const (
+	testClientID     = "client"
+	testClientSecret = "secret"
+	testRedirectURI  = "https://client.example.com/callback"
+)
+
+type testOAuthRepository struct {
+	light.OAuthRepository
+	client   light.OAuthClient
+	created  []light.OAuthClient
+	consents []light.OAuthConsent
+}
+
+func (r *testOAuthRepository) FindClient(ctx context.Context, client light.OAuthClient) (light.OAuthClient, error) {
+	if client.ClientID.String != r.client.ClientID.String {
+		return light.OAuthClient{}, sql.ErrNoRows
+	}
+
+	return r.client, nil
+}
+
+func (r *testOAuthRepository) CreateClient(ctx context.Context, client light.OAuthClient) error {
+	r.created = append(r.created, client)
+
+	return nil
+}
+
+func (r *testOAuthRepository) SaveConsent(ctx context.Context, consent light.OAuthConsent) error {
+	r.consents = append(r.consents, consent)
+
+	return nil
+}
+
+func newTestService(t *testing.T) (*service, *testOAuthRepository, light.User) {
+	t.Helper()
+	user := light.User{UUID: types.NewNullUUID(), Email: types.NewNullString("user@example.com")}
+	oauth := &testOAuthRepository{client: light.OAuthClient{
+		UUID:         types.NewNullUUID(),
+		ClientID:     types.NewNullString(testClientID),
+		SecretHash:   types.NewNullString(hasher.NewSHA256([]byte(testClientSecret))),
+		RedirectURIs: types.NewNullString(testRedirectURI),
+	}}
+	users := &componentstest.Users{}
+	users.Add(user)
+
+	return &service{Componenter: componentstest.New(t, nil), userRepository: users, oauthRepository: oauth}, oauth, user
+}
+
+func TestRegisterClient_RedirectURIs(t *testing.T) {
+	s, oauth, _ := newTestService(t)
+	s.Config().OIDC.RedirectSchemes = []string{"com.example.app"}
+	admin := light.User{UUID: types.NewNullUUID(), Role: types.NewNullInt64(types.RoleAdmin)}
+	s.userRepository.(*componentstest.Users).Add(admin)
+	ctx := context.WithValue(context.Background(), types.User{}, &admin)
+
+	tests := []struct {
+		uri  string
+		want bool
+	}{
+		{uri: "https://client.example.com/callback", want: true},
+		{uri: "http://127.0.0.1:8080/callback", want: true},
+		{uri: "http://[::1]/callback", want: true},
+		{uri: "http://localhost:3000/callback", want: true},
+		{uri: "com.example.app:/callback", want: true},
+		{uri: "http://client.example.com/callback"},
+		{uri: "https:///callback"},
+		{uri: "https://client.example.com/callback#token"},
+		{uri: "javascript:alert(1)"},
+		{uri: "org.example.other:/callback"},
+		{uri: "/callback"},
+	}
+	for _, tt := range tests {
+		_, err := s.RegisterClient(ctx, request.OAuthClientCreateReq{Name: "client", RedirectURIs: []string{tt.uri}})
+		if got := err == nil; got != tt.want {
+			t.Errorf("RegisterClient(%q) error = %v, want accepted %t", tt.uri, err, tt.want)
+		}
+	}
+	if len(oauth.created) != 5 {
+		t.Errorf("clients created = %d, want 5", len(oauth.created))
+	}
+}
+
+func consentRequest(scope string) request.ConsentRequest {
+	return request.ConsentRequest{
+		AuthorizeRequest: request.AuthorizeRequest{
+			ResponseType: "code",
+			ClientID:     testClientID,
+			RedirectURI:  testRedirectURI,
+			Scope:        scope,
+			State:        "state",
+		},
+		Approve: true,
+	}
+}
+
+func TestConsent_Impersonated(t *testing.T) {
+	s, oauth, user := newTestService(t)
+	impersonated := user
+	impersonated.Actor = types.NewNullUUID()
+
+	ctx := context.WithValue(context.Background(), types.User{}, &impersonated)
+	if _, err := s.Consent(ctx, consentRequest("openid")); err == nil {
+		t.Fatal("Consent() of impersonated user error is nil")
+	}
+	if len(oauth.consents) != 0 {
+		t.Fatalf("consent saved for impersonated user: %+v", oauth.consents)
+	}
+
+	ctx = context.WithValue(context.Background(), types.User{}, &user)
+	res, err := s.Consent(ctx, consentRequest("openid"))
+	if err != nil {
+		t.Fatalf("Consent() error = %v", err)
+	}
+	uri, err := url.Parse(res.RedirectTo)
+	if err != nil || uri.Query().Get("code") == "" || uri.Query().Get("state") != "state" {
+		t.Errorf("Consent() redirect = %s, %v", res.RedirectTo, err)
+	}
+	if len(oauth.consents) != 1 || oauth.consents[0].UserUUID.String != user.UUID.String {
+		t.Errorf("consents = %+v", oauth.consents)
+	}
+}
+
+func TestToken_OfflineAccess(t *testing.T) {
+	s, _, user := newTestService(t)
+	ctx := context.WithValue(context.Background(), types.User{}, &user)
+	exchange := func(scope string) request.TokenData {
+		t.Helper()
+		res, err := s.Consent(ctx, consentRequest(scope))
+		if err != nil {
+			t.Fatal(err)
+		}
+		uri, err := url.Parse(res.RedirectTo)
+		if err != nil {
+			t.Fatal(err)
+		}
+		token, err := s.Token(context.Background(), request.TokenRequest{
+			GrantType:    GrantAuthorizationCode,
+			Code:         uri.Query().Get("code"),
+			RedirectURI:  testRedirectURI,
+			ClientID:     testClientID,
+			ClientSecret: testClientSecret,
+		})
+		if err != nil {
+			t.Fatalf("Token(%q) error = %v", scope, err)
+		}
+
+		return token
+	}
+
+	if token := exchange("openid email"); token.RefreshToken != "" || token.IDToken == "" {
+		t.Errorf("Token() without offline_access = %+v", token)
+	}
+
+	token := exchange("openid offline_access")
+	if token.RefreshToken == "" {
+		t.Fatal("Token() with offline_access has no refresh token")
+	}
+	refreshReq := request.TokenRequest{
+		GrantType:    GrantRefreshToken,
+		RefreshToken: token.RefreshToken,
+		ClientID:     testClientID,
+		ClientSecret: testClientSecret,
+	}
+	refreshed, err := s.Token(context.Background(), refreshReq)
+	if err != nil || refreshed.RefreshToken == "" || refreshed.RefreshToken == token.RefreshToken {
+		t.Fatalf("Token() refresh = %+v, %v", refreshed, err)
+	}
+	// refresh token is rotated on use
+	if _, err = s.Token(context.Background(), refreshReq); err == nil {
+		t.Error("Token() with used refresh token error is nil")
+	}
+	// narrowed scope without offline_access ends refresh chain
+	refreshReq.RefreshToken = refreshed.RefreshToken
+	refreshReq.Scope = "openid"
+	narrowed, err := s.Token(context.Background(), refreshReq)
+	if err != nil || narrowed.RefreshToken != "" {
+		t.Errorf("Token() refresh with narrowed scope = %+v, %v", narrowed, err)
+	}
+}
@@ -0,0 +1,55 @@
+package light
+
+import (
+	"context"
+	"time"
+
+	"github.com/ptflp/go-light/types"
+)
+
+// OutboxMessage serialized email waiting for delivery by outbox worker
+type OutboxMessage struct {
+	UUID           types.NullUUID   `json:"message_id" db:"uuid" ops:"create" orm_type:"binary(16)" orm_default:"not null primary key"`
+	IdempotencyKey types.NullString `json:"idempotency_key" db:"idempotency_key" ops:"create" orm_type:"varchar(191)" orm_default:"null" orm_index:"index,unique"`
+	Sender         types.NullString `json:"sender" db:"sender" ops:"create" orm_type:"varchar(255)" orm_default:"not null"`
+	Recipients     types.NullString `json:"recipients" db:"recipients" ops:"create" orm_type:"text" orm_default:"not null"`
+	Subject        types.NullString `json:"subject" db:"subject" ops:"create" orm_type:"varchar(255)" orm_default:"null"`
+	// Data raw message, cleared when message is sent
+	Data          types.NullString `json:"-" db:"data" ops:"create,update" orm_type:"mediumtext" orm_default:"not null"`
+	Status        types.NullInt64  `json:"status" db:"status" ops:"create,update" orm_type:"int" orm_default:"not null" orm_index:"index"`
+	Attempts      types.NullInt64  `json:"attempts" db:"attempts" ops:"update" orm_type:"int" orm_default:"default 0 not null"`
+	NextAttemptAt types.NullTime   `json:"next_attempt_at" db:"next_attempt_at" ops:"create,update" orm_type:"timestamp" orm_default:"null" orm_index:"index"`
+	LastError     types.NullString `json:"last_error" db:"last_error" ops:"update" orm_type:"varchar(512)" orm_default:"null"`
+	SentAt        types.NullTime   `json:"sent_at" db:"sent_at" ops:"update" orm_type:"timestamp" orm_default:"null"`
+	CreatedAt     time.Time        `json:"created_at" db:"created_at" orm_type:"timestamp" orm_default:"default (now()) not null" orm_index:"index"`
+	UpdatedAt     time.Time        `json:"updated_at" db:"updated_at" orm_type:"timestamp" orm_default:"default (now()) null on update CURRENT_TIMESTAMP"`
+}
+
+func (o OutboxMessage) OnCreate() string {
+	return ""
+}
+
+func (o OutboxMessage) TableName() string {
+	return "email_outbox"
+}
+
+// OutboxStats queue depth and failures for monitoring
+type OutboxStats struct {
+	Pending       int64          `json:"pending"`
+	Retrying      int64          `json:"retrying"`
+	Sending       int64          `json:"sending"`
+	Dead          int64          `json:"dead"`
+	OldestPending types.NullTime `json:"oldest_pending"`
+}
+
+type OutboxRepository interface {
+	// Create stores message, false when message with same idempotency key exists
+	Create(ctx context.Context, msg OutboxMessage) (bool, error)
+	// Claim locks due messages for delivery until lease expires, messages of crashed workers are claimed again
+	Claim(ctx context.Context, limit uint64, lease time.Duration) ([]OutboxMessage, error)
+	Update(ctx context.Context, msg OutboxMessage) error
+	Stats(ctx context.Context) (OutboxStats, error)
+	ListDead(ctx context.Context, limit uint64) ([]OutboxMessage, error)
+	// Purge removes sent and dead messages last updated before time
+	Purge(ctx context.Context, before time.Time) (int64, error)
+}
@@ -0,0 +1,270 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nyaruka/phonenumbers"
+	"github.com/ptflp/go-light/config"
+	"go.uber.org/zap"
+)
+
+var ErrNoSMSProvider = errors.New("no sms provider for phone")
+
+// SMSGateway sends message with first healthy provider of route, falls back to next one on failure.
+// Provider is skipped for cooldown period after threshold of consecutive failures, then single probe is let through.
+type SMSGateway struct {
+	members   []*smsMember
+	routes    []config.SMSRoute
+	threshold int
+	cooldown  time.Duration
+	logger    *zap.Logger
+	tracker   *SMSTracker
+}
+
+type smsMember struct {
+	SMSProvider
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	probing   bool
+	sent      int64
+	failed    int64
+	cost      float64
+	lastError string
+}
+
+// SMSProviderStats health and cost of provider since start, provider is unhealthy until probe after cooldown succeeds
+type SMSProviderStats struct {
+	Name      string
+	Healthy   bool
+	Sent      int64
+	Failed    int64
+	Cost      float64
+	LastError string
+}
+
+func NewSMSGateway(cfg *config.SMS, logger *zap.Logger, providers ...SMSProvider) (*SMSGateway, error) {
+	byName := make(map[string]SMSProvider)
+	for _, provider := range providers {
+		byName[provider.Name()] = provider
+	}
+
+	g := &SMSGateway{
+		routes:    cfg.Routes,
+		threshold: cfg.Threshold(),
+		cooldown:  cfg.CooldownPeriod(),
+		logger:    logger,
+	}
+	for _, name := range cfg.ProviderOrder() {
+		provider, ok := byName[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown sms provider %q", name)
+		}
+		g.members = append(g.members, &smsMember{SMSProvider: provider})
+	}
+	for _, route := range cfg.Routes {
+		for _, name := range route.Providers {
+			if g.member(name) == nil {
+				return nil, fmt.Errorf("sms route provider %q is not in providers list", name)
+			}
+		}
+	}
+
+	return g, nil
+}
+
+func (g *SMSGateway) Send(ctx context.Context, phone, msg string) error {
+	candidates := g.route(phone)
+	if len(candidates) == 0 {
+		return ErrNoSMSProvider
+	}
+
+	var errs []string
+	var tried bool
+	for _, m := range candidates {
+		if ctx.Err() != nil {
+			break
+		}
+		// availability is checked right before delivery so probe isn't taken by provider that is not tried
+		if !m.available(time.Now()) {
+			continue
+		}
+		tried = true
+		if done, err := g.deliver(ctx, m, phone, msg, &errs); done {
+			return err
+		}
+	}
+	if !tried && ctx.Err() == nil {
+		// trying unhealthy providers is better than not sending at all
+		g.logger.Warn("all sms providers are unhealthy", zap.String("phone", phone))
+		for _, m := range candidates {
+			if ctx.Err() != nil {
+				break
+			}
+			if done, err := g.deliver(ctx, m, phone, msg, &errs); done {
+				return err
+			}
+		}
+	}
+	if ctx.Err() != nil {
+		errs = append(errs, ctx.Err().Error())
+	}
+
+	return fmt.Errorf("sms not sent: %s", strings.Join(errs, "; "))
+}
+
+// deliver returns true when sending is finished: message is sent or phone is rejected
+func (g *SMSGateway) deliver(ctx context.Context, m *smsMember, phone, msg string, errs *[]string) (bool, error) {
+	result, err := m.Deliver(ctx, phone, msg)
+	if err == nil {
+		m.success(result)
+		g.logger.Info("sms sent", zap.String("provider", m.Name()), zap.String("id", result.ID), zap.Float64("cost", result.Cost))
+		if g.tracker != nil {
+			g.tracker.record(m.Name(), phone, result)
+		}
+		return true, nil
+	}
+	// other providers would reject number too, provider is healthy
+	if errors.Is(err, ErrSMSInvalidPhone) {
+		m.release()
+		return true, err
+	}
+	if m.failure(err, g.threshold, g.cooldown) {
+		g.logger.Warn("sms provider disabled", zap.String("provider", m.Name()), zap.Duration("cooldown", g.cooldown))
+	}
+	g.logger.Warn("sms provider failed", zap.String("provider", m.Name()), zap.String("phone", phone), zap.Error(err))
+	*errs = append(*errs, fmt.Sprintf("%s: %s", m.Name(), err))
+
+	return false, nil
+}
+
+func (g *SMSGateway) Stats() []SMSProviderStats {
+	stats := make([]SMSProviderStats, 0, len(g.members))
+	for _, m := range g.members {
+		m.mu.Lock()
+		stats = append(stats, SMSProviderStats{
+			Name:      m.Name(),
+			Healthy:   m.openUntil.IsZero(),
+			Sent:      m.sent,
+			Failed:    m.failed,
+			Cost:      m.cost,
+			LastError: m.lastError,
+		})
+		m.mu.Unlock()
+	}
+
+	return stats
+}
+
+// Balance account balance of provider
+func (g *SMSGateway) Balance(ctx context.Context, name string) (SMSBalance, bool, error) {
+	m := g.member(name)
+	if m == nil {
+		return SMSBalance{}, false, fmt.Errorf("unknown sms provider %q", name)
+	}
+	balancer, ok := m.SMSProvider.(SMSBalancer)
+	if !ok {
+		return SMSBalance{}, false, nil
+	}
+	balance, err := balancer.Balance(ctx)
+
+	return balance, true, err
+}
+
+// route providers of first route matching country of phone, all providers otherwise
+func (g *SMSGateway) route(phone string) []*smsMember {
+	region := phoneRegion(phone)
+	for _, route := range g.routes {
+		for _, country := range route.Countries {
+			if !strings.EqualFold(country, region) {
+				continue
+			}
+			members := make([]*smsMember, 0, len(route.Providers))
+			for _, name := range route.Providers {
+				members = append(members, g.member(name))
+			}
+			return members
+		}
+	}
+
+	return g.members
+}
+
+func (g *SMSGateway) member(name string) *smsMember {
+	for _, m := range g.members {
+		if m.Name() == name {
+			return m
+		}
+	}
+
+	return nil
+}
+
+// phoneRegion region code of phone in international format without "+"
+func phoneRegion(phone string) string {
+	num, err := phonenumbers.Parse("+"+strings.TrimPrefix(phone, "+"), "")
+	if err != nil {
+		return ""
+	}
+
+	return phonenumbers.GetRegionCodeForNumber(num)
+}
+
+// available closed circuit, or single probe after cooldown
+func (m *smsMember) available(now time.Time) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.openUntil.IsZero() {
+		return true
+	}
+	if now.Before(m.openUntil) || m.probing {
+		return false
+	}
+	m.probing = true
+
+	return true
+}
+
+func (m *smsMember) success(result SMSResult) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.failures = 0
+	m.openUntil = time.Time{}
+	m.probing = false
+	m.sent++
+	m.cost += result.Cost
+}
+
+// release ends probe without changing circuit state
+func (m *smsMember) release() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.probing = false
+}
+
+// failure returns true when circuit is opened
+func (m *smsMember) failure(err error, threshold int, cooldown time.Duration) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.failures++
+	m.failed++
+	m.lastError = err.Error()
+	wasProbing := m.probing
+	m.probing = false
+	if m.failures < threshold && !wasProbing {
+		return false
+	}
+	m.openUntil = time.Now().Add(cooldown)
+
+	return true
+}
@@ -0,0 +1,274 @@
+package providers
+
+import (
+	"context"
+	"crypto/sha1"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	light "github.com/ptflp/go-light"
+	"github.com/ptflp/go-light/config"
+	"github.com/ptflp/go-light/types"
+	"go.uber.org/zap"
+)
+
+type testSMSProvider struct {
+	name  string
+	err   error
+	cost  float64
+	calls []string
+}
+
+func (p *testSMSProvider) Name() string {
+	return p.name
+}
+
+func (p *testSMSProvider) Deliver(ctx context.Context, phone, msg string) (SMSResult, error) {
+	p.calls = append(p.calls, phone)
+	if p.err != nil {
+		return SMSResult{}, p.err
+	}
+
+	return SMSResult{ID: "1", Cost: p.cost}, nil
+}
+
+func TestSMSGateway_Failover(t *testing.T) {
+	primary := &testSMSProvider{name: "primary", err: errors.New("unavailable"), cost: 1}
+	backup := &testSMSProvider{name: "backup", cost: 2.5}
+	cfg := &config.SMS{Providers: []string{"primary", "backup"}, FailureThreshold: 2, Cooldown: 1}
+	g, err := NewSMSGateway(cfg, zap.NewNop(), primary, backup)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if err = g.Send(context.Background(), "79644288083", "code"); err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+	}
+	// circuit is opened after two failures
+	if len(primary.calls) != 2 || len(backup.calls) != 3 {
+		t.Fatalf("calls primary = %d, backup = %d", len(primary.calls), len(backup.calls))
+	}
+	stats := g.Stats()
+	if stats[0].Healthy || stats[0].Failed != 2 || stats[1].Sent != 3 || stats[1].Cost != 7.5 {
+		t.Errorf("stats = %+v", stats)
+	}
+
+	// probe after cooldown closes circuit
+	g.members[0].openUntil = time.Now().Add(-time.Second)
+	primary.err = nil
+	if err = g.Send(context.Background(), "79644288083", "code"); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if len(primary.calls) != 3 || !g.Stats()[0].Healthy {
+		t.Errorf("probe is not sent to recovered provider, stats = %+v", g.Stats())
+	}
+
+	backup.err = errors.New("rejected")
+	primary.err = errors.New("unavailable")
+	if err = g.Send(context.Background(), "79644288083", "code"); err == nil {
+		t.Error("Send() error is nil when every provider failed")
+	}
+}
+
+func TestSMSGateway_ProbeOfSecondProvider(t *testing.T) {
+	primary := &testSMSProvider{name: "primary"}
+	broken := &testSMSProvider{name: "broken", err: errors.New("unavailable")}
+	cfg := &config.SMS{Providers: []string{"primary", "broken"}, FailureThreshold: 1, Cooldown: 1}
+	g, err := NewSMSGateway(cfg, zap.NewNop(), primary, broken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// circuit of broken provider is open and cooldown is over
+	g.members[1].openUntil = time.Now().Add(-time.Second)
+
+	if err = g.Send(context.Background(), "79644288083", "code"); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	primary.err = ErrSMSInvalidPhone
+	if err = g.Send(context.Background(), "79644288083", "code"); !errors.Is(err, ErrSMSInvalidPhone) {
+		t.Fatalf("Send() error = %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err = g.Send(ctx, "79644288083", "code"); err == nil {
+		t.Fatal("Send() error is nil with canceled context")
+	}
+	// probe is not taken by provider that wasn't tried
+	if g.members[1].probing || len(broken.calls) != 0 {
+		t.Fatalf("probing = %v, calls = %d", g.members[1].probing, len(broken.calls))
+	}
+
+	primary.err = errors.New("unavailable")
+	broken.err = nil
+	if err = g.Send(context.Background(), "79644288083", "code"); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if len(broken.calls) != 1 || !g.Stats()[1].Healthy {
+		t.Errorf("probe is not sent to second provider, stats = %+v", g.Stats())
+	}
+}
+
+func TestSMSGateway_Routes(t *testing.T) {
+	local := &testSMSProvider{name: "local"}
+	global := &testSMSProvider{name: "global"}
+	cfg := &config.SMS{
+		Providers: []string{"global", "local"},
+		Routes:    []config.SMSRoute{{Countries: []string{"ru", "KZ"}, Providers: []string{"local"}}},
+	}
+	g, err := NewSMSGateway(cfg, zap.NewNop(), local, global)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, phone := range []string{"79644288083", "77012345678", "4915112345678"} {
+		if err = g.Send(context.Background(), phone, "code"); err != nil {
+			t.Fatalf("Send(%s) error = %v", phone, err)
+		}
+	}
+	if len(local.calls) != 2 || len(global.calls) != 1 || global.calls[0] != "4915112345678" {
+		t.Errorf("calls local = %v, global = %v", local.calls, global.calls)
+	}
+
+	cfg.Routes[0].Providers = []string{"unknown"}
+	if _, err = NewSMSGateway(cfg, zap.NewNop(), local, global); err == nil {
+		t.Error("route with unknown provider is accepted")
+	}
+}
+
+func TestHTTPSMS_Deliver(t *testing.T) {
+	var body, auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		body, auth = string(data), r.Header.Get("Authorization")
+		if r.URL.Query().Get("to") != "79644288083" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write([]byte(`{"message_id": 42}`))
+	}))
+	defer server.Close()
+
+	h := NewHTTPSMS(&config.SMSHTTP{
+		Name:        "http",
+		URL:         server.URL + "/send?to={phone}",
+		Method:      "post",
+		Body:        `{"text": "{message}"}`,
+		ContentType: "application/json",
+		Headers:     map[string]string{"Authorization": "Bearer token"},
+		IDField:     "message_id",
+		Cost:        0.5,
+	})
+	result, err := h.Deliver(context.Background(), "79644288083", `Код: "1234"`)
+	if err != nil {
+		t.Fatalf("Deliver() error = %v", err)
+	}
+	if result.ID != "42" || result.Cost != 0.5 {
+		t.Errorf("Deliver() = %+v", result)
+	}
+	if body != `{"text": "Код: \"1234\""}` || auth != "Bearer token" {
+		t.Errorf("body = %s, authorization = %s", body, auth)
+	}
+
+	if _, err = h.Deliver(context.Background(), "+1", "code"); err == nil {
+		t.Error("Deliver() error is nil on http error status")
+	}
+}
+
+type testSMSRepository struct {
+	messages []light.SMSMessage
+}
+
+func (r *testSMSRepository) Create(ctx context.Context, msg light.SMSMessage) error {
+	msg.CreatedAt = time.Now()
+	r.messages = append(r.messages, msg)
+
+	return nil
+}
+
+func (r *testSMSRepository) Update(ctx context.Context, msg light.SMSMessage) error {
+	for i := range r.messages {
+		if r.messages[i].UUID.String == msg.UUID.String {
+			r.messages[i] = msg
+		}
+	}
+
+	return nil
+}
+
+func (r *testSMSRepository) FindByProviderID(ctx context.Context, provider, providerID string) (light.SMSMessage, error) {
+	for i := range r.messages {
+		if r.messages[i].Provider.String == provider && r.messages[i].ProviderID.String == providerID {
+			return r.messages[i], nil
+		}
+	}
+
+	return light.SMSMessage{}, sql.ErrNoRows
+}
+
+func (r *testSMSRepository) ListSent(ctx context.Context, limit uint64) ([]light.SMSMessage, error) {
+	var messages []light.SMSMessage
+	for i := range r.messages {
+		if r.messages[i].Status.Int64 == types.SMSSent {
+			messages = append(messages, r.messages[i])
+		}
+	}
+
+	return messages, nil
+}
+
+func TestSMSTracker(t *testing.T) {
+	server := newTestSMSCServer(t)
+	defer server.Close()
+	cfg := &config.SMS{}
+	g, err := NewSMSGateway(cfg, zap.NewNop(), NewSMSC(testSMSCConfig(server)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := &testSMSRepository{}
+	tracker := NewSMSTracker(g, repo, cfg, zap.NewNop())
+
+	for _, phone := range []string{"79644288083", "79644288084"} {
+		if err = g.Send(context.Background(), phone, "code"); err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+	}
+	if len(repo.messages) != 2 || repo.messages[0].ProviderID.String != "100" || repo.messages[0].Cost.Float64 != 2.5 {
+		t.Fatalf("messages = %+v", repo.messages)
+	}
+	if err = g.Send(context.Background(), "70000000000", "code"); !errors.Is(err, ErrSMSInvalidPhone) {
+		t.Fatalf("Send() error = %v, want %v", err, ErrSMSInvalidPhone)
+	}
+	if g.Stats()[0].Failed != 0 {
+		t.Error("invalid phone is counted as provider failure")
+	}
+
+	server.status["100"] = 1
+	tracker.poll(context.Background())
+	if repo.messages[0].Status.Int64 != types.SMSDelivered || !repo.messages[0].DeliveredAt.Valid {
+		t.Errorf("polled message = %+v", repo.messages[0])
+	}
+	if repo.messages[1].Status.Int64 != types.SMSSent || !repo.messages[1].CheckedAt.Valid {
+		t.Errorf("pending message = %+v", repo.messages[1])
+	}
+
+	sum := sha1.Sum([]byte("101:79644288084:20:parol"))
+	values := url.Values{"id": {"101"}, "phone": {"79644288084"}, "status": {"20"}, "err": {"1"}, "sha1": {hex.EncodeToString(sum[:])}}
+	if err = tracker.Callback(context.Background(), config.SMSProviderSMSC, values); err != nil {
+		t.Fatalf("Callback() error = %v", err)
+	}
+	if repo.messages[1].Status.Int64 != types.SMSFailed || repo.messages[1].Error.String != "undeliverable (error 1)" {
+		t.Errorf("callback message = %+v", repo.messages[1])
+	}
+	if err = tracker.Callback(context.Background(), "unknown", values); err == nil {
+		t.Error("callback of unknown provider is accepted")
+	}
+}
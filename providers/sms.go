@@ -1,7 +1,61 @@
 package providers
 
-import "context"
+import (
+	"context"
+	"errors"
+	"net/url"
+	"time"
+)
+
+// errors of sms providers, provider specific errors match them with errors.Is
+var (
+	ErrSMSInvalidPhone      = errors.New("invalid phone number")
+	ErrSMSInsufficientFunds = errors.New("insufficient funds")
+	ErrSMSFlood             = errors.New("too many messages")
+	ErrSMSAuth              = errors.New("sms provider authorization failed")
+	ErrSMSProhibited        = errors.New("message is prohibited")
+)
 
 type SMS interface {
 	Send(ctx context.Context, phone, msg string) error
 }
+
+// SMSProvider member of SMSGateway
+type SMSProvider interface {
+	Name() string
+	Deliver(ctx context.Context, phone, msg string) (SMSResult, error)
+}
+
+// SMSResult message id assigned by provider and price of message
+type SMSResult struct {
+	ID   string
+	Cost float64
+}
+
+// SMSStatus delivery state of message, types.SMSSent until final state is known
+type SMSStatus struct {
+	ID     string
+	Status int64
+	Error  string
+	Time   time.Time
+}
+
+// SMSStatusChecker provider with delivery status requests
+type SMSStatusChecker interface {
+	Status(ctx context.Context, phone, id string) (SMSStatus, error)
+}
+
+// SMSCallbackParser provider pushing delivery status to callback endpoint
+type SMSCallbackParser interface {
+	ParseCallback(values url.Values) (SMSStatus, error)
+}
+
+type SMSBalance struct {
+	Balance  float64
+	Currency string
+}
+
+// SMSBalancer provider with account balance requests
+type SMSBalancer interface {
+	Balance(ctx context.Context) (SMSBalance, error)
+}
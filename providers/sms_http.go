@@ -0,0 +1,121 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ptflp/go-light/config"
+)
+
+const (
+	smsTimeout      = 10 * time.Second
+	smsErrorLength  = 256
+	contentTypeJSON = "application/json"
+	contentTypeForm = "application/x-www-form-urlencoded"
+)
+
+// HTTPSMS generic provider configured with request template
+type HTTPSMS struct {
+	client *http.Client
+	cfg    *config.SMSHTTP
+}
+
+func NewHTTPSMS(cfg *config.SMSHTTP) *HTTPSMS {
+	return &HTTPSMS{
+		client: &http.Client{Timeout: smsTimeout},
+		cfg:    cfg,
+	}
+}
+
+func (h *HTTPSMS) Name() string {
+	return h.cfg.Name
+}
+
+func (h *HTTPSMS) Send(ctx context.Context, phone, msg string) error {
+	_, err := h.Deliver(ctx, phone, msg)
+
+	return err
+}
+
+func (h *HTTPSMS) Deliver(ctx context.Context, phone, msg string) (SMSResult, error) {
+	req, err := h.buildRequest(ctx, phone, msg)
+	if err != nil {
+		return SMSResult{}, err
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return SMSResult{}, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil {
+		return SMSResult{}, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		text := string(body)
+		if len(text) > smsErrorLength {
+			text = text[:smsErrorLength]
+		}
+		return SMSResult{}, fmt.Errorf("%s: http status %d: %s", h.cfg.Name, resp.StatusCode, text)
+	}
+
+	result := SMSResult{Cost: h.cfg.Cost}
+	if h.cfg.IDField != "" {
+		var fields map[string]interface{}
+		if json.Unmarshal(body, &fields) == nil && fields[h.cfg.IDField] != nil {
+			result.ID = fmt.Sprint(fields[h.cfg.IDField])
+		}
+	}
+
+	return result, nil
+}
+
+func (h *HTTPSMS) buildRequest(ctx context.Context, phone, msg string) (*http.Request, error) {
+	escapeURL := strings.NewReplacer("{phone}", url.QueryEscape(phone), "{message}", url.QueryEscape(msg))
+	method := strings.ToUpper(h.cfg.Method)
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	var body io.Reader
+	if h.cfg.Body != "" {
+		var escapeBody *strings.Replacer
+		switch {
+		case strings.HasPrefix(h.cfg.ContentType, contentTypeJSON):
+			escapeBody = strings.NewReplacer("{phone}", jsonEscape(phone), "{message}", jsonEscape(msg))
+		case strings.HasPrefix(h.cfg.ContentType, contentTypeForm):
+			escapeBody = escapeURL
+		default:
+			escapeBody = strings.NewReplacer("{phone}", phone, "{message}", msg)
+		}
+		body = strings.NewReader(escapeBody.Replace(h.cfg.Body))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, escapeURL.Replace(h.cfg.URL), body)
+	if err != nil {
+		return nil, err
+	}
+	if h.cfg.ContentType != "" {
+		req.Header.Set("Content-Type", h.cfg.ContentType)
+	}
+	for name, value := range h.cfg.Headers {
+		req.Header.Set(name, value)
+	}
+
+	return req, nil
+}
+
+// jsonEscape json string without quotes
+func jsonEscape(s string) string {
+	data, _ := json.Marshal(s)
+
+	return string(data[1 : len(data)-1])
+}
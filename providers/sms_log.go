@@ -0,0 +1,34 @@
+package providers
+
+import (
+	"context"
+
+	"github.com/ptflp/go-light/config"
+	"go.uber.org/zap"
+)
+
+// LogSMS dev provider, messages are written to log instead of sending
+type LogSMS struct {
+	logger *zap.Logger
+}
+
+func NewLogSMS(logger *zap.Logger) *LogSMS {
+	return &LogSMS{logger: logger}
+}
+
+func (l *LogSMS) Name() string {
+	return config.SMSProviderLog
+}
+
+func (l *LogSMS) Send(ctx context.Context, phone, msg string) error {
+	_, err := l.Deliver(ctx, phone, msg)
+
+	return err
+}
+
+func (l *LogSMS) Deliver(ctx context.Context, phone, msg string) (SMSResult, error) {
+	_ = ctx
+	l.logger.Info("sms", zap.String("phone", phone), zap.String("message", msg))
+
+	return SMSResult{}, nil
+}
@@ -2,13 +2,53 @@ package providers
 
 import (
 	"context"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/ptflp/go-light/config"
+	"github.com/ptflp/go-light/types"
 )
 
+// responses are always requested in json
+const smscFormatJSON = "3"
+
+// SMSCError error response of smsc api
+type SMSCError struct {
+	Code    int    `json:"error_code"`
+	Message string `json:"error"`
+}
+
+func (e *SMSCError) Error() string {
+	return fmt.Sprintf("smsc error %d: %s", e.Code, e.Message)
+}
+
+// Is maps smsc error codes of send request to provider independent errors
+func (e *SMSCError) Is(target error) bool {
+	switch e.Code {
+	case 2, 4:
+		return target == ErrSMSAuth
+	case 3:
+		return target == ErrSMSInsufficientFunds
+	case 6:
+		return target == ErrSMSProhibited
+	case 7, 8:
+		return target == ErrSMSInvalidPhone
+	case 9:
+		return target == ErrSMSFlood
+	}
+
+	return false
+}
+
 type SMSC struct {
 	client *http.Client
 	cfg    *config.SMSC
@@ -16,65 +56,226 @@ type SMSC struct {
 
 func NewSMSC(cfg *config.SMSC) *SMSC {
 	return &SMSC{
-		client: &http.Client{},
+		client: &http.Client{Timeout: smsTimeout},
 		cfg:    cfg,
 	}
 }
 
+func (s *SMSC) Name() string {
+	return config.SMSProviderSMSC
+}
+
 func (s *SMSC) Send(ctx context.Context, phone, msg string) error {
+	_, err := s.Deliver(ctx, phone, msg)
+
+	return err
+}
+
+func (s *SMSC) Deliver(ctx context.Context, phone, msg string) (SMSResult, error) {
 	if s.cfg.Dev {
-		return nil
+		return SMSResult{}, nil
 	}
 	smscUrl, err := s.buildUrl(phone, msg)
 	if err != nil {
-		return err
+		return SMSResult{}, err
 	}
 
-	resp, err := s.call(ctx, smscUrl)
-	_ = resp
+	resp := &Response{}
+	err = s.call(ctx, smscUrl, resp)
 	if err != nil {
-		return err
+		return SMSResult{}, err
+	}
+	// cost is returned only when requested with cost parameter
+	cost, _ := strconv.ParseFloat(resp.Cost, 64)
+
+	return SMSResult{ID: strconv.Itoa(resp.ID), Cost: cost}, nil
+}
+
+// FlashCall code is generated by smsc and returned in response
+func (s *SMSC) FlashCall(ctx context.Context, phone string) (string, error) {
+	if s.cfg.Dev {
+		return "", nil
+	}
+	resp, err := s.voiceCall(ctx, phone, "code")
+	if err != nil {
+		return "", err
+	}
+	if resp.Code == "" {
+		return "", errors.New("smsc flash call response without code")
+	}
+
+	return resp.Code, nil
+}
+
+func (s *SMSC) Call(ctx context.Context, phone, msg string) error {
+	if s.cfg.Dev {
+		return nil
+	}
+	_, err := s.voiceCall(ctx, phone, msg)
+
+	return err
+}
+
+// Status requests delivery status of message
+func (s *SMSC) Status(ctx context.Context, phone, id string) (SMSStatus, error) {
+	statusUrl, err := s.endpoint("sys/status.php", url.Values{"phone": {phone}, "id": {id}})
+	if err != nil {
+		return SMSStatus{}, err
+	}
+
+	resp := &StatusResponse{}
+	err = s.call(ctx, statusUrl, resp)
+	if err != nil {
+		return SMSStatus{}, err
+	}
+
+	status := SMSStatus{ID: id, Status: smscStatus(resp.Status), Error: smscStatusError(resp.Status, resp.Err)}
+	if resp.LastTimestamp > 0 {
+		status.Time = time.Unix(resp.LastTimestamp, 0)
+	}
+
+	return status, nil
+}
+
+func (s *SMSC) Balance(ctx context.Context) (SMSBalance, error) {
+	balanceUrl, err := s.endpoint("sys/balance.php", url.Values{"cur": {"1"}})
+	if err != nil {
+		return SMSBalance{}, err
+	}
+
+	resp := &BalanceResponse{}
+	err = s.call(ctx, balanceUrl, resp)
+	if err != nil {
+		return SMSBalance{}, err
 	}
-	return nil
+	balance, err := strconv.ParseFloat(resp.Balance, 64)
+	if err != nil {
+		return SMSBalance{}, err
+	}
+
+	return SMSBalance{Balance: balance, Currency: resp.Currency}, nil
+}
+
+// ParseCallback reads status pushed by smsc, request is signed with sha1(id:phone:status:password)
+func (s *SMSC) ParseCallback(values url.Values) (SMSStatus, error) {
+	id, phone, status := values.Get("id"), values.Get("phone"), values.Get("status")
+	sum := sha1.Sum([]byte(strings.Join([]string{id, phone, status, s.cfg.Pwd}, ":")))
+	if subtle.ConstantTimeCompare([]byte(strings.ToLower(values.Get("sha1"))), []byte(hex.EncodeToString(sum[:]))) != 1 {
+		return SMSStatus{}, errors.New("wrong smsc callback signature")
+	}
+	code, err := strconv.Atoi(status)
+	if err != nil {
+		return SMSStatus{}, fmt.Errorf("wrong smsc callback status %q", status)
+	}
+	errCode, _ := strconv.Atoi(values.Get("err"))
+
+	result := SMSStatus{ID: id, Status: smscStatus(code), Error: smscStatusError(code, errCode), Time: time.Now()}
+	if ts, err := strconv.ParseInt(values.Get("ts"), 10, 64); err == nil && ts > 0 {
+		result.Time = time.Unix(ts, 0)
+	}
+
+	return result, nil
 }
 
 func (s *SMSC) buildUrl(phone, msg string) (string, error) {
-	u := url.URL{
-		Scheme: "https",
-		Host:   "smsc.ru",
-		Path:   "sys/send.php",
-	}
-	q := u.Query()
-	q.Set("login", s.cfg.Login)
-	q.Set("psw", s.cfg.Pwd)
-	q.Set("phones", phone)
-	q.Set("mes", msg)
-	q.Set("cost", s.cfg.Cost)
-	q.Set("fmt", s.cfg.Fmt)
-	u.RawQuery = q.Encode()
+	params := url.Values{"phones": {phone}, "mes": {msg}}
+	if s.cfg.Cost != "" {
+		params.Set("cost", s.cfg.Cost)
+	}
 
-	return u.String(), nil
+	return s.endpoint("sys/send.php", params)
 }
 
-func (s *SMSC) call(ctx context.Context, url string) (*Response, error) {
-	req, err := http.NewRequest("GET", url, nil)
+// voiceCall sends message as call, "code" message requests flash call
+func (s *SMSC) voiceCall(ctx context.Context, phone, msg string) (*Response, error) {
+	callUrl, err := s.endpoint("sys/send.php", url.Values{"phones": {phone}, "mes": {msg}, "call": {"1"}})
 	if err != nil {
 		return nil, err
 	}
-	req = req.WithContext(ctx)
 
-	r, err := s.client.Do(req)
+	resp := &Response{}
+	err = s.call(ctx, callUrl, resp)
 	if err != nil {
 		return nil, err
 	}
+
+	return resp, nil
+}
+
+func (s *SMSC) endpoint(path string, params url.Values) (string, error) {
+	u, err := url.Parse(s.cfg.BaseURL())
+	if err != nil {
+		return "", err
+	}
+	u.Path = path
+	params.Set("login", s.cfg.Login)
+	params.Set("psw", s.cfg.Pwd)
+	params.Set("fmt", smscFormatJSON)
+	u.RawQuery = params.Encode()
+
+	return u.String(), nil
+}
+
+// call decodes response into dest, error response is returned as *SMSCError
+func (s *SMSC) call(ctx context.Context, url string, dest interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+
+	r, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
 	defer r.Body.Close()
-	response := &Response{}
-	err = json.NewDecoder(r.Body).Decode(response)
+	if r.StatusCode != http.StatusOK {
+		return fmt.Errorf("smsc http status %d", r.StatusCode)
+	}
+
+	var raw json.RawMessage
+	err = json.NewDecoder(r.Body).Decode(&raw)
 	if err != nil {
-		return nil, err
+		return err
+	}
+	smscErr := &SMSCError{}
+	if err = json.Unmarshal(raw, smscErr); err == nil && (smscErr.Code != 0 || smscErr.Message != "") {
+		return smscErr
+	}
+
+	return json.Unmarshal(raw, dest)
+}
+
+// smscStatus maps smsc message status to types.SMSSent, types.SMSDelivered or types.SMSFailed
+func smscStatus(code int) int64 {
+	switch code {
+	case 1, 2, 4:
+		return types.SMSDelivered
+	case -2, 3, 20, 22, 23, 24, 25:
+		return types.SMSFailed
+	default:
+		// -3 not found yet, -1 waiting, 0 transferred to operator
+		return types.SMSSent
+	}
+}
+
+func smscStatusError(code, errCode int) string {
+	if smscStatus(code) != types.SMSFailed {
+		return ""
+	}
+	descriptions := map[int]string{
+		-2: "stopped",
+		3:  "expired",
+		20: "undeliverable",
+		22: "invalid number",
+		23: "prohibited",
+		24: "insufficient funds",
+		25: "unavailable number",
+	}
+	if errCode != 0 {
+		return fmt.Sprintf("%s (error %d)", descriptions[code], errCode)
 	}
 
-	return response, nil
+	return descriptions[code]
 }
 
 type Response struct {
@@ -82,4 +283,18 @@ type Response struct {
 	Cnt     int    `json:"cnt"`
 	Cost    string `json:"cost"`
 	Balance string `json:"balance"`
+	// Code last digits of caller number of flash call
+	Code string `json:"code"`
+}
+
+type StatusResponse struct {
+	Status        int    `json:"status"`
+	LastDate      string `json:"last_date"`
+	LastTimestamp int64  `json:"last_timestamp"`
+	Err           int    `json:"err"`
+}
+
+type BalanceResponse struct {
+	Balance  string `json:"balance"`
+	Currency string `json:"currency"`
 }
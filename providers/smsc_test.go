@@ -1,10 +1,19 @@
 package providers
 
 import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
 	"testing"
 
 	"github.com/ptflp/go-light/config"
+	"github.com/ptflp/go-light/types"
 )
 
 func TestSMSC_buildUrl(t *testing.T) {
@@ -60,3 +69,153 @@ func TestSMSC_buildUrl(t *testing.T) {
 		})
 	}
 }
+
+// testSMSCServer local stand-in of smsc api
+type testSMSCServer struct {
+	*httptest.Server
+	status map[string]int
+}
+
+func newTestSMSCServer(t *testing.T) *testSMSCServer {
+	s := &testSMSCServer{status: make(map[string]int)}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("login") != "login" || q.Get("psw") != "parol" {
+			_, _ = w.Write([]byte(`{"error": "authorise error", "error_code": 2}`))
+			return
+		}
+		if q.Get("fmt") != "3" {
+			t.Errorf("fmt = %s", q.Get("fmt"))
+		}
+		switch r.URL.Path {
+		case "/sys/send.php":
+			switch q.Get("phones") {
+			case "70000000000":
+				_, _ = w.Write([]byte(`{"error": "invalid number", "error_code": 7}`))
+			case "70000000001":
+				_, _ = w.Write([]byte(`{"error": "no money", "error_code": 3}`))
+			case "70000000002":
+				_, _ = w.Write([]byte(`{"error": "duplicate request, wait a minute", "error_code": 9}`))
+			default:
+				id := len(s.status) + 100
+				s.status[strconv.Itoa(id)] = -1
+				if q.Get("call") == "1" && q.Get("mes") == "code" {
+					_, _ = fmt.Fprintf(w, `{"id": %d, "cnt": 1, "code": "0451"}`, id)
+					return
+				}
+				_, _ = fmt.Fprintf(w, `{"id": %d, "cnt": 1, "cost": "2.5", "balance": "100.5"}`, id)
+			}
+		case "/sys/status.php":
+			status, ok := s.status[q.Get("id")]
+			if !ok {
+				status = -3
+			}
+			_, _ = fmt.Fprintf(w, `{"status": %d, "last_date": "01.01.2030 12:00:00", "last_timestamp": 1893488400, "err": 0}`, status)
+		case "/sys/balance.php":
+			_, _ = w.Write([]byte(`{"balance": "100.50", "currency": "RUR"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	return s
+}
+
+func testSMSCConfig(s *testSMSCServer) *config.SMSC {
+	return &config.SMSC{Login: "login", Pwd: "parol", Cost: "3", URL: s.URL}
+}
+
+func TestSMSC_Deliver(t *testing.T) {
+	server := newTestSMSCServer(t)
+	defer server.Close()
+	smsc := NewSMSC(testSMSCConfig(server))
+
+	result, err := smsc.Deliver(context.Background(), "79644288083", "code")
+	if err != nil {
+		t.Fatalf("Deliver() error = %v", err)
+	}
+	if result.ID != "100" || result.Cost != 2.5 {
+		t.Errorf("Deliver() = %+v", result)
+	}
+
+	errs := map[string]error{
+		"70000000000": ErrSMSInvalidPhone,
+		"70000000001": ErrSMSInsufficientFunds,
+		"70000000002": ErrSMSFlood,
+	}
+	for phone, want := range errs {
+		_, err = smsc.Deliver(context.Background(), phone, "code")
+		var smscErr *SMSCError
+		if !errors.Is(err, want) || !errors.As(err, &smscErr) {
+			t.Errorf("Deliver(%s) error = %v, want %v", phone, err, want)
+		}
+	}
+
+	cfg := testSMSCConfig(server)
+	cfg.Pwd = "wrong"
+	if _, err = NewSMSC(cfg).Deliver(context.Background(), "79644288083", "code"); !errors.Is(err, ErrSMSAuth) {
+		t.Errorf("Deliver() error = %v, want %v", err, ErrSMSAuth)
+	}
+
+	balance, err := smsc.Balance(context.Background())
+	if err != nil || balance.Balance != 100.5 || balance.Currency != "RUR" {
+		t.Errorf("Balance() = %+v, %v", balance, err)
+	}
+}
+
+func TestSMSC_Status(t *testing.T) {
+	server := newTestSMSCServer(t)
+	defer server.Close()
+	smsc := NewSMSC(testSMSCConfig(server))
+	server.status["1"] = 1
+	server.status["2"] = 22
+	server.status["3"] = 0
+
+	tests := []struct {
+		id     string
+		status int64
+		error  string
+	}{
+		{id: "1", status: types.SMSDelivered},
+		{id: "2", status: types.SMSFailed, error: "invalid number"},
+		{id: "3", status: types.SMSSent},
+		{id: "4", status: types.SMSSent},
+	}
+	for _, tt := range tests {
+		status, err := smsc.Status(context.Background(), "79644288083", tt.id)
+		if err != nil {
+			t.Fatalf("Status(%s) error = %v", tt.id, err)
+		}
+		if status.Status != tt.status || status.Error != tt.error || status.Time.Unix() != 1893488400 {
+			t.Errorf("Status(%s) = %+v", tt.id, status)
+		}
+	}
+
+	sum := sha1.Sum([]byte("1:79644288083:1:parol"))
+	values := url.Values{"id": {"1"}, "phone": {"79644288083"}, "status": {"1"}, "sha1": {hex.EncodeToString(sum[:])}}
+	status, err := smsc.ParseCallback(values)
+	if err != nil || status.ID != "1" || status.Status != types.SMSDelivered {
+		t.Errorf("ParseCallback() = %+v, %v", status, err)
+	}
+	values.Set("status", "22")
+	if _, err = smsc.ParseCallback(values); err == nil {
+		t.Error("callback with wrong signature is accepted")
+	}
+}
+
+func TestSMSC_Voice(t *testing.T) {
+	server := newTestSMSCServer(t)
+	defer server.Close()
+	smsc := NewSMSC(testSMSCConfig(server))
+
+	code, err := smsc.FlashCall(context.Background(), "79644288083")
+	if err != nil || code != "0451" {
+		t.Errorf("FlashCall() = %s, %v", code, err)
+	}
+	if err = smsc.Call(context.Background(), "79644288083", "Ваш код: 1 2 3 4"); err != nil {
+		t.Errorf("Call() error = %v", err)
+	}
+	if _, err = smsc.FlashCall(context.Background(), "70000000000"); !errors.Is(err, ErrSMSInvalidPhone) {
+		t.Errorf("FlashCall() error = %v, want %v", err, ErrSMSInvalidPhone)
+	}
+}
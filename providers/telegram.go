@@ -0,0 +1,156 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/ptflp/go-light/config"
+)
+
+var (
+	ErrTelegramDisabled = errors.New("telegram bot is not configured")
+	// ErrTelegramBlocked bot is blocked by user or chat doesn't exist anymore
+	ErrTelegramBlocked = errors.New("telegram chat is not available")
+)
+
+// TelegramError error response of bot api
+type TelegramError struct {
+	Code        int    `json:"error_code"`
+	Description string `json:"description"`
+}
+
+func (e *TelegramError) Error() string {
+	return fmt.Sprintf("telegram error %d: %s", e.Code, e.Description)
+}
+
+func (e *TelegramError) Is(target error) bool {
+	if target != ErrTelegramBlocked {
+		return false
+	}
+
+	return e.Code == http.StatusForbidden || (e.Code == http.StatusBadRequest && strings.Contains(e.Description, "chat not found"))
+}
+
+// TelegramUpdate incoming update of webhook, only messages are handled
+type TelegramUpdate struct {
+	UpdateID int64            `json:"update_id"`
+	Message  *TelegramMessage `json:"message"`
+}
+
+type TelegramMessage struct {
+	MessageID int64        `json:"message_id"`
+	Chat      TelegramChat `json:"chat"`
+	Text      string       `json:"text"`
+}
+
+type TelegramChat struct {
+	ID       int64  `json:"id"`
+	Type     string `json:"type"`
+	Username string `json:"username"`
+}
+
+// Command bot command of message and its argument, "/start token" returns "/start", "token"
+func (m *TelegramMessage) Command() (string, string) {
+	if !strings.HasPrefix(m.Text, "/") {
+		return "", ""
+	}
+	fields := strings.Fields(m.Text)
+	// commands of group chats are addressed as /command@bot
+	command := strings.SplitN(fields[0], "@", 2)[0]
+	if len(fields) < 2 {
+		return command, ""
+	}
+
+	return command, fields[1]
+}
+
+// Telegram client of bot api
+type Telegram struct {
+	client *http.Client
+	cfg    *config.Telegram
+}
+
+func NewTelegram(cfg *config.Telegram) *Telegram {
+	return &Telegram{
+		client: &http.Client{Timeout: smsTimeout},
+		cfg:    cfg,
+	}
+}
+
+func (t *Telegram) Enabled() bool {
+	return t.cfg.Enabled()
+}
+
+func (t *Telegram) SendMessage(ctx context.Context, chatID int64, text string) error {
+	return t.call(ctx, "sendMessage", map[string]interface{}{
+		"chat_id":                  chatID,
+		"text":                     text,
+		"disable_web_page_preview": true,
+	})
+}
+
+// SetWebhook registers url of updates, secret is sent back in X-Telegram-Bot-Api-Secret-Token header
+func (t *Telegram) SetWebhook(ctx context.Context, webhook, secret string) error {
+	params := map[string]interface{}{
+		"url":             webhook,
+		"allowed_updates": []string{"message"},
+	}
+	if secret != "" {
+		params["secret_token"] = secret
+	}
+
+	return t.call(ctx, "setWebhook", params)
+}
+
+// DeepLink link opening chat with bot, token is sent back with /start command
+func (t *Telegram) DeepLink(token string) string {
+	return fmt.Sprintf("https://t.me/%s?start=%s", t.cfg.Bot, url.QueryEscape(token))
+}
+
+// call error response is returned as *TelegramError
+func (t *Telegram) call(ctx context.Context, method string, params interface{}) error {
+	if !t.Enabled() {
+		return ErrTelegramDisabled
+	}
+	body, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	endpoint := fmt.Sprintf("%s/bot%s/%s", strings.TrimSuffix(t.cfg.BaseURL(), "/"), t.cfg.Token, method)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentTypeJSON)
+
+	r, err := t.client.Do(req)
+	if err != nil {
+		// error of client contains url with token
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return urlErr.Err
+		}
+		return err
+	}
+	defer r.Body.Close()
+
+	var resp struct {
+		OK bool `json:"ok"`
+		TelegramError
+	}
+	err = json.NewDecoder(r.Body).Decode(&resp)
+	if err != nil {
+		return fmt.Errorf("telegram http status %d: %w", r.StatusCode, err)
+	}
+	if !resp.OK {
+		return &resp.TelegramError
+	}
+
+	return nil
+}
@@ -0,0 +1,113 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ptflp/go-light/config"
+)
+
+// testTelegramServer local stand-in of bot api
+type testTelegramServer struct {
+	*httptest.Server
+	messages map[int64][]string
+	webhook  string
+}
+
+func newTestTelegramServer(t *testing.T) *testTelegramServer {
+	s := &testTelegramServer{messages: make(map[int64][]string)}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var params struct {
+			ChatID      int64  `json:"chat_id"`
+			Text        string `json:"text"`
+			URL         string `json:"url"`
+			SecretToken string `json:"secret_token"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+			t.Errorf("request body error = %v", err)
+		}
+		switch r.URL.Path {
+		case "/bottoken/sendMessage":
+			switch params.ChatID {
+			case 403:
+				w.WriteHeader(http.StatusForbidden)
+				_, _ = w.Write([]byte(`{"ok": false, "error_code": 403, "description": "Forbidden: bot was blocked by the user"}`))
+			case 400:
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"ok": false, "error_code": 400, "description": "Bad Request: chat not found"}`))
+			default:
+				s.messages[params.ChatID] = append(s.messages[params.ChatID], params.Text)
+				_, _ = w.Write([]byte(`{"ok": true, "result": {"message_id": 1}}`))
+			}
+		case "/bottoken/setWebhook":
+			s.webhook = params.URL + " " + params.SecretToken
+			_, _ = w.Write([]byte(`{"ok": true, "result": true}`))
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"ok": false, "error_code": 401, "description": "Unauthorized"}`))
+		}
+	}))
+
+	return s
+}
+
+func TestTelegram(t *testing.T) {
+	server := newTestTelegramServer(t)
+	defer server.Close()
+	tg := NewTelegram(&config.Telegram{Token: "token", Bot: "light_bot", URL: server.URL})
+
+	if err := tg.SendMessage(context.Background(), 42, "Ваш код: 1234"); err != nil {
+		t.Fatalf("SendMessage() error = %v", err)
+	}
+	if len(server.messages[42]) != 1 || server.messages[42][0] != "Ваш код: 1234" {
+		t.Errorf("messages = %v", server.messages)
+	}
+	for _, chatID := range []int64{403, 400} {
+		err := tg.SendMessage(context.Background(), chatID, "text")
+		var tgErr *TelegramError
+		if !errors.Is(err, ErrTelegramBlocked) || !errors.As(err, &tgErr) {
+			t.Errorf("SendMessage(%d) error = %v, want %v", chatID, err, ErrTelegramBlocked)
+		}
+	}
+
+	if err := tg.SetWebhook(context.Background(), "https://api.example.com/telegram/webhook", "secret"); err != nil {
+		t.Fatalf("SetWebhook() error = %v", err)
+	}
+	if server.webhook != "https://api.example.com/telegram/webhook secret" {
+		t.Errorf("webhook = %s", server.webhook)
+	}
+	if link := tg.DeepLink("abc"); link != "https://t.me/light_bot?start=abc" {
+		t.Errorf("DeepLink() = %s", link)
+	}
+
+	wrong := NewTelegram(&config.Telegram{Token: "wrong", URL: server.URL})
+	if err := wrong.SendMessage(context.Background(), 42, "text"); err == nil || errors.Is(err, ErrTelegramBlocked) {
+		t.Errorf("SendMessage() error = %v with wrong token", err)
+	}
+	disabled := NewTelegram(&config.Telegram{})
+	if err := disabled.SendMessage(context.Background(), 42, "text"); !errors.Is(err, ErrTelegramDisabled) {
+		t.Errorf("SendMessage() error = %v, want %v", err, ErrTelegramDisabled)
+	}
+}
+
+func TestTelegramMessage_Command(t *testing.T) {
+	tests := []struct {
+		text    string
+		command string
+		arg     string
+	}{
+		{text: "/start abc", command: "/start", arg: "abc"},
+		{text: "/stop@light_bot", command: "/stop"},
+		{text: "hello", command: ""},
+	}
+	for _, tt := range tests {
+		m := &TelegramMessage{Text: tt.text}
+		if command, arg := m.Command(); command != tt.command || arg != tt.arg {
+			t.Errorf("Command(%q) = %q, %q", tt.text, command, arg)
+		}
+	}
+}
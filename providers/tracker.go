@@ -0,0 +1,169 @@
+package providers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	light "github.com/ptflp/go-light"
+	"github.com/ptflp/go-light/config"
+	"github.com/ptflp/go-light/types"
+	"go.uber.org/zap"
+)
+
+const (
+	smsStatusBatch   = 50
+	smsStatusTTL     = 24 * time.Hour
+	smsStatusTimeout = 10 * time.Second
+)
+
+// SMSTracker stores messages sent by gateway and updates their delivery status by polling and provider callbacks
+type SMSTracker struct {
+	gateway  *SMSGateway
+	repo     light.SMSRepository
+	interval time.Duration
+	logger   *zap.Logger
+}
+
+func NewSMSTracker(gateway *SMSGateway, repo light.SMSRepository, cfg *config.SMS, logger *zap.Logger) *SMSTracker {
+	t := &SMSTracker{
+		gateway:  gateway,
+		repo:     repo,
+		interval: cfg.StatusPoll(),
+		logger:   logger,
+	}
+	gateway.tracker = t
+
+	return t
+}
+
+// Run polls delivery status of sent messages until context is done
+func (t *SMSTracker) Run(ctx context.Context) {
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			t.poll(ctx)
+		}
+	}
+}
+
+// Callback applies status pushed by provider
+func (t *SMSTracker) Callback(ctx context.Context, provider string, values url.Values) error {
+	m := t.gateway.member(provider)
+	if m == nil {
+		return fmt.Errorf("unknown sms provider %q", provider)
+	}
+	parser, ok := m.SMSProvider.(SMSCallbackParser)
+	if !ok {
+		return fmt.Errorf("sms provider %s has no status callback", provider)
+	}
+	status, err := parser.ParseCallback(values)
+	if err != nil {
+		return err
+	}
+
+	msg, err := t.repo.FindByProviderID(ctx, provider, status.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		// status of message sent before tracking was enabled
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return t.update(ctx, msg, status)
+}
+
+// record stores message accepted by provider, messages without provider id can't be tracked
+func (t *SMSTracker) record(provider, phone string, result SMSResult) {
+	if result.ID == "" {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), smsStatusTimeout)
+	defer cancel()
+
+	err := t.repo.Create(ctx, light.SMSMessage{
+		UUID:       types.NewNullUUID(),
+		Provider:   types.NewNullString(provider),
+		ProviderID: types.NewNullString(result.ID),
+		Phone:      types.NewNullString(phone),
+		Cost:       types.NewNullFloat64(result.Cost),
+		Status:     types.NewNullInt64(types.SMSSent),
+	})
+	if err != nil {
+		t.logger.Error("sms message record", zap.String("provider", provider), zap.String("id", result.ID), zap.Error(err))
+	}
+}
+
+func (t *SMSTracker) poll(ctx context.Context) {
+	messages, err := t.repo.ListSent(ctx, smsStatusBatch)
+	if err != nil {
+		t.logger.Error("sms status list", zap.Error(err))
+		return
+	}
+
+	for i := range messages {
+		if ctx.Err() != nil {
+			return
+		}
+		msg := messages[i]
+		status := SMSStatus{ID: msg.ProviderID.String, Status: types.SMSSent}
+		if time.Since(msg.CreatedAt) > smsStatusTTL {
+			status.Status = types.SMSFailed
+			status.Error = "delivery status unknown"
+		} else if m := t.gateway.member(msg.Provider.String); m != nil {
+			if checker, ok := m.SMSProvider.(SMSStatusChecker); ok {
+				status, err = t.status(ctx, checker, msg)
+				if err != nil {
+					t.logger.Warn("sms status request", zap.String("provider", msg.Provider.String), zap.String("id", msg.ProviderID.String), zap.Error(err))
+					if errors.Is(err, ErrSMSFlood) {
+						return
+					}
+					status = SMSStatus{ID: msg.ProviderID.String, Status: types.SMSSent}
+				}
+			}
+		}
+		if err = t.update(ctx, msg, status); err != nil {
+			t.logger.Error("sms status update", zap.String("id", msg.ProviderID.String), zap.Error(err))
+		}
+	}
+}
+
+func (t *SMSTracker) status(ctx context.Context, checker SMSStatusChecker, msg light.SMSMessage) (SMSStatus, error) {
+	ctx, cancel := context.WithTimeout(ctx, smsStatusTimeout)
+	defer cancel()
+
+	return checker.Status(ctx, msg.Phone.String, msg.ProviderID.String)
+}
+
+func (t *SMSTracker) update(ctx context.Context, msg light.SMSMessage, status SMSStatus) error {
+	// final state is not changed by late callbacks
+	if msg.Status.Int64 != types.SMSSent {
+		return nil
+	}
+	msg.Status = types.NewNullInt64(status.Status)
+	if status.Error != "" {
+		msg.Error = types.NewNullString(status.Error)
+	}
+	msg.CheckedAt.SetValid(time.Now())
+	if status.Status == types.SMSDelivered {
+		deliveredAt := status.Time
+		if deliveredAt.IsZero() {
+			deliveredAt = time.Now()
+		}
+		msg.DeliveredAt.SetValid(deliveredAt)
+	}
+	if status.Status == types.SMSFailed {
+		t.logger.Warn("sms not delivered", zap.String("provider", msg.Provider.String), zap.String("id", msg.ProviderID.String), zap.String("error", status.Error))
+	}
+
+	return t.repo.Update(ctx, msg)
+}
@@ -0,0 +1,113 @@
+package providers
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// vapidTTL lifetime of authorization token, push services accept up to 24 hours
+const vapidTTL = 12 * time.Hour
+
+// VAPID application server identification of RFC 8292
+type VAPID struct {
+	key     *ecdsa.PrivateKey
+	subject string
+}
+
+// LoadVAPID reads P-256 key of PEM file, new key is generated and saved when file doesn't exist
+func LoadVAPID(path, subject string) (*VAPID, error) {
+	data, err := ioutil.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return generateVAPID(path, subject)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("vapid key %s is not pem encoded", path)
+	}
+	key, err := parseECKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("vapid key %s: %w", path, err)
+	}
+	if key.Curve != elliptic.P256() {
+		return nil, fmt.Errorf("vapid key %s is not P-256 key", path)
+	}
+
+	return NewVAPID(key, subject), nil
+}
+
+func NewVAPID(key *ecdsa.PrivateKey, subject string) *VAPID {
+	return &VAPID{key: key, subject: subject}
+}
+
+// parseECKey accepts SEC 1 and PKCS #8 keys
+func parseECKey(der []byte) (*ecdsa.PrivateKey, error) {
+	if key, err := x509.ParseECPrivateKey(der); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := parsed.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("key is not ecdsa key")
+	}
+
+	return key, nil
+}
+
+func generateVAPID(path, subject string) (*VAPID, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	err = ioutil.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewVAPID(key, subject), nil
+}
+
+// PublicKey uncompressed public key in base64url, applicationServerKey of PushManager.subscribe
+func (v *VAPID) PublicKey() string {
+	return base64.RawURLEncoding.EncodeToString(elliptic.Marshal(v.key.Curve, v.key.X, v.key.Y))
+}
+
+// Authorization header value of request to push endpoint
+func (v *VAPID) Authorization(endpoint string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+		"aud": fmt.Sprintf("%s://%s", u.Scheme, u.Host),
+		"exp": time.Now().Add(vapidTTL).Unix(),
+		"sub": v.subject,
+	}).SignedString(v.key)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("vapid t=%s, k=%s", token, v.PublicKey()), nil
+}
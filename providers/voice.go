@@ -0,0 +1,11 @@
+package providers
+
+import "context"
+
+// Voice provider of verification calls
+type Voice interface {
+	// FlashCall calls phone from number which last digits are returned as code
+	FlashCall(ctx context.Context, phone string) (string, error)
+	// Call dictates message with speech synthesis
+	Call(ctx context.Context, phone, msg string) error
+}
@@ -0,0 +1,227 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+const (
+	// pushRecordSize single record of aes128gcm content coding holds whole message
+	pushRecordSize = 4096
+	// pushHeaderSize salt, record size, key id length and 65 bytes key id
+	pushHeaderSize = 16 + 4 + 1 + 65
+	// MaxPushPayload push services accept 4096 bytes of encrypted body
+	MaxPushPayload = pushRecordSize - pushHeaderSize - 16 - 1
+)
+
+var (
+	// ErrPushGone subscription is expired or unsubscribed by browser and must be removed
+	ErrPushGone       = errors.New("push subscription is gone")
+	ErrPushSubscriber = errors.New("wrong push subscription keys")
+	// ErrPushAddress endpoint resolves to loopback, private or link-local address
+	ErrPushAddress = errors.New("push endpoint address is not public")
+)
+
+// nonPublicNetworks networks push endpoints must not resolve to
+var nonPublicNetworks = func() []*net.IPNet {
+	cidrs := []string{
+		"0.0.0.0/8", "10.0.0.0/8", "100.64.0.0/10", "127.0.0.0/8", "169.254.0.0/16", "172.16.0.0/12", "192.168.0.0/16",
+		"::/128", "::1/128", "fc00::/7", "fe80::/10",
+	}
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, _ := net.ParseCIDR(cidr)
+		networks = append(networks, network)
+	}
+
+	return networks
+}()
+
+// publicAddress refuses connection to address of internal network, address is checked after dns resolution
+func publicAddress(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsMulticast() {
+		return ErrPushAddress
+	}
+	for _, n := range nonPublicNetworks {
+		if n.Contains(ip) {
+			return ErrPushAddress
+		}
+	}
+
+	return nil
+}
+
+// PushSubscription endpoint and keys of PushSubscription.toJSON() of browser
+type PushSubscription struct {
+	Endpoint string
+	// P256dh base64url public key of user agent
+	P256dh string
+	// Auth base64url authentication secret
+	Auth string
+}
+
+// WebPush sends encrypted messages (RFC 8291) to push endpoints authorized with VAPID
+type WebPush struct {
+	client *http.Client
+	vapid  *VAPID
+	ttl    time.Duration
+}
+
+func NewWebPush(vapid *VAPID, ttl time.Duration) *WebPush {
+	dialer := &net.Dialer{Timeout: smsTimeout, Control: publicAddress}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	// endpoint is user input, proxy would bypass address check
+	transport.Proxy = nil
+
+	return &WebPush{
+		client: &http.Client{Timeout: smsTimeout, Transport: transport},
+		vapid:  vapid,
+		ttl:    ttl,
+	}
+}
+
+// PublicKey applicationServerKey of subscriptions
+func (p *WebPush) PublicKey() string {
+	return p.vapid.PublicKey()
+}
+
+func (p *WebPush) Send(ctx context.Context, sub PushSubscription, payload []byte) error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	salt := make([]byte, 16)
+	if _, err = rand.Read(salt); err != nil {
+		return err
+	}
+	body, err := encryptPush(sub, payload, key.D.FillBytes(make([]byte, 32)), salt)
+	if err != nil {
+		return err
+	}
+	authorization, err := p.vapid.Authorization(sub.Endpoint)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", authorization)
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("TTL", strconv.Itoa(int(p.ttl.Seconds())))
+
+	r, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer r.Body.Close()
+
+	switch {
+	case r.StatusCode == http.StatusNotFound || r.StatusCode == http.StatusGone:
+		return ErrPushGone
+	case r.StatusCode >= http.StatusMultipleChoices:
+		data, _ := ioutil.ReadAll(io.LimitReader(r.Body, smsErrorLength))
+		return fmt.Errorf("push http status %d: %s", r.StatusCode, strings.TrimSpace(string(data)))
+	}
+
+	return nil
+}
+
+// encryptPush encrypts payload with aes128gcm content coding (RFC 8188) using keys derived by RFC 8291,
+// asPrivate ephemeral key of application server and salt must be random for every message
+func encryptPush(sub PushSubscription, payload, asPrivate, salt []byte) ([]byte, error) {
+	if len(payload) > MaxPushPayload {
+		return nil, fmt.Errorf("push payload is %d bytes, max %d", len(payload), MaxPushPayload)
+	}
+	uaPublic, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(sub.P256dh, "="))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrPushSubscriber, err)
+	}
+	authSecret, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(sub.Auth, "="))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrPushSubscriber, err)
+	}
+	curve := elliptic.P256()
+	uaX, uaY := elliptic.Unmarshal(curve, uaPublic)
+	if uaX == nil || len(authSecret) != 16 {
+		return nil, ErrPushSubscriber
+	}
+
+	asX, asY := curve.ScalarBaseMult(asPrivate)
+	asPublic := elliptic.Marshal(curve, asX, asY)
+
+	sharedX, _ := curve.ScalarMult(uaX, uaY, asPrivate)
+	shared := make([]byte, 32)
+	sharedX.FillBytes(shared)
+
+	// ikm of content encryption key is bound to both public keys and authentication secret
+	keyInfo := append(append([]byte("WebPush: info\x00"), uaPublic...), asPublic...)
+	ikm, err := hkdfBytes(hkdf.Extract(sha256.New, shared, authSecret), keyInfo, 32)
+	if err != nil {
+		return nil, err
+	}
+	prk := hkdf.Extract(sha256.New, ikm, salt)
+	cek, err := hkdfBytes(prk, []byte("Content-Encoding: aes128gcm\x00"), 16)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := hkdfBytes(prk, []byte("Content-Encoding: nonce\x00"), 12)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, 0, pushHeaderSize)
+	header = append(header, salt...)
+	header = append(header, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(header[16:], pushRecordSize)
+	header = append(header, byte(len(asPublic)))
+	header = append(header, asPublic...)
+
+	// 0x02 delimiter of last record, no padding
+	record := append(append([]byte{}, payload...), 2)
+
+	return gcm.Seal(header, nonce, record, nil), nil
+}
+
+func hkdfBytes(prk, info []byte, length int) ([]byte, error) {
+	b := make([]byte, length)
+	_, err := io.ReadFull(hkdf.Expand(sha256.New, prk, info), b)
+
+	return b, err
+}
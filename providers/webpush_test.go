@@ -0,0 +1,102 @@
+package providers
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEncryptPush(t *testing.T) {
+	// example of RFC 8291 appendix A
+	decode := func(s string) []byte {
+		b, err := base64.RawURLEncoding.DecodeString(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+	sub := PushSubscription{
+		P256dh: "BCVxsr7N_eNgVRqvHtD0zTZsEc6-VV-JvLexhqUzORcxaOzi6-AYWXvTBHm4bjyPjs7Vd8pZGH6SRpkNtoIAiw4",
+		Auth:   "BTBZMqHH6r4Tts7J_aSIgg",
+	}
+	body, err := encryptPush(sub, []byte("When I grow up, I want to be a watermelon"),
+		decode("yfWPiYE-n46HLnH0KqZOF1fJJU3MYrct3AELtAQ-oRw"), decode("DGv6ra1nlYgDCS1FRnbzlw"))
+	if err != nil {
+		t.Fatalf("encryptPush() error = %v", err)
+	}
+	want := "DGv6ra1nlYgDCS1FRnbzlwAAEABBBP4z9KsN6nGRTbVYI_c7VJSPQTBtkgcy27mlmlMoZIIgDll6e3vCYLocInmYWAmS6TlzAC8wEqKK6PBru3jl7A_yl95bQpu6cVPTpK4Mqgkf1CXztLVBSt2Ks3oZwbuwXPXLWyouBWLVWGNWQexSgSxsj_Qulcy4a-fN"
+	if got := base64.RawURLEncoding.EncodeToString(body); got != want {
+		t.Errorf("encryptPush() = %s, want %s", got, want)
+	}
+
+	sub.Auth = "short"
+	if _, err = encryptPush(sub, []byte("text"), decode("yfWPiYE-n46HLnH0KqZOF1fJJU3MYrct3AELtAQ-oRw"), decode("DGv6ra1nlYgDCS1FRnbzlw")); !errors.Is(err, ErrPushSubscriber) {
+		t.Errorf("encryptPush() error = %v, want %v", err, ErrPushSubscriber)
+	}
+}
+
+func TestWebPush_Send(t *testing.T) {
+	var authorization, encoding string
+	var size int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		authorization, encoding, size = r.Header.Get("Authorization"), r.Header.Get("Content-Encoding"), len(body)
+		if r.URL.Path == "/gone" {
+			w.WriteHeader(http.StatusGone)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "vapid")
+	vapid, err := LoadVAPID(path, "mailto:admin@example.com")
+	if err != nil {
+		t.Fatalf("LoadVAPID() error = %v", err)
+	}
+	// generated key is saved and loaded on next start
+	loaded, err := LoadVAPID(path, "mailto:admin@example.com")
+	if err != nil || loaded.PublicKey() != vapid.PublicKey() {
+		t.Fatalf("LoadVAPID() of saved key = %v, %v", loaded, err)
+	}
+
+	ua, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sub := PushSubscription{
+		Endpoint: server.URL + "/push",
+		P256dh:   base64.RawURLEncoding.EncodeToString(elliptic.Marshal(ua.Curve, ua.X, ua.Y)),
+		Auth:     "BTBZMqHH6r4Tts7J_aSIgg",
+	}
+	push := NewWebPush(vapid, time.Hour)
+	// test server listens on loopback
+	if err = push.Send(context.Background(), sub, []byte("text")); !errors.Is(err, ErrPushAddress) {
+		t.Fatalf("Send() to loopback error = %v, want %v", err, ErrPushAddress)
+	}
+	push.client = server.Client()
+	if err = push.Send(context.Background(), sub, []byte(`{"title": "title"}`)); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if !strings.HasPrefix(authorization, "vapid t=") || !strings.HasSuffix(authorization, ", k="+vapid.PublicKey()) {
+		t.Errorf("authorization = %s", authorization)
+	}
+	if encoding != "aes128gcm" || size != pushHeaderSize+len(`{"title": "title"}`)+1+16 {
+		t.Errorf("content encoding = %s, body size = %d", encoding, size)
+	}
+
+	sub.Endpoint = server.URL + "/gone"
+	if err = push.Send(context.Background(), sub, []byte("text")); !errors.Is(err, ErrPushGone) {
+		t.Errorf("Send() error = %v, want %v", err, ErrPushGone)
+	}
+}
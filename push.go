@@ -0,0 +1,38 @@
+package light
+
+import (
+	"context"
+	"time"
+
+	"github.com/ptflp/go-light/types"
+)
+
+// PushSubscription browser push subscription of user device
+type PushSubscription struct {
+	UUID      types.NullUUID   `json:"subscription_id" db:"uuid" ops:"create" orm_type:"binary(16)" orm_default:"not null primary key"`
+	UserUUID  types.NullUUID   `json:"user_id" db:"user_uuid" ops:"create,update" orm_type:"binary(16)" orm_default:"not null" orm_index:"index"`
+	Endpoint  types.NullString `json:"endpoint" db:"endpoint" ops:"create" orm_type:"varchar(512)" orm_default:"not null" orm_index:"index,unique"`
+	P256dh    types.NullString `json:"-" db:"p256dh" ops:"create,update" orm_type:"varchar(128)" orm_default:"not null"`
+	Auth      types.NullString `json:"-" db:"auth" ops:"create,update" orm_type:"varchar(64)" orm_default:"not null"`
+	UserAgent types.NullString `json:"user_agent" db:"user_agent" ops:"create,update" orm_type:"varchar(255)" orm_default:"null"`
+	CreatedAt time.Time        `json:"created_at" db:"created_at" orm_type:"timestamp" orm_default:"default (now()) not null"`
+	UpdatedAt time.Time        `json:"updated_at" db:"updated_at" orm_type:"timestamp" orm_default:"default (now()) null on update CURRENT_TIMESTAMP"`
+}
+
+func (p PushSubscription) OnCreate() string {
+	return ""
+}
+
+func (p PushSubscription) TableName() string {
+	return "push_subscriptions"
+}
+
+type PushRepository interface {
+	// Save adds subscription or updates keys of known endpoint, endpoint is moved to user of subscription
+	Save(ctx context.Context, subscription PushSubscription) error
+	// Delete removes subscription of user, empty user removes expired endpoint of any user
+	Delete(ctx context.Context, user User, endpoint string) error
+	// DeleteByUser removes every subscription of user
+	DeleteByUser(ctx context.Context, user User) error
+	ListByUser(ctx context.Context, user User) ([]PushSubscription, error)
+}
@@ -1,7 +1,18 @@
 package light
 
 type Repositories struct {
-	Users UserRepository
+	Users   UserRepository
+	Exports ExportRepository
+	Invites InviteRepository
+	OAuth   OAuthRepository
+	Audit   AuditRepository
+	Outbox  OutboxRepository
+	// Suppressions email suppression list and notification unsubscribes
+	Suppressions SuppressionRepository
+	// SMS sent sms messages and their delivery status
+	SMS SMSRepository
+	// Push browser push subscriptions of users
+	Push PushRepository
 }
 
 type Tabler interface {
@@ -0,0 +1,66 @@
+package request
+
+import (
+	"time"
+
+	"github.com/ptflp/go-light/types"
+)
+
+//go:generate easytags $GOFILE
+
+type ImpersonateReq struct {
+	UserID string `json:"user_id"`
+	Reason string `json:"reason"`
+}
+
+type ImpersonateData struct {
+	AccessToken string    `json:"access_token"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	User        UserData  `json:"user"`
+}
+
+type AuditReq struct {
+	UserID string `json:"user_id"`
+}
+
+type AuditEventData struct {
+	UUID      types.NullUUID   `json:"event_id"`
+	Type      types.NullInt64  `json:"type"`
+	UserUUID  types.NullUUID   `json:"user_id"`
+	ActorUUID types.NullUUID   `json:"actor_id"`
+	Reason    types.NullString `json:"reason"`
+	IP        types.NullString `json:"ip"`
+	CreatedAt time.Time        `json:"created_at"`
+}
+
+type OutboxData struct {
+	Pending       int64             `json:"pending"`
+	Retrying      int64             `json:"retrying"`
+	Sending       int64             `json:"sending"`
+	Dead          int64             `json:"dead"`
+	OldestPending types.NullTime    `json:"oldest_pending"`
+	Failures      []OutboxEmailData `json:"failures"`
+}
+
+type OutboxEmailData struct {
+	UUID          types.NullUUID   `json:"message_id"`
+	Recipients    types.NullString `json:"recipients"`
+	Subject       types.NullString `json:"subject"`
+	Attempts      types.NullInt64  `json:"attempts"`
+	LastError     types.NullString `json:"last_error"`
+	NextAttemptAt types.NullTime   `json:"next_attempt_at"`
+	CreatedAt     time.Time        `json:"created_at"`
+	UpdatedAt     time.Time        `json:"updated_at"`
+}
+
+type SMSProviderData struct {
+	Name      string  `json:"name"`
+	Healthy   bool    `json:"healthy"`
+	Sent      int64   `json:"sent"`
+	Failed    int64   `json:"failed"`
+	Cost      float64 `json:"cost"`
+	LastError string  `json:"last_error"`
+	// Balance account balance, null when provider has no balance api
+	Balance  *float64 `json:"balance"`
+	Currency string   `json:"currency,omitempty"`
+}
@@ -2,16 +2,29 @@ package request
 
 type PhoneCodeRequest struct {
 	Phone string `json:"phone"`
+	// Channel sms, flash, voice or telegram, first configured channel when empty
+	Channel string `json:"channel,omitempty"`
+}
+
+type PhoneCodeData struct {
+	// Channel code is sent with, delivery falls back to next configured channel.
+	// Telegram is reported whenever it was tried, so response does not reveal linked telegram account.
+	// Last digits of caller number are the code for flash
+	Channel string `json:"channel"`
 }
 
 type CheckCodeRequest struct {
-	Phone string `json:"phone"`
-	Code  int    `json:"code"`
+	Phone      string `json:"phone"`
+	Code       int    `json:"code"`
+	InviteCode string `json:"invite_code"`
 }
 
 type EmailActivationRequest struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Email      string `json:"email"`
+	Password   string `json:"password"`
+	InviteCode string `json:"invite_code"`
+	// Language of activation email and created account
+	Language int64 `json:"language,omitempty"`
 }
 
 type EmailVerificationRequest struct {
@@ -23,10 +36,22 @@ type EmailLoginRequest struct {
 	Password string `json:"password"`
 }
 
+type LoginRequest struct {
+	// Login email, phone or nickname
+	Login    string `json:"login"`
+	Password string `json:"password"`
+}
+
 type RefreshTokenRequest struct {
 	RefreshToken string `json:"refresh_token"`
+	DeviceID     string `json:"device_id"`
+}
+
+type GuestLoginRequest struct {
+	DeviceID string `json:"device_id"`
 }
 
 type StateRequest struct {
-	State string `json:"state"`
+	State      string `json:"state"`
+	InviteCode string `json:"invite_code"`
 }
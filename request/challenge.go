@@ -0,0 +1,13 @@
+package request
+
+import "time"
+
+//go:generate easytags $GOFILE
+
+type ChallengeData struct {
+	Type       string    `json:"type"`
+	Challenge  string    `json:"challenge,omitempty"`
+	Difficulty int       `json:"difficulty,omitempty"`
+	ExpiresAt  time.Time `json:"expires_at,omitempty"`
+	SiteKey    string    `json:"site_key,omitempty"`
+}
@@ -0,0 +1,28 @@
+package request
+
+import (
+	"time"
+
+	"github.com/ptflp/go-light/types"
+)
+
+//go:generate easytags $GOFILE
+
+type InviteCreateReq struct {
+	MaxUses   int64 `json:"max_uses"`
+	ExpiresIn int64 `json:"expires_in"`
+}
+
+type InviteData struct {
+	Code      types.NullString `json:"code"`
+	MaxUses   types.NullInt64  `json:"max_uses"`
+	Uses      types.NullInt64  `json:"uses"`
+	ExpiresAt types.NullTime   `json:"expires_at"`
+	CreatedAt time.Time        `json:"created_at"`
+}
+
+type ReferralData struct {
+	UserUUID   types.NullUUID `json:"user_id"`
+	InviteUUID types.NullUUID `json:"invite_id"`
+	CreatedAt  time.Time      `json:"created_at"`
+}
@@ -0,0 +1,57 @@
+package request
+
+//go:generate easytags $GOFILE
+
+type NotificationPreferenceReq struct {
+	Category string `json:"category"`
+	Enabled  bool   `json:"enabled"`
+}
+
+type NotificationPreferencesData struct {
+	Email bool `json:"email"`
+	// Telegram notifications are sent to linked chat unless disabled with notify_telegram of profile
+	Telegram       bool `json:"telegram"`
+	TelegramLinked bool `json:"telegram_linked"`
+	// Push notifications are sent to subscribed devices unless disabled with notify_push of profile
+	Push        bool `json:"push"`
+	PushDevices int  `json:"push_devices"`
+	// Categories email categories, disabled by unsubscribe links, telegram and push are sent of every category
+	Categories map[string]bool `json:"categories"`
+}
+
+type TelegramLinkData struct {
+	// Link deep link to bot, account is linked to chat where it's opened
+	Link string `json:"link"`
+}
+
+type NotifyReq struct {
+	UserID   string `json:"user_id"`
+	Category string `json:"category"`
+	Title    string `json:"title"`
+	Text     string `json:"text"`
+	Link     string `json:"link"`
+}
+
+type BouncesData struct {
+	Suppressed int `json:"suppressed"`
+}
+
+type PushKeyData struct {
+	// PublicKey vapid key, applicationServerKey of PushManager.subscribe
+	PublicKey string `json:"public_key"`
+}
+
+// PushSubscriptionReq result of PushSubscription.toJSON() of browser
+type PushSubscriptionReq struct {
+	Endpoint string   `json:"endpoint"`
+	Keys     PushKeys `json:"keys"`
+}
+
+type PushKeys struct {
+	P256dh string `json:"p256dh"`
+	Auth   string `json:"auth"`
+}
+
+type PushUnsubscribeReq struct {
+	Endpoint string `json:"endpoint"`
+}
@@ -11,7 +11,7 @@ type ProfileUpdateReq struct {
 	Cost           *float64 `json:"cost,omitempty" db:"cost" ops:"update"`
 	Trial          *bool    `json:"trial,omitempty" db:"trial" ops:"update"`
 	NotifyEmail    *bool    `json:"notify_email,omitempty" db:"notify_email" ops:"update"`
-	NotifyTelegram *bool    `json:"notify_telegram,omitempty" db:"trial" ops:"update"`
+	NotifyTelegram *bool    `json:"notify_telegram,omitempty" db:"notify_telegram" ops:"update"`
 	NotifyPush     *bool    `json:"notify_push,omitempty" db:"notify_push" ops:"update"`
 	Language       *int64   `json:"language,omitempty" db:"language" ops:"update"`
 }
@@ -20,3 +20,7 @@ type SetPasswordReq struct {
 	Password    string  `json:"password"`
 	OldPassword *string `json:"old_password"`
 }
+
+type DeleteAccountReq struct {
+	Password *string `json:"password"`
+}
@@ -0,0 +1,27 @@
+package request
+
+import "time"
+
+//go:generate easytags $GOFILE
+
+type QRChallengeData struct {
+	ChallengeID string    `json:"challenge_id"`
+	PollToken   string    `json:"poll_token"`
+	Payload     string    `json:"payload"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+type QRStatusRequest struct {
+	ChallengeID string `json:"challenge_id"`
+	PollToken   string `json:"poll_token"`
+	Wait        bool   `json:"wait"`
+}
+
+type QRStatusData struct {
+	Status string         `json:"status"`
+	Auth   *AuthTokenData `json:"auth,omitempty"`
+}
+
+type QRApproveRequest struct {
+	ChallengeID string `json:"challenge_id"`
+}
@@ -13,6 +13,12 @@ type Response struct {
 	Data    interface{} `json:"data,omitempty"`
 }
 
+type PhoneCodeResponse struct {
+	Success bool          `json:"success"`
+	Msg     string        `json:"msg"`
+	Data    PhoneCodeData `json:"data"`
+}
+
 type AuthTokenResponse struct {
 	Success bool          `json:"success"`
 	Msg     string        `json:"msg"`
@@ -27,6 +33,7 @@ type AuthTokenData struct {
 
 type UserData struct {
 	UUID           types.NullUUID    `json:"user_id" db:"uuid" ops:"create"`
+	Role           types.NullInt64   `json:"role" db:"role"`
 	Phone          types.NullString  `json:"phone" db:"phone" ops:"update,create"`
 	Email          types.NullString  `json:"email" db:"email" ops:"update,create"`
 	Avatar         types.NullString  `json:"profile_image" db:"avatar" ops:"update"`
@@ -54,6 +61,9 @@ type UserData struct {
 	PasswordSet *bool `json:"password_set,omitempty"`
 	AvatarSet   bool  `json:"profile_image_set"`
 
+	Impersonated   bool   `json:"impersonated,omitempty"`
+	ImpersonatorID string `json:"impersonator_id,omitempty"`
+
 	Counts *UserDataCounts `json:"counts,omitempty"`
 }
 
@@ -152,3 +162,16 @@ type MessageData struct {
 	ChatUUID types.NullUUID `json:"chat_id" db:"chat_uuid" ops:"create" orm_type:"binary(16)" orm_default:"null"`
 	UserUUID types.NullUUID `json:"user_id" db:"user_uuid" ops:"create" orm_type:"binary(16)" orm_default:"null"`
 }
+
+type IdentityData struct {
+	Provider string `json:"provider"`
+	ID       string `json:"id"`
+}
+
+// SessionData refresh token issued to user, ID is hash of the token
+type SessionData struct {
+	ID        string    `json:"id"`
+	Device    bool      `json:"device"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
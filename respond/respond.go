@@ -19,6 +19,7 @@ type Responder interface {
 	ErrorUnauthorized(w http.ResponseWriter, err error)
 	ErrorBadRequest(w http.ResponseWriter, err error)
 	ErrorForbidden(w http.ResponseWriter, err error)
+	ErrorTooManyRequests(w http.ResponseWriter, err error)
 	ErrorInternal(w http.ResponseWriter, err error)
 }
 
@@ -67,6 +68,19 @@ func (r *Respond) ErrorForbidden(w http.ResponseWriter, err error) {
 	}
 }
 
+func (r *Respond) ErrorTooManyRequests(w http.ResponseWriter, err error) {
+	r.log.Warn("http response too many requests", zap.Error(err))
+	w.Header().Set("Content-Type", "application/json;charset=utf-8")
+	w.WriteHeader(http.StatusTooManyRequests)
+	if err := json.NewEncoder(w).Encode(request.Response{
+		Success: false,
+		Msg:     err.Error(),
+		Data:    nil,
+	}); err != nil {
+		r.log.Error("response writer error on write", zap.Error(err))
+	}
+}
+
 func (r *Respond) ErrorUnauthorized(w http.ResponseWriter, err error) {
 	r.log.Warn("http resposne Unauthorized", zap.Error(err))
 	w.Header().Set("Content-Type", "application/json;charset=utf-8")
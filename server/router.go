@@ -1,13 +1,9 @@
 package server
 
 import (
-	"bytes"
 	"net/http"
 	"time"
 
-	"github.com/ptflp/go-light/email"
-	"github.com/ptflp/go-light/request"
-
 	"github.com/go-chi/cors"
 
 	"github.com/ptflp/go-light/components"
@@ -35,7 +31,7 @@ func NewRouter(services *services.Services, cmps components.Componenter) (*chi.M
 				AllowedOrigins: []string{"https://*", "http://*"},
 				// AllowOriginFunc:  func(r *http.Request, origin string) bool { return true },
 				AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-				AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
+				AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", middlewares.ChallengeHeader, middlewares.ChallengeSolutionHeader},
 				ExposedHeaders:   []string{"Link"},
 				AllowCredentials: false,
 				MaxAge:           300, // Maximum value not ignored by any of major browsers
@@ -45,31 +41,26 @@ func NewRouter(services *services.Services, cmps components.Componenter) (*chi.M
 
 	r.Use(middleware.RealIP)
 	r.Use(middleware.RequestID)
+	if header := cmps.Config().Phone.RegionHeader; header != "" {
+		r.Use(middlewares.NewRegion(header).Detect)
+	}
+	guard := middlewares.NewChallenge(cmps.Responder(), cmps.Challenge(), cmps.Config().Challenge.Routes)
+	r.Use(guard.Check)
 
 	authController := controllers.NewAuth(cmps.Responder(), services.AuthService, cmps.Logger())
 
-	r.Get("/test", func(w http.ResponseWriter, r *http.Request) {
-
-		msg := email.NewMessage()
-		var b bytes.Buffer
-
-		b.Write([]byte("test"))
-
-		msg.SetBody(b)
-		msg.SetReceiver("globallinkliberty@gmail.com")
-		msg.SetSubject("test")
-		_ = msg.OpenFile(".gitignore")
-		_ = msg.OpenFile(".env")
-		err := cmps.Email().Send(msg)
+	token := middlewares.NewCheckToken(cmps.Responder(), cmps.JWTKeys())
 
-		cmps.Responder().SendJSON(w, request.Response{
-			Success: err == nil,
-			Msg:     "test",
-			Data:    err,
-		})
-	})
+	// anti-enumeration: uniform response time of endpoints which could reveal account existence
+	security := cmps.Config().Security
+	timing := func(next http.Handler) http.Handler { return next }
+	if security.AntiEnumeration {
+		timing = middlewares.NewUniformTiming(security.ResponseTime()).Pad
+	}
 
-	token := middlewares.NewCheckToken(cmps.Responder(), cmps.JWTKeys())
+	// ./docs/challenge.go
+	challenges := controllers.NewChallengeController(cmps.Responder(), cmps.Challenge(), cmps.Logger())
+	r.Get("/challenge", challenges.Challenge())
 
 	r.Get("/swagger", swaggerUI)
 	r.Get("/static/*", func(w http.ResponseWriter, r *http.Request) {
@@ -80,15 +71,29 @@ func NewRouter(services *services.Services, cmps components.Componenter) (*chi.M
 	})
 
 	r.Route("/auth", func(r chi.Router) {
-		r.Post("/email/registration", authController.EmailActivation())
+		// guest token is optional, guest account is upgraded when present
+		r.Use(token.Check)
+		r.With(timing).Post("/email/registration", authController.EmailActivation())
 		r.Post("/email/verification", authController.EmailVerification())
 		r.Post("/email/login", authController.EmailLogin())
+		r.Post("/login", authController.Login())
 		r.Post("/checkemail", authController.CheckCode())
 
 		r.Post("/token/refresh", authController.RefreshToken())
+		// every guest is a users row, creation is limited per ip, challenge is required when /auth/guest is configured
+		guests := middlewares.NewRateLimit(cmps.Responder(), security.GuestRate(), time.Hour)
+		r.With(guests.LimitIP).Post("/guest", authController.GuestLogin())
 
 		r.Post("/code", authController.SendCode())
 		r.Post("/checkcode", authController.CheckCode())
+
+		r.Post("/qr", authController.QRChallenge())
+		r.Post("/qr/status", authController.QRStatus())
+		r.Group(func(r chi.Router) {
+			r.Use(token.CheckStrict)
+			r.Use(token.DenyGuests)
+			r.Post("/qr/approve", authController.QRApprove())
+		})
 	})
 
 	users := controllers.NewUsersController(cmps.Responder(), services.User, cmps.Logger())
@@ -109,19 +114,123 @@ func NewRouter(services *services.Services, cmps components.Componenter) (*chi.M
 		})
 	})
 
+	export := controllers.NewExportController(cmps.Responder(), services.Export, cmps.Logger())
+	notifications := controllers.NewNotificationsController(cmps.Responder(), services.Notifications, cmps.Logger())
+	r.Route("/profile", func(r chi.Router) {
+		r.Use(token.CheckStrict)
+		r.Get("/", users.Profile())
+		r.Group(func(r chi.Router) {
+			r.Use(token.DenyGuests)
+			// ./docs/notifications.go
+			r.Get("/notifications", notifications.Preferences())
+			r.Post("/notifications/email", notifications.SetEmailPreference())
+			r.Post("/notifications/telegram/unlink", notifications.TelegramUnlink())
+			r.Post("/notifications/push/unsubscribe", notifications.PushUnsubscribe())
+			r.Group(func(r chi.Router) {
+				r.Use(token.DenyImpersonation)
+				r.Post("/password", users.SetPassword())
+				r.Post("/delete", users.Delete())
+				r.Post("/export", export.Request())
+				r.Post("/notifications/telegram/link", notifications.TelegramLink())
+				r.Post("/notifications/push/subscribe", notifications.PushSubscribe())
+			})
+		})
+	})
+	r.With(token.CheckStrict).Get("/export/{exportID}", export.Download())
+	// ./docs/sms.go
+	if cmps.SMSTracker() != nil {
+		sms := controllers.NewSMSController(cmps.Responder(), cmps.SMSTracker(), cmps.Logger())
+		r.Post("/sms/status/{provider}", sms.Status())
+	}
+	r.Route("/email", func(r chi.Router) {
+		r.Get("/unsubscribe", notifications.UnsubscribePage())
+		r.Post("/unsubscribe", notifications.Unsubscribe())
+		r.Post("/bounces", notifications.Bounces())
+	})
+	r.Post("/telegram/webhook", notifications.TelegramWebhook())
+	r.Get("/push/key", notifications.PushKey())
+
+	invites := controllers.NewInvitesController(cmps.Responder(), services.Invite, cmps.Logger())
+	r.Route("/invites", func(r chi.Router) {
+		r.Use(token.CheckStrict)
+		r.Use(token.DenyGuests)
+		r.With(token.DenyImpersonation).Post("/create", invites.Create())
+		r.Post("/list", invites.List())
+		r.Post("/referrals", invites.Referrals())
+	})
+
+	oauth := controllers.NewOIDCController(cmps.Responder(), services.OIDC, cmps.Logger())
+	r.Get("/.well-known/openid-configuration", oauth.Discovery())
+	r.Route("/oauth", func(r chi.Router) {
+		r.Get("/jwks", oauth.JWKS())
+		r.Get("/authorize", oauth.Authorize())
+		r.Post("/token", oauth.Token())
+		r.Get("/userinfo", oauth.UserInfo())
+		r.Post("/userinfo", oauth.UserInfo())
+		r.Group(func(r chi.Router) {
+			r.Use(token.CheckStrict)
+			r.Use(token.DenyGuests)
+			r.Post("/consent/data", oauth.ConsentData())
+			r.With(token.DenyImpersonation).Post("/consent", oauth.Consent())
+			r.With(token.DenyImpersonation).Post("/clients/create", oauth.RegisterClient())
+			r.Post("/clients/list", oauth.ListClients())
+		})
+	})
+
+	admin := controllers.NewAdminController(cmps.Responder(), services.Admin, cmps.Logger())
+	r.Route("/admin", func(r chi.Router) {
+		r.Use(token.CheckStrict)
+		r.Use(token.DenyGuests)
+		r.Post("/impersonate", admin.Impersonate())
+		r.Post("/audit", admin.Audit())
+		r.Post("/outbox", admin.Outbox())
+		r.Post("/sms", admin.SMS())
+		r.Post("/notify", admin.Notify())
+	})
+
 	r.Route("/recover", func(r chi.Router) {
-		r.Post("/password", users.RecoverPassword())
+		r.With(timing).Post("/password", users.RecoverPassword())
 		r.Post("/check/phone", users.CheckPhoneCode())
 		r.Post("/set/password", users.PasswordReset())
 	})
 
 	r.Route("/exist", func(r chi.Router) {
+		if security.AntiEnumeration {
+			limiter := middlewares.NewRateLimit(cmps.Responder(), security.ExistenceRate(), time.Minute)
+			r.Use(token.CheckStrict, limiter.Limit)
+		}
+		r.Use(timing)
 		r.Post("/email", users.EmailExist())
 		r.Post("/nickname", users.NicknameExist())
 	})
+	// ./docs/templates.go
+	if cmps.Config().App.Dev {
+		templates := controllers.NewTemplatesController(cmps.Responder(), cmps.Templates(), cmps.Logger())
+		r.Route("/dev/templates", func(r chi.Router) {
+			r.Get("/", templates.List())
+			r.Get("/{name}", templates.Preview())
+		})
+	}
+
+	// ./docs/mail.go
+	if cmps.Config().App.Dev && cmps.Catcher() != nil {
+		mail := controllers.NewMailController(cmps.Responder(), cmps.Catcher(), cmps.Logger())
+		r.Route("/dev/mail", func(r chi.Router) {
+			r.Get("/", mail.Inbox())
+			r.Get("/messages", mail.List())
+			r.Post("/clear", mail.Clear())
+			r.Get("/{messageID}", mail.Message())
+			r.Get("/{messageID}/html", mail.HTML())
+			r.Get("/{messageID}/text", mail.Text())
+			r.Get("/{messageID}/raw", mail.Raw())
+			r.Get("/{messageID}/attachments/{index}", mail.Attachment())
+		})
+	}
+
 	r.Route("/system", func(r chi.Router) {
 		r.Use(middleware.Timeout(200 * time.Millisecond))
 		r.Use(token.CheckStrict)
+		r.Use(token.DenyGuests)
 		r.Get("/config", func(w http.ResponseWriter, r *http.Request) {
 			cmps.Responder().SendJSON(w, cmps.Config())
 		})
@@ -0,0 +1,218 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	light "github.com/ptflp/go-light"
+	"github.com/ptflp/go-light/components"
+	"github.com/ptflp/go-light/decoder"
+	"github.com/ptflp/go-light/request"
+	"github.com/ptflp/go-light/session"
+	"github.com/ptflp/go-light/types"
+	"go.uber.org/zap"
+)
+
+const outboxFailuresLimit = 20
+
+type Admin struct {
+	*decoder.Decoder
+	userRepository   light.UserRepository
+	auditRepository  light.AuditRepository
+	outboxRepository light.OutboxRepository
+	notifications    *Notifications
+	components.Componenter
+}
+
+func NewAdminService(rs light.Repositories, cmps components.Componenter, notifications *Notifications) *Admin {
+	return &Admin{userRepository: rs.Users, auditRepository: rs.Audit, outboxRepository: rs.Outbox, notifications: notifications, Decoder: decoder.NewDecoder(), Componenter: cmps}
+}
+
+// Impersonate issues short-lived access token for target user on behalf of admin, every call is recorded in audit log
+func (a *Admin) Impersonate(ctx context.Context, req request.ImpersonateReq, ip string) (request.ImpersonateData, error) {
+	admin, err := a.admin(ctx)
+	if err != nil {
+		return request.ImpersonateData{}, err
+	}
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" {
+		return request.ImpersonateData{}, errors.New("reason is required")
+	}
+	target := light.User{UUID: types.NewNullUUID(req.UserID)}
+	if !target.UUID.Valid {
+		return request.ImpersonateData{}, errors.New("wrong user_id")
+	}
+	target, err = a.userRepository.Find(ctx, target)
+	if err != nil {
+		return request.ImpersonateData{}, err
+	}
+	if target.DeletedAt.Valid {
+		return request.ImpersonateData{}, errors.New("user deleted")
+	}
+	if target.UUID.String == admin.UUID.String {
+		return request.ImpersonateData{}, errors.New("can't impersonate yourself")
+	}
+
+	err = a.auditRepository.Create(ctx, light.AuditEvent{
+		UUID:      types.NewNullUUID(),
+		Type:      types.NewNullInt64(types.AuditImpersonation),
+		UserUUID:  target.UUID,
+		ActorUUID: admin.UUID,
+		Reason:    types.NewNullString(reason),
+		IP:        types.NewNullString(ip),
+	})
+	if err != nil {
+		return request.ImpersonateData{}, err
+	}
+
+	accessToken, err := a.JWTKeys().CreateImpersonationToken(target, admin)
+	if err != nil {
+		return request.ImpersonateData{}, err
+	}
+
+	data := request.ImpersonateData{
+		AccessToken: accessToken,
+		ExpiresAt:   time.Now().Add(session.ImpersonationTTL),
+	}
+	err = a.MapStructs(&data.User, &target)
+	if err != nil {
+		return request.ImpersonateData{}, err
+	}
+	data.User.Impersonated = true
+	data.User.ImpersonatorID = admin.UUID.String
+
+	return data, nil
+}
+
+// Audit list of audit events where user is target or actor
+func (a *Admin) Audit(ctx context.Context, req request.AuditReq) ([]request.AuditEventData, error) {
+	_, err := a.admin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	user := light.User{UUID: types.NewNullUUID(req.UserID)}
+	if !user.UUID.Valid {
+		return nil, errors.New("wrong user_id")
+	}
+
+	events, err := a.auditRepository.FindByUser(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	eventsData := []request.AuditEventData{}
+	err = a.MapStructs(&eventsData, &events)
+
+	return eventsData, err
+}
+
+// Outbox email queue depth and last dead-lettered messages
+func (a *Admin) Outbox(ctx context.Context) (request.OutboxData, error) {
+	_, err := a.admin(ctx)
+	if err != nil {
+		return request.OutboxData{}, err
+	}
+
+	stats, err := a.outboxRepository.Stats(ctx)
+	if err != nil {
+		return request.OutboxData{}, err
+	}
+	dead, err := a.outboxRepository.ListDead(ctx, outboxFailuresLimit)
+	if err != nil {
+		return request.OutboxData{}, err
+	}
+
+	data := request.OutboxData{
+		Pending:       stats.Pending,
+		Retrying:      stats.Retrying,
+		Sending:       stats.Sending,
+		Dead:          stats.Dead,
+		OldestPending: stats.OldestPending,
+		Failures:      []request.OutboxEmailData{},
+	}
+	if len(dead) > 0 {
+		err = a.MapStructs(&data.Failures, &dead)
+	}
+
+	return data, err
+}
+
+// SMS health and cost of sms providers since start
+func (a *Admin) SMS(ctx context.Context) ([]request.SMSProviderData, error) {
+	_, err := a.admin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := a.SMSGateway().Stats()
+	providersData := make([]request.SMSProviderData, 0, len(stats))
+	for i := range stats {
+		providerData := request.SMSProviderData{
+			Name:      stats[i].Name,
+			Healthy:   stats[i].Healthy,
+			Sent:      stats[i].Sent,
+			Failed:    stats[i].Failed,
+			Cost:      stats[i].Cost,
+			LastError: stats[i].LastError,
+		}
+		balance, ok, err := a.SMSGateway().Balance(ctx, stats[i].Name)
+		if err != nil {
+			a.Logger().Warn("sms balance", zap.String("provider", stats[i].Name), zap.Error(err))
+		}
+		if ok && err == nil {
+			providerData.Balance = &balance.Balance
+			providerData.Currency = balance.Currency
+		}
+		providersData = append(providersData, providerData)
+	}
+
+	return providersData, nil
+}
+
+// Notify sends notification to user, user preferences are honored
+func (a *Admin) Notify(ctx context.Context, req request.NotifyReq) error {
+	_, err := a.admin(ctx)
+	if err != nil {
+		return err
+	}
+	if req.Title == "" || req.Text == "" {
+		return errors.New("title and text are required")
+	}
+	target := light.User{UUID: types.NewNullUUID(req.UserID)}
+	if !target.UUID.Valid {
+		return errors.New("wrong user_id")
+	}
+	target, err = a.userRepository.Find(ctx, target)
+	if err != nil {
+		return err
+	}
+
+	return a.notifications.Notify(ctx, target, Notification{
+		Category: req.Category,
+		Title:    req.Title,
+		Text:     req.Text,
+		Link:     req.Link,
+	})
+}
+
+func (a *Admin) admin(ctx context.Context) (light.User, error) {
+	user, err := extractUser(ctx)
+	if err != nil {
+		return light.User{}, err
+	}
+	// admin privileges are not delegated via impersonation
+	if user.Impersonated() {
+		return light.User{}, light.ErrImpersonated
+	}
+	user, err = a.userRepository.Find(ctx, user)
+	if err != nil {
+		return light.User{}, err
+	}
+	if !user.IsAdmin() {
+		return light.User{}, errors.New("admin role required")
+	}
+
+	return user, nil
+}
@@ -0,0 +1,261 @@
+package services
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	light "github.com/ptflp/go-light"
+	"github.com/ptflp/go-light/components"
+	"github.com/ptflp/go-light/decoder"
+	"github.com/ptflp/go-light/email"
+	"github.com/ptflp/go-light/request"
+	"go.uber.org/zap"
+)
+
+const (
+	ExportFileKey    = "export:file:%s"
+	ExportPendingKey = "export:pending:%s"
+
+	exportDir      = "./exports"
+	exportTTL      = 24 * time.Hour
+	exportInterval = 24 * time.Hour
+	// exportIDLength random bytes of export id, id is a part of download link
+	exportIDLength = 32
+)
+
+// exportFields columns exported per table, tables not listed are skipped.
+// Secrets (client secret hashes, push keys) and data of other users (admin ip and reason in audit) are left out
+var exportFields = map[string][]string{
+	light.Invite{}.TableName():           {"uuid", "code", "max_uses", "uses", "expires_at", "created_at", "deleted_at"},
+	light.InviteUse{}.TableName():        {"uuid", "invite_uuid", "created_at"},
+	light.OAuthClient{}.TableName():      {"uuid", "client_id", "name", "redirect_uris", "public", "created_at", "deleted_at"},
+	light.OAuthConsent{}.TableName():     {"uuid", "client_uuid", "scope", "created_at", "updated_at"},
+	light.AuditEvent{}.TableName():       {"uuid", "type", "created_at"},
+	light.EmailUnsubscribe{}.TableName(): {"category", "created_at"},
+	light.PushSubscription{}.TableName(): {"uuid", "user_agent", "created_at"},
+}
+
+// exportFile archive of user, only owner is allowed to download it
+type exportFile struct {
+	Path string `json:"path"`
+	UUID string `json:"uuid"`
+}
+
+type Export struct {
+	*decoder.Decoder
+	userRepository   light.UserRepository
+	exportRepository light.ExportRepository
+	components.Componenter
+}
+
+func NewExportService(rs light.Repositories, cmps components.Componenter) *Export {
+	return &Export{userRepository: rs.Users, exportRepository: rs.Exports, Decoder: decoder.NewDecoder(), Componenter: cmps}
+}
+
+// Request starts assembling of user data archive, user is notified by email when it's ready
+func (e *Export) Request(ctx context.Context) error {
+	user, err := extractUser(ctx)
+	if err != nil {
+		return err
+	}
+	user, err = e.userRepository.Find(ctx, user)
+	if err != nil {
+		return err
+	}
+	if !user.Email.Valid {
+		return errors.New("email is required to receive data export")
+	}
+
+	var requestedAt int64
+	key := fmt.Sprintf(ExportPendingKey, user.UUID.String)
+	if err = e.Cache().Get(key, &requestedAt); err == nil {
+		return errors.New("data export already requested, try again later")
+	}
+	requestedAt = time.Now().Unix()
+	e.Cache().Set(key, &requestedAt, exportInterval)
+
+	go e.build(context.Background(), user)
+
+	return nil
+}
+
+// Download returns path of archive by export id, archive of another user is not found
+func (e *Export) Download(ctx context.Context, exportID string) (string, error) {
+	user, err := extractUser(ctx)
+	if err != nil {
+		return "", err
+	}
+	var file exportFile
+	err = e.Cache().Get(fmt.Sprintf(ExportFileKey, exportID), &file)
+	if err != nil || file.UUID != user.UUID.String {
+		return "", errors.New("export not found or expired")
+	}
+
+	return file.Path, nil
+}
+
+func (e *Export) build(ctx context.Context, user light.User) {
+	e.cleanup()
+
+	pendingKey := fmt.Sprintf(ExportPendingKey, user.UUID.String)
+	b := make([]byte, exportIDLength)
+	_, err := rand.Read(b)
+	if err != nil {
+		_ = e.Cache().Del(pendingKey)
+		e.Logger().Error("export id generation", zap.Error(err))
+		return
+	}
+	exportID := hex.EncodeToString(b)
+
+	path, err := e.writeArchive(ctx, exportID, user)
+	if err != nil {
+		// user is allowed to request export again
+		_ = e.Cache().Del(pendingKey)
+		e.Logger().Error("export archive", zap.String("uuid", user.UUID.String), zap.Error(err))
+		return
+	}
+	e.Cache().Set(fmt.Sprintf(ExportFileKey, exportID), &exportFile{Path: path, UUID: user.UUID.String}, exportTTL)
+
+	err = e.notify(user, exportID)
+	if err != nil {
+		e.Logger().Error("export notification", zap.String("uuid", user.UUID.String), zap.Error(err))
+	}
+}
+
+// writeArchive partially written archive is removed on error
+func (e *Export) writeArchive(ctx context.Context, exportID string, user light.User) (string, error) {
+	err := os.MkdirAll(exportDir, 0700)
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(exportDir, exportID+".zip")
+	fl, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return "", err
+	}
+
+	err = e.writeEntries(ctx, zip.NewWriter(fl), user)
+	if cerr := fl.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		_ = os.Remove(path)
+		return "", err
+	}
+
+	return path, nil
+}
+
+func (e *Export) writeEntries(ctx context.Context, zw *zip.Writer, user light.User) error {
+	var userData request.UserData
+	err := e.MapStructs(&userData, &user)
+	if err != nil {
+		return err
+	}
+	err = e.writeJSON(zw, "user.json", userData)
+	if err != nil {
+		return err
+	}
+
+	identities := make([]request.IdentityData, 0, 2)
+	if user.FacebookID.Valid {
+		identities = append(identities, request.IdentityData{Provider: "facebook", ID: fmt.Sprintf("%d", user.FacebookID.Int64)})
+	}
+	if user.GoogleID.Valid {
+		identities = append(identities, request.IdentityData{Provider: "google", ID: user.GoogleID.String})
+	}
+	err = e.writeJSON(zw, "identities.json", identities)
+	if err != nil {
+		return err
+	}
+
+	sessions := e.JWTKeys().Sessions(user.UUID.String)
+	if sessions == nil {
+		sessions = []request.SessionData{}
+	}
+	err = e.writeJSON(zw, "sessions.json", sessions)
+	if err != nil {
+		return err
+	}
+
+	for name, table := range light.GetTables() {
+		fields, ok := exportFields[name]
+		if !ok {
+			continue
+		}
+		var rows []map[string]interface{}
+		rows, err = e.exportRepository.FindByUser(ctx, table, fields, user)
+		if err != nil {
+			return err
+		}
+		if rows == nil {
+			continue
+		}
+		err = e.writeJSON(zw, name+".json", rows)
+		if err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
+
+func (e *Export) writeJSON(zw *zip.Writer, name string, value interface{}) error {
+	w, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+
+	return e.Encode(w, value)
+}
+
+// notify links frontend page, it downloads archive with access token of user
+func (e *Export) notify(user light.User, exportID string) error {
+	uri, err := url.Parse(e.Config().App.FrontEnd)
+	if err != nil {
+		return err
+	}
+	uri.Path = path.Join(uri.Path, "export", exportID)
+
+	msg, err := e.Templates().Message(email.TemplateExport, user.Language.Int64, struct {
+		Link    string
+		Expires string
+	}{
+		Link:    uri.String(),
+		Expires: time.Now().Add(exportTTL).Format("02.01.2006 15:04"),
+	})
+	if err != nil {
+		return err
+	}
+	msg.SetReceiver(user.Email.String)
+	msg.SetIdempotencyKey("export:" + exportID)
+
+	return e.Email().Send(msg)
+}
+
+// cleanup removes expired archives
+func (e *Export) cleanup() {
+	files, err := ioutil.ReadDir(exportDir)
+	if err != nil {
+		return
+	}
+	for i := range files {
+		if time.Since(files[i].ModTime()) < exportTTL {
+			continue
+		}
+		err = os.Remove(filepath.Join(exportDir, files[i].Name()))
+		if err != nil {
+			e.Logger().Error("export cleanup", zap.Error(err))
+		}
+	}
+}
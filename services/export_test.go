@@ -0,0 +1,34 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	light "github.com/ptflp/go-light"
+)
+
+func TestExportFields(t *testing.T) {
+	tables := light.GetTables()
+	for name, fields := range exportFields {
+		table, ok := tables[name]
+		if !ok {
+			t.Errorf("exported table %s not registered", name)
+			continue
+		}
+		hidden := make(map[string]bool)
+		entity := reflect.TypeOf(table.Entity)
+		for i := 0; i < entity.NumField(); i++ {
+			if entity.Field(i).Tag.Get("json") == "-" {
+				hidden[entity.Field(i).Tag.Get("db")] = true
+			}
+		}
+		for _, field := range fields {
+			if _, ok = table.FieldsMap[field]; !ok {
+				t.Errorf("exported field %s.%s not exist", name, field)
+			}
+			if hidden[field] {
+				t.Errorf("exported field %s.%s is hidden from json", name, field)
+			}
+		}
+	}
+}
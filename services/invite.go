@@ -0,0 +1,195 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	light "github.com/ptflp/go-light"
+	"github.com/ptflp/go-light/components"
+	"github.com/ptflp/go-light/decoder"
+	"github.com/ptflp/go-light/request"
+	"github.com/ptflp/go-light/types"
+)
+
+const (
+	inviteCodeLength = 10
+	defaultInviteTTL = 7 * 24
+)
+
+type Invite struct {
+	*decoder.Decoder
+	userRepository   light.UserRepository
+	inviteRepository light.InviteRepository
+	components.Componenter
+}
+
+func NewInviteService(rs light.Repositories, cmps components.Componenter) *Invite {
+	return &Invite{userRepository: rs.Users, inviteRepository: rs.Invites, Decoder: decoder.NewDecoder(), Componenter: cmps}
+}
+
+// Required reports whether registration is invite-only
+func (i *Invite) Required() bool {
+	return i.Config().Invites.Required
+}
+
+// Check validates invite code without redeeming it
+func (i *Invite) Check(ctx context.Context, code string) error {
+	_, err := i.find(ctx, code)
+
+	return err
+}
+
+func (i *Invite) Redeem(ctx context.Context, code string, invitee light.User) error {
+	invite, err := i.find(ctx, code)
+	if err != nil {
+		return err
+	}
+
+	return i.inviteRepository.Redeem(ctx, invite, i.inviteUse(invite, invitee), invitee)
+}
+
+func (i *Invite) RedeemGuest(ctx context.Context, code string, identity light.User) error {
+	invite, err := i.find(ctx, code)
+	if err != nil {
+		return err
+	}
+
+	return i.inviteRepository.RedeemGuest(ctx, invite, i.inviteUse(invite, identity), identity)
+}
+
+func (i *Invite) inviteUse(invite light.Invite, invitee light.User) light.InviteUse {
+	return light.InviteUse{
+		UUID:        types.NewNullUUID(),
+		InviteUUID:  invite.UUID,
+		InviterUUID: invite.UserUUID,
+		UserUUID:    invitee.UUID,
+	}
+}
+
+func (i *Invite) Create(ctx context.Context, req request.InviteCreateReq) (request.InviteData, error) {
+	user, err := extractUser(ctx)
+	if err != nil {
+		return request.InviteData{}, err
+	}
+	user, err = i.userRepository.Find(ctx, user)
+	if err != nil {
+		return request.InviteData{}, err
+	}
+
+	ttl := int64(i.Config().Invites.TTL)
+	if ttl <= 0 {
+		ttl = defaultInviteTTL
+	}
+	maxUses := int64(1)
+
+	if user.IsAdmin() {
+		if req.MaxUses > 0 {
+			maxUses = req.MaxUses
+		}
+		ttl = req.ExpiresIn
+	} else {
+		count, err := i.inviteRepository.CountByUser(ctx, user)
+		if err != nil {
+			return request.InviteData{}, err
+		}
+		if count >= int64(i.Config().Invites.Quota) {
+			return request.InviteData{}, errors.New("invites quota exceeded")
+		}
+	}
+
+	code, err := genInviteCode()
+	if err != nil {
+		return request.InviteData{}, err
+	}
+	invite := light.Invite{
+		UUID:     types.NewNullUUID(),
+		Code:     types.NewNullString(code),
+		UserUUID: user.UUID,
+		MaxUses:  types.NewNullInt64(maxUses),
+	}
+	// admin invites without expiration when expires_in not set
+	if ttl > 0 {
+		invite.ExpiresAt.SetValid(time.Now().Add(time.Duration(ttl) * time.Hour))
+	}
+
+	err = i.inviteRepository.Create(ctx, invite)
+	if err != nil {
+		return request.InviteData{}, err
+	}
+
+	invite, err = i.inviteRepository.FindByCode(ctx, invite)
+	if err != nil {
+		return request.InviteData{}, err
+	}
+
+	var inviteData request.InviteData
+	err = i.MapStructs(&inviteData, &invite)
+
+	return inviteData, err
+}
+
+func (i *Invite) List(ctx context.Context) ([]request.InviteData, error) {
+	user, err := extractUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	invites, err := i.inviteRepository.ListByUser(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	invitesData := []request.InviteData{}
+	err = i.MapStructs(&invitesData, &invites)
+
+	return invitesData, err
+}
+
+func (i *Invite) Referrals(ctx context.Context) ([]request.ReferralData, error) {
+	user, err := extractUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	referrals, err := i.inviteRepository.Referrals(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	referralsData := []request.ReferralData{}
+	err = i.MapStructs(&referralsData, &referrals)
+
+	return referralsData, err
+}
+
+func (i *Invite) find(ctx context.Context, code string) (light.Invite, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if code == "" {
+		return light.Invite{}, errors.New("invite code is required")
+	}
+	invite, err := i.inviteRepository.FindByCode(ctx, light.Invite{Code: types.NewNullString(code)})
+	if err != nil {
+		return light.Invite{}, fmt.Errorf("invalid invite code %s", code)
+	}
+	if invite.ExpiresAt.Valid && invite.ExpiresAt.Time.Time.Before(time.Now()) {
+		return light.Invite{}, errors.New("invite code expired")
+	}
+	if invite.Uses.Int64 >= invite.MaxUses.Int64 {
+		return light.Invite{}, errors.New("invite code exhausted")
+	}
+
+	return invite, nil
+}
+
+func genInviteCode() (string, error) {
+	b := make([]byte, inviteCodeLength)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	return base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b)[:inviteCodeLength], nil
+}
@@ -0,0 +1,89 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	light "github.com/ptflp/go-light"
+	"github.com/ptflp/go-light/types"
+	"github.com/volatiletech/null/v8"
+)
+
+type testInviteRepository struct {
+	light.InviteRepository
+	invites  map[string]light.Invite
+	uses     []light.InviteUse
+	invitees []light.User
+}
+
+func (r *testInviteRepository) FindByCode(ctx context.Context, invite light.Invite) (light.Invite, error) {
+	found, ok := r.invites[invite.Code.String]
+	if !ok {
+		return light.Invite{}, sql.ErrNoRows
+	}
+
+	return found, nil
+}
+
+func (r *testInviteRepository) Redeem(ctx context.Context, invite light.Invite, inviteUse light.InviteUse, invitee light.User) error {
+	r.uses = append(r.uses, inviteUse)
+	r.invitees = append(r.invitees, invitee)
+
+	return nil
+}
+
+func TestInvite_Redeem(t *testing.T) {
+	inviter := types.NewNullUUID()
+	invite := light.Invite{
+		UUID:     types.NewNullUUID(),
+		Code:     types.NewNullString("ABCDEFGHIJ"),
+		UserUUID: inviter,
+		MaxUses:  types.NewNullInt64(2),
+		Uses:     types.NewNullInt64(1),
+	}
+	expired := light.Invite{
+		UUID:      types.NewNullUUID(),
+		Code:      types.NewNullString("EXPIRED000"),
+		MaxUses:   types.NewNullInt64(1),
+		ExpiresAt: types.NullTime{Time: null.TimeFrom(time.Now().Add(-time.Hour))},
+	}
+	exhausted := light.Invite{
+		UUID:    types.NewNullUUID(),
+		Code:    types.NewNullString("EXHAUSTED0"),
+		MaxUses: types.NewNullInt64(1),
+		Uses:    types.NewNullInt64(1),
+	}
+	repo := &testInviteRepository{invites: map[string]light.Invite{
+		invite.Code.String:    invite,
+		expired.Code.String:   expired,
+		exhausted.Code.String: exhausted,
+	}}
+	i := &Invite{inviteRepository: repo}
+	ctx := context.Background()
+
+	for _, code := range []string{"", "unknown", expired.Code.String, exhausted.Code.String} {
+		if err := i.Redeem(ctx, code, light.User{UUID: types.NewNullUUID()}); err == nil {
+			t.Errorf("Redeem(%q) error is nil", code)
+		}
+	}
+	if len(repo.uses) != 0 {
+		t.Fatalf("invalid invite is used: %+v", repo.uses)
+	}
+
+	invitee := light.User{UUID: types.NewNullUUID()}
+	if err := i.Redeem(ctx, " abcdefghij ", invitee); err != nil {
+		t.Fatalf("Redeem() error = %v", err)
+	}
+	if len(repo.uses) != 1 || len(repo.invitees) != 1 {
+		t.Fatalf("uses = %+v", repo.uses)
+	}
+	use := repo.uses[0]
+	if !use.UUID.Valid || use.InviteUUID.String != invite.UUID.String || use.InviterUUID.String != inviter.String || use.UserUUID.String != invitee.UUID.String {
+		t.Errorf("invite use = %+v", use)
+	}
+	if repo.invitees[0].UUID.String != invitee.UUID.String {
+		t.Errorf("invitee = %+v", repo.invitees[0])
+	}
+}
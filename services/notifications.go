@@ -0,0 +1,290 @@
+package services
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+
+	light "github.com/ptflp/go-light"
+	"github.com/ptflp/go-light/components"
+	"github.com/ptflp/go-light/decoder"
+	"github.com/ptflp/go-light/email"
+	"github.com/ptflp/go-light/request"
+	"github.com/ptflp/go-light/types"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrNotificationDisabled = errors.New("notifications of category are disabled by user")
+	ErrBounceSecret         = errors.New("wrong bounce secret")
+)
+
+// Notification message of notification category
+type Notification struct {
+	Category string
+	Title    string
+	Text     string
+	Link     string
+}
+
+type Notifications struct {
+	*decoder.Decoder
+	userRepository        light.UserRepository
+	suppressionRepository light.SuppressionRepository
+	pushRepository        light.PushRepository
+	signer                *email.UnsubscribeSigner
+	components.Componenter
+}
+
+func NewNotificationsService(rs light.Repositories, cmps components.Componenter) *Notifications {
+	secret := cmps.Config().Email.UnsubscribeSecret
+	signer, err := email.NewUnsubscribeSigner(secret)
+	if err != nil {
+		cmps.Logger().Fatal("unsubscribe signer initialization error", zap.Error(err))
+	}
+	if secret == "" {
+		cmps.Logger().Warn("unsubscribe secret is not set, unsubscribe links are valid for current instance only")
+	}
+
+	return &Notifications{
+		userRepository:        rs.Users,
+		suppressionRepository: rs.Suppressions,
+		pushRepository:        rs.Push,
+		signer:                signer,
+		Decoder:               decoder.NewDecoder(),
+		Componenter:           cmps,
+	}
+}
+
+// Notify sends notification to email, linked telegram chat and push subscriptions unless user disabled channel,
+// email is not sent of category user unsubscribed from
+func (n *Notifications) Notify(ctx context.Context, user light.User, notification Notification) error {
+	if notification.Category == types.NotifyAll || !validCategory(notification.Category) {
+		return fmt.Errorf("wrong notification category %q", notification.Category)
+	}
+	preferences, err := n.preferences(ctx, user)
+	if err != nil {
+		return err
+	}
+
+	var channels int
+	var errs []string
+	// categories are unsubscribed by email links, telegram and push are not affected
+	if preferences.Email && preferences.Categories[notification.Category] && user.Email.Valid {
+		channels++
+		if err = n.notifyEmail(user, notification); err != nil {
+			errs = append(errs, fmt.Sprintf("email: %s", err))
+		}
+	}
+	if preferences.Telegram {
+		channels++
+		if err = n.notifyTelegram(ctx, user, notification); err != nil {
+			errs = append(errs, fmt.Sprintf("telegram: %s", err))
+		}
+	}
+	if preferences.Push && preferences.PushDevices > 0 {
+		channels++
+		if err = n.notifyPush(ctx, user, notification); err != nil {
+			errs = append(errs, fmt.Sprintf("push: %s", err))
+		}
+	}
+	if channels == 0 && !user.Email.Valid && !user.TelegramID.Valid && preferences.PushDevices == 0 {
+		return errors.New("user has no email, telegram or push subscriptions")
+	}
+	if channels == 0 {
+		return ErrNotificationDisabled
+	}
+	// notification is delivered when at least one channel succeeded
+	if len(errs) == channels {
+		return fmt.Errorf("notification not sent: %s", strings.Join(errs, "; "))
+	}
+	if len(errs) > 0 {
+		n.Logger().Warn("notification channel failed", zap.String("uuid", user.UUID.String), zap.Strings("errors", errs))
+	}
+
+	return nil
+}
+
+func (n *Notifications) notifyEmail(user light.User, notification Notification) error {
+	link, err := n.unsubscribeLink(user, notification.Category)
+	if err != nil {
+		return err
+	}
+	msg, err := n.Templates().Message(email.TemplateNotification, user.Language.Int64, struct {
+		Title       string
+		Text        string
+		Link        string
+		Unsubscribe string
+	}{
+		Title:       notification.Title,
+		Text:        notification.Text,
+		Link:        notification.Link,
+		Unsubscribe: link,
+	})
+	if err != nil {
+		return err
+	}
+	msg.SetReceiver(user.Email.String)
+	if link != "" {
+		email.SetUnsubscribe(msg, link)
+	}
+
+	return n.Email().Send(msg)
+}
+
+// Unsubscribe disables email of category of signed unsubscribe link, all emails are disabled by types.NotifyAll
+func (n *Notifications) Unsubscribe(ctx context.Context, token string) error {
+	userID, category, err := n.signer.Parse(token)
+	if err != nil {
+		return err
+	}
+	if !validCategory(category) {
+		return email.ErrUnsubscribeToken
+	}
+	user, err := n.userRepository.Find(ctx, light.User{UUID: types.NewNullUUID(userID)})
+	if err != nil {
+		return err
+	}
+
+	return n.setPreference(ctx, user, category, false)
+}
+
+func (n *Notifications) Preferences(ctx context.Context) (request.NotificationPreferencesData, error) {
+	user, err := extractUser(ctx)
+	if err != nil {
+		return request.NotificationPreferencesData{}, err
+	}
+	user, err = n.userRepository.Find(ctx, user)
+	if err != nil {
+		return request.NotificationPreferencesData{}, err
+	}
+
+	return n.preferences(ctx, user)
+}
+
+func (n *Notifications) SetEmailPreference(ctx context.Context, req request.NotificationPreferenceReq) (request.NotificationPreferencesData, error) {
+	if !validCategory(req.Category) {
+		return request.NotificationPreferencesData{}, fmt.Errorf("wrong notification category %q", req.Category)
+	}
+	user, err := extractUser(ctx)
+	if err != nil {
+		return request.NotificationPreferencesData{}, err
+	}
+	user, err = n.userRepository.Find(ctx, user)
+	if err != nil {
+		return request.NotificationPreferencesData{}, err
+	}
+	err = n.setPreference(ctx, user, req.Category, req.Enabled)
+	if err != nil {
+		return request.NotificationPreferencesData{}, err
+	}
+	user, err = n.userRepository.Find(ctx, user)
+	if err != nil {
+		return request.NotificationPreferencesData{}, err
+	}
+
+	return n.preferences(ctx, user)
+}
+
+// Bounces adds addresses of bounce or complaint report to suppression list
+func (n *Notifications) Bounces(ctx context.Context, secret, contentType string, body []byte) (request.BouncesData, error) {
+	expected := n.Config().Email.BounceSecret
+	if expected == "" || subtle.ConstantTimeCompare([]byte(secret), []byte(expected)) != 1 {
+		return request.BouncesData{}, ErrBounceSecret
+	}
+	bounces, err := email.ParseReport(contentType, body)
+	if err != nil {
+		return request.BouncesData{}, err
+	}
+
+	for i := range bounces {
+		err = n.suppressionRepository.Suppress(ctx, light.EmailSuppression{
+			UUID:    types.NewNullUUID(),
+			Email:   types.NewNullString(bounces[i].Email),
+			Reason:  types.NewNullInt64(bounces[i].Reason()),
+			Details: types.NewNullString(bounces[i].Details),
+		})
+		if err != nil {
+			return request.BouncesData{}, err
+		}
+		n.Logger().Info("email address suppressed", zap.String("email", bounces[i].Email), zap.String("type", bounces[i].Type))
+	}
+
+	return request.BouncesData{Suppressed: len(bounces)}, nil
+}
+
+// preferences notifications of every channel are enabled unless user disabled them
+func (n *Notifications) preferences(ctx context.Context, user light.User) (request.NotificationPreferencesData, error) {
+	unsubscribed, err := n.suppressionRepository.Unsubscribed(ctx, user)
+	if err != nil {
+		return request.NotificationPreferencesData{}, err
+	}
+	subscriptions, err := n.pushRepository.ListByUser(ctx, user)
+	if err != nil {
+		return request.NotificationPreferencesData{}, err
+	}
+
+	data := request.NotificationPreferencesData{
+		Email:          !user.NotifyEmail.Valid || user.NotifyEmail.Bool,
+		Telegram:       user.TelegramID.Valid && (!user.NotifyTelegram.Valid || user.NotifyTelegram.Bool),
+		TelegramLinked: user.TelegramID.Valid,
+		Push:           !user.NotifyPush.Valid || user.NotifyPush.Bool,
+		PushDevices:    len(subscriptions),
+		Categories:     make(map[string]bool),
+	}
+	for _, category := range types.NotifyCategories {
+		if category != types.NotifyAll {
+			data.Categories[category] = true
+		}
+	}
+	for i := range unsubscribed {
+		data.Categories[unsubscribed[i]] = false
+	}
+
+	return data, nil
+}
+
+func (n *Notifications) setPreference(ctx context.Context, user light.User, category string, enabled bool) error {
+	if category == types.NotifyAll {
+		user.NotifyEmail = types.NewNullBool(enabled)
+		return n.userRepository.Update(ctx, user)
+	}
+	if enabled {
+		return n.suppressionRepository.Resubscribe(ctx, user, category)
+	}
+
+	return n.suppressionRepository.Unsubscribe(ctx, light.EmailUnsubscribe{
+		UUID:     types.NewNullUUID(),
+		UserUUID: user.UUID,
+		Category: types.NewNullString(category),
+	})
+}
+
+// unsubscribeLink one-click POST is handled by api, link is not added until public api url is configured
+func (n *Notifications) unsubscribeLink(user light.User, category string) (string, error) {
+	if n.Config().App.API == "" {
+		return "", nil
+	}
+	uri, err := url.Parse(n.Config().App.API)
+	if err != nil {
+		return "", err
+	}
+	uri.Path = path.Join(uri.Path, "email/unsubscribe")
+	uri.RawQuery = url.Values{"token": {n.signer.Token(user.UUID.String, category)}}.Encode()
+
+	return uri.String(), nil
+}
+
+func validCategory(category string) bool {
+	for i := range types.NotifyCategories {
+		if types.NotifyCategories[i] == category {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,90 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	light "github.com/ptflp/go-light"
+	"github.com/ptflp/go-light/components/componentstest"
+	"github.com/ptflp/go-light/config"
+	"github.com/ptflp/go-light/email"
+	"github.com/ptflp/go-light/providers"
+	"github.com/ptflp/go-light/types"
+)
+
+func TestNotifications_UnsubscribeLink(t *testing.T) {
+	signer, err := email.NewUnsubscribeSigner("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf := &config.Config{App: config.App{FrontEnd: "https://example.com"}}
+	n := &Notifications{Componenter: componentstest.New(t, conf), signer: signer}
+	user := light.User{UUID: types.NewNullUUID()}
+
+	// one-click POST is not handled by frontend
+	if link, err := n.unsubscribeLink(user, types.NotifyAll); err != nil || link != "" {
+		t.Errorf("unsubscribeLink() without api url = %q, %v", link, err)
+	}
+
+	conf.App.API = "https://api.example.com/v1"
+	link, err := n.unsubscribeLink(user, types.NotifyAll)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uri, err := url.Parse(link)
+	if err != nil || uri.Host != "api.example.com" || uri.Path != "/v1/email/unsubscribe" {
+		t.Fatalf("unsubscribeLink() = %q, %v", link, err)
+	}
+	userID, category, err := signer.Parse(uri.Query().Get("token"))
+	if err != nil || userID != user.UUID.String || category != types.NotifyAll {
+		t.Errorf("unsubscribe token = %s, %s, %v", userID, category, err)
+	}
+}
+
+type testSuppressionRepository struct {
+	light.SuppressionRepository
+	unsubscribed []string
+}
+
+func (r *testSuppressionRepository) Unsubscribed(ctx context.Context, user light.User) ([]string, error) {
+	return r.unsubscribed, nil
+}
+
+func TestNotifications_NotifyUnsubscribed(t *testing.T) {
+	var sent int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sent++
+		_, _ = w.Write([]byte(`{"ok":true,"result":{}}`))
+	}))
+	defer server.Close()
+
+	cmps := componentstest.New(t, nil)
+	cmps.SetTelegram(providers.NewTelegram(&config.Telegram{Token: "token", URL: server.URL}))
+	n := &Notifications{
+		Componenter:           cmps,
+		suppressionRepository: &testSuppressionRepository{unsubscribed: []string{types.NotifyNews}},
+		pushRepository:        &testPushRepository{},
+	}
+	user := light.User{
+		UUID:       types.NewNullUUID(),
+		Email:      types.NewNullString("user@example.com"),
+		TelegramID: types.NewNullInt64(1),
+	}
+
+	// email of unsubscribed category is not sent, telegram is
+	err := n.Notify(context.Background(), user, Notification{Category: types.NotifyNews, Title: "news"})
+	if err != nil || sent != 1 {
+		t.Fatalf("Notify() of unsubscribed category = %v, telegram messages %d", err, sent)
+	}
+
+	// nothing left to deliver without telegram
+	user.TelegramID = types.NullInt64{}
+	err = n.Notify(context.Background(), user, Notification{Category: types.NotifyNews, Title: "news"})
+	if !errors.Is(err, ErrNotificationDisabled) {
+		t.Errorf("Notify() of unsubscribed category by email only error = %v", err)
+	}
+}
@@ -0,0 +1,137 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	light "github.com/ptflp/go-light"
+	"github.com/ptflp/go-light/providers"
+	"github.com/ptflp/go-light/request"
+	"github.com/ptflp/go-light/types"
+	"go.uber.org/zap"
+)
+
+var ErrPushEndpoint = errors.New("wrong push endpoint")
+
+func (n *Notifications) PushKey() request.PushKeyData {
+	return request.PushKeyData{PublicKey: n.Push().PublicKey()}
+}
+
+// PushSubscribe stores subscription of device, subscription of another user is moved to current one
+func (n *Notifications) PushSubscribe(ctx context.Context, req request.PushSubscriptionReq, userAgent string) (request.NotificationPreferencesData, error) {
+	user, err := extractUser(ctx)
+	if err != nil {
+		return request.NotificationPreferencesData{}, err
+	}
+	endpoint, err := url.Parse(req.Endpoint)
+	if err != nil || endpoint.Scheme != "https" || endpoint.User != nil || !n.pushHost(endpoint.Hostname()) {
+		return request.NotificationPreferencesData{}, ErrPushEndpoint
+	}
+	if req.Keys.P256dh == "" || req.Keys.Auth == "" {
+		return request.NotificationPreferencesData{}, providers.ErrPushSubscriber
+	}
+	if len(userAgent) > 255 {
+		userAgent = userAgent[:255]
+	}
+
+	err = n.pushRepository.Save(ctx, light.PushSubscription{
+		UUID:      types.NewNullUUID(),
+		UserUUID:  user.UUID,
+		Endpoint:  types.NewNullString(req.Endpoint),
+		P256dh:    types.NewNullString(req.Keys.P256dh),
+		Auth:      types.NewNullString(req.Keys.Auth),
+		UserAgent: types.NewNullString(userAgent),
+	})
+	if err != nil {
+		return request.NotificationPreferencesData{}, err
+	}
+	user, err = n.userRepository.Find(ctx, user)
+	if err != nil {
+		return request.NotificationPreferencesData{}, err
+	}
+
+	return n.preferences(ctx, user)
+}
+
+// pushHost endpoint is sent requests by server, only known push services are allowed
+func (n *Notifications) pushHost(host string) bool {
+	host = strings.ToLower(host)
+	for _, allowed := range n.Config().Push.PushHosts() {
+		allowed = strings.ToLower(allowed)
+		if host == allowed || strings.HasSuffix(host, "."+allowed) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (n *Notifications) PushUnsubscribe(ctx context.Context, req request.PushUnsubscribeReq) (request.NotificationPreferencesData, error) {
+	user, err := extractUser(ctx)
+	if err != nil {
+		return request.NotificationPreferencesData{}, err
+	}
+	err = n.pushRepository.Delete(ctx, user, req.Endpoint)
+	if err != nil {
+		return request.NotificationPreferencesData{}, err
+	}
+	user, err = n.userRepository.Find(ctx, user)
+	if err != nil {
+		return request.NotificationPreferencesData{}, err
+	}
+
+	return n.preferences(ctx, user)
+}
+
+// notifyPush sends notification to every device of user, expired subscriptions are removed
+func (n *Notifications) notifyPush(ctx context.Context, user light.User, notification Notification) error {
+	subscriptions, err := n.pushRepository.ListByUser(ctx, user)
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(struct {
+		Category string `json:"category"`
+		Title    string `json:"title"`
+		Body     string `json:"body"`
+		URL      string `json:"url,omitempty"`
+	}{
+		Category: notification.Category,
+		Title:    notification.Title,
+		Body:     notification.Text,
+		URL:      notification.Link,
+	})
+	if err != nil {
+		return err
+	}
+
+	var delivered int
+	for i := range subscriptions {
+		endpoint := subscriptions[i].Endpoint.String
+		err = n.Push().Send(ctx, providers.PushSubscription{
+			Endpoint: endpoint,
+			P256dh:   subscriptions[i].P256dh.String,
+			Auth:     subscriptions[i].Auth.String,
+		}, payload)
+		if errors.Is(err, providers.ErrPushGone) || errors.Is(err, providers.ErrPushSubscriber) {
+			if deleteErr := n.pushRepository.Delete(ctx, light.User{}, endpoint); deleteErr != nil {
+				n.Logger().Error("push subscription delete", zap.Error(deleteErr))
+			}
+			n.Logger().Info("push subscription removed", zap.String("uuid", user.UUID.String), zap.Error(err))
+			continue
+		}
+		if err != nil {
+			n.Logger().Warn("push delivery", zap.String("uuid", user.UUID.String), zap.Error(err))
+			continue
+		}
+		delivered++
+	}
+	if delivered == 0 {
+		return fmt.Errorf("push notification is not delivered to %d devices", len(subscriptions))
+	}
+
+	return nil
+}
@@ -6,20 +6,37 @@ import (
 	light "github.com/ptflp/go-light"
 	"github.com/ptflp/go-light/auth"
 	"github.com/ptflp/go-light/components"
+	"github.com/ptflp/go-light/oidc"
 )
 
 type Services struct {
 	AuthService light.AuthService
+	OIDC        light.OIDCService
 	// TODO change to interface
-	User *User
+	User   *User
+	Export *Export
+	Invite *Invite
+	Admin  *Admin
+	// Notifications email notification preferences and suppression list
+	Notifications *Notifications
 }
 
 func NewServices(ctx context.Context, cmps components.Componenter, reps light.Repositories) *Services {
 	var services Services
 	user := NewUserService(reps, cmps)
 	services.User = user
+	go user.PurgeDeleted(ctx)
 
-	services.AuthService = auth.NewAuthService(reps, cmps)
+	services.Export = NewExportService(reps, cmps)
+
+	invite := NewInviteService(reps, cmps)
+	services.Invite = invite
+
+	services.Notifications = NewNotificationsService(reps, cmps)
+	services.Admin = NewAdminService(reps, cmps, services.Notifications)
+
+	services.AuthService = auth.NewAuthService(reps, cmps, invite)
+	services.OIDC = oidc.NewOIDCService(reps, cmps)
 
 	return &services
 }
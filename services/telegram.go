@@ -0,0 +1,168 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	light "github.com/ptflp/go-light"
+	"github.com/ptflp/go-light/providers"
+	"github.com/ptflp/go-light/request"
+	"github.com/ptflp/go-light/types"
+	"go.uber.org/zap"
+)
+
+const (
+	TelegramLinkKey = "telegram:link:%s"
+	telegramLinkTTL = 15 * time.Minute
+)
+
+var ErrTelegramSecret = errors.New("wrong telegram webhook secret")
+
+// TelegramLink returns deep link to bot, chat where /start command of link is sent is linked to user
+func (n *Notifications) TelegramLink(ctx context.Context) (request.TelegramLinkData, error) {
+	if !n.Telegram().Enabled() {
+		return request.TelegramLinkData{}, providers.ErrTelegramDisabled
+	}
+	user, err := extractUser(ctx)
+	if err != nil {
+		return request.TelegramLinkData{}, err
+	}
+	// linked chat receives login codes of account
+	if user.Impersonated() {
+		return request.TelegramLinkData{}, light.ErrImpersonated
+	}
+
+	b := make([]byte, 16)
+	_, err = rand.Read(b)
+	if err != nil {
+		return request.TelegramLinkData{}, err
+	}
+	token := hex.EncodeToString(b)
+	n.Cache().Set(fmt.Sprintf(TelegramLinkKey, token), &user.UUID.String, telegramLinkTTL)
+
+	return request.TelegramLinkData{Link: n.Telegram().DeepLink(token)}, nil
+}
+
+func (n *Notifications) TelegramUnlink(ctx context.Context) (request.NotificationPreferencesData, error) {
+	user, err := extractUser(ctx)
+	if err != nil {
+		return request.NotificationPreferencesData{}, err
+	}
+	user, err = n.userRepository.Find(ctx, user)
+	if err != nil {
+		return request.NotificationPreferencesData{}, err
+	}
+	if user.TelegramID.Valid {
+		chatID := user.TelegramID.Int64
+		user.TelegramID = types.NullInt64{}
+		if err = n.userRepository.Update(ctx, user); err != nil {
+			return request.NotificationPreferencesData{}, err
+		}
+		if err = n.Telegram().SendMessage(ctx, chatID, "Аккаунт отвязан."); err != nil {
+			n.Logger().Warn("telegram message", zap.Error(err))
+		}
+	}
+
+	return n.preferences(ctx, user)
+}
+
+// TelegramUpdate handles commands of private chats: /start with link token links chat, /stop unlinks it
+func (n *Notifications) TelegramUpdate(ctx context.Context, secret string, update providers.TelegramUpdate) error {
+	expected := n.Config().Telegram.WebhookSecret
+	if expected == "" || subtle.ConstantTimeCompare([]byte(secret), []byte(expected)) != 1 {
+		return ErrTelegramSecret
+	}
+	msg := update.Message
+	if msg == nil || msg.Chat.Type != "private" {
+		return nil
+	}
+
+	var reply string
+	var err error
+	command, arg := msg.Command()
+	switch command {
+	case "/start":
+		reply, err = n.linkChat(ctx, msg.Chat.ID, arg)
+	case "/stop", "/unlink":
+		reply, err = n.unlinkChat(ctx, msg.Chat.ID)
+	default:
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return n.Telegram().SendMessage(ctx, msg.Chat.ID, reply)
+}
+
+func (n *Notifications) linkChat(ctx context.Context, chatID int64, token string) (string, error) {
+	var userID string
+	key := fmt.Sprintf(TelegramLinkKey, token)
+	if token == "" || n.Cache().Get(key, &userID) != nil {
+		return "Ссылка недействительна, получите новую в настройках уведомлений.", nil
+	}
+	_ = n.Cache().Del(key)
+
+	user, err := n.userRepository.Find(ctx, light.User{UUID: types.NewNullUUID(userID)})
+	if err != nil {
+		return "", err
+	}
+	// chat is linked to one account only
+	linked, err := n.userRepository.FindByTelegram(ctx, light.User{TelegramID: types.NewNullInt64(chatID)})
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return "", err
+	}
+	if err == nil && linked.UUID.String != user.UUID.String {
+		linked.TelegramID = types.NullInt64{}
+		if err = n.userRepository.Update(ctx, linked); err != nil {
+			return "", err
+		}
+	}
+	user.TelegramID = types.NewNullInt64(chatID)
+	if err = n.userRepository.Update(ctx, user); err != nil {
+		return "", err
+	}
+	n.Logger().Info("telegram linked", zap.String("uuid", user.UUID.String))
+
+	return "Аккаунт привязан, уведомления будут приходить в этот чат. Отправьте /stop, чтобы отвязать.", nil
+}
+
+func (n *Notifications) unlinkChat(ctx context.Context, chatID int64) (string, error) {
+	user, err := n.userRepository.FindByTelegram(ctx, light.User{TelegramID: types.NewNullInt64(chatID)})
+	if errors.Is(err, sql.ErrNoRows) {
+		return "Чат не привязан к аккаунту.", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	user.TelegramID = types.NullInt64{}
+	if err = n.userRepository.Update(ctx, user); err != nil {
+		return "", err
+	}
+
+	return "Аккаунт отвязан.", nil
+}
+
+// notifyTelegram chat is unlinked when user blocked bot
+func (n *Notifications) notifyTelegram(ctx context.Context, user light.User, notification Notification) error {
+	text := []string{notification.Title, notification.Text}
+	if notification.Link != "" {
+		text = append(text, notification.Link)
+	}
+	err := n.Telegram().SendMessage(ctx, user.TelegramID.Int64, strings.Join(text, "\n\n"))
+	if errors.Is(err, providers.ErrTelegramBlocked) {
+		user.TelegramID = types.NullInt64{}
+		if updateErr := n.userRepository.Update(ctx, user); updateErr != nil {
+			n.Logger().Error("telegram unlink", zap.String("uuid", user.UUID.String), zap.Error(updateErr))
+		}
+	}
+
+	return err
+}
@@ -1,16 +1,16 @@
 package services
 
 import (
-	"bytes"
 	"context"
+	"database/sql"
 	"errors"
 	"fmt"
-	"html/template"
 	"math/rand"
 	"net/url"
 	"time"
 
 	"github.com/ptflp/go-light/email"
+	"github.com/ptflp/go-light/session"
 	"github.com/ptflp/go-light/types"
 
 	"github.com/ptflp/go-light/utils"
@@ -18,8 +18,6 @@ import (
 	"github.com/ptflp/go-light/components"
 	"go.uber.org/zap"
 
-	"github.com/ptflp/go-light/validators"
-
 	"github.com/ptflp/go-light/decoder"
 
 	"github.com/ptflp/go-light/hasher"
@@ -32,16 +30,21 @@ const (
 	PhoneRecoverKey      = "phone:recover:%s"
 	PasswordRecoveryUUID = "R"
 	RecoveryIDKey        = "recover:id:%s"
+
+	purgeInterval = time.Hour
+	// guestRetention guest without refresh within refresh token lifetime can't sign in anymore
+	guestRetention = session.RefreshTokenTTL
 )
 
 type User struct {
 	*decoder.Decoder
 	userRepository light.UserRepository
+	pushRepository light.PushRepository
 	components.Componenter
 }
 
 func NewUserService(rs light.Repositories, cmps components.Componenter) *User {
-	return &User{userRepository: rs.Users, Decoder: decoder.NewDecoder(), Componenter: cmps}
+	return &User{userRepository: rs.Users, pushRepository: rs.Push, Decoder: decoder.NewDecoder(), Componenter: cmps}
 }
 
 func (u *User) CheckEmailPass(ctx context.Context, user light.User) bool {
@@ -68,6 +71,7 @@ func (u *User) GetProfile(ctx context.Context) (request.UserData, error) {
 	if err != nil {
 		return request.UserData{}, err
 	}
+	actor := user.Actor
 
 	user, err = u.userRepository.Find(ctx, user)
 	if err != nil {
@@ -79,6 +83,10 @@ func (u *User) GetProfile(ctx context.Context) (request.UserData, error) {
 	if err != nil {
 		return request.UserData{}, err
 	}
+	if actor.Valid {
+		userData.Impersonated = true
+		userData.ImpersonatorID = actor.String
+	}
 
 	userData.Counts = &request.UserDataCounts{
 		Friends: 377,
@@ -94,6 +102,14 @@ func (u *User) UpdateProfile(ctx context.Context, profileUpdateReq request.Profi
 	if err != nil {
 		return request.UserData{}, err
 	}
+	if profileUpdateReq.Email != nil {
+		var address string
+		address, err = u.EmailValidator().Validate(*profileUpdateReq.Email)
+		if err != nil {
+			return request.UserData{}, err
+		}
+		profileUpdateReq.Email = &address
+	}
 
 	err = u.MapStructs(&user, &profileUpdateReq)
 	if err != nil {
@@ -120,6 +136,9 @@ func (u *User) SetPassword(ctx context.Context, setPasswordReq request.SetPasswo
 	if err != nil {
 		return err
 	}
+	if user.Impersonated() {
+		return light.ErrImpersonated
+	}
 	user, err = u.userRepository.Find(ctx, user)
 	if err != nil {
 		return err
@@ -142,21 +161,86 @@ func (u *User) SetPassword(ctx context.Context, setPasswordReq request.SetPasswo
 	return u.userRepository.SetPassword(ctx, user)
 }
 
-func (u *User) prepareRecoveryTemplate(recoverUrl string) (bytes.Buffer, error) {
-	tmpl, err := template.ParseFiles("./templates/password_recovery.html")
+func (u *User) Delete(ctx context.Context, deleteAccountReq request.DeleteAccountReq) error {
+	user, err := extractUser(ctx)
+	if err != nil {
+		return err
+	}
+	if user.Impersonated() {
+		return light.ErrImpersonated
+	}
+	user, err = u.userRepository.Find(ctx, user)
 	if err != nil {
-		u.Logger().Error("recover password template parse", zap.Error(err))
-		return bytes.Buffer{}, err
+		return err
+	}
+	if user.DeletedAt.Valid {
+		return fmt.Errorf("user already deleted")
 	}
-	type PasswordRecover struct {
-		PasswordRecover string
+	if user.Password.Valid {
+		if deleteAccountReq.Password == nil {
+			return fmt.Errorf("password is required")
+		}
+		if !hasher.CheckPasswordHash(*deleteAccountReq.Password, user.Password.String) {
+			return fmt.Errorf("wrong password")
+		}
 	}
 
-	b := bytes.Buffer{}
+	err = u.userRepository.Delete(ctx, user)
+	if err != nil {
+		return err
+	}
+	u.JWTKeys().RevokeSessions(user.UUID.String)
+	// devices are not notified anymore, restored account subscribes again
+	err = u.pushRepository.DeleteByUser(ctx, user)
+	if err != nil {
+		u.Logger().Error("delete push subscriptions", zap.String("uuid", user.UUID.String), zap.Error(err))
+	}
 
-	err = tmpl.Execute(&b, PasswordRecover{PasswordRecover: recoverUrl})
+	return nil
+}
 
-	return b, err
+// PurgeDeleted anonymizes accounts which were not restored within grace period
+func (u *User) PurgeDeleted(ctx context.Context) {
+	ticker := time.NewTicker(purgeInterval)
+	defer ticker.Stop()
+
+	for {
+		u.purge(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (u *User) purge(ctx context.Context) {
+	purged, err := u.userRepository.PurgeGuests(ctx, time.Now().Add(-guestRetention))
+	if err != nil {
+		u.Logger().Error("purge inactive guests", zap.Error(err))
+	}
+	if purged > 0 {
+		u.Logger().Info("inactive guests purged", zap.Int64("count", purged))
+	}
+
+	users, err := u.userRepository.FindDeleted(ctx, time.Now().Add(-u.Config().App.DeletionGrace()))
+	if err != nil {
+		u.Logger().Error("find deleted users", zap.Error(err))
+		return
+	}
+	for i := range users {
+		err = u.pushRepository.DeleteByUser(ctx, users[i])
+		if err != nil {
+			u.Logger().Error("delete push subscriptions", zap.String("uuid", users[i].UUID.String), zap.Error(err))
+			continue
+		}
+		err = u.userRepository.Anonymize(ctx, users[i])
+		if err != nil {
+			u.Logger().Error("anonymize user", zap.String("uuid", users[i].UUID.String), zap.Error(err))
+			continue
+		}
+		u.Logger().Info("user anonymized", zap.String("uuid", users[i].UUID.String))
+	}
 }
 
 func (u *User) List(ctx context.Context) ([]request.UserData, error) {
@@ -268,6 +352,9 @@ func (u *User) Get(ctx context.Context, req request.UserIDNickRequest) (request.
 			return request.UserData{}, err
 		}
 	}
+	if user.DeletedAt.Valid {
+		return request.UserData{}, errors.New("user not found")
+	}
 
 	userData := request.UserData{}
 	err = u.MapStructs(&userData, &user)
@@ -305,13 +392,13 @@ func (u *User) PasswordRecover(ctx context.Context, req request.PasswordRecoverR
 	}
 
 	if user.Email.Valid {
-		err = validators.CheckEmailFormat(user.Email.String)
+		user.Email.String, err = u.EmailValidator().Canonical(user.Email.String)
 		if err != nil {
 			return err
 		}
 		user, err = u.userRepository.FindByEmail(ctx, user)
 		if err != nil {
-			return err
+			return u.hideMissing(err)
 		}
 		// send email
 		var recoverUrl string
@@ -320,32 +407,30 @@ func (u *User) PasswordRecover(ctx context.Context, req request.PasswordRecoverR
 			return err
 		}
 
-		var body bytes.Buffer
-		body, err = u.prepareRecoveryTemplate(recoverUrl)
+		var msg *email.Message
+		msg, err = u.Templates().Message(email.TemplatePasswordRecovery, user.Language.Int64, struct {
+			Link string
+		}{
+			Link: recoverUrl,
+		})
 		if err != nil {
 			return err
 		}
-
-		msg := email.NewMessage()
-		msg.SetSubject("Восстановление пароля")
-		msg.SetType(email.TypeHtml)
 		msg.SetReceiver(user.Email.String)
-		msg.SetBody(body)
 
-		err = u.Email().Send(msg)
-		if err != nil {
-			return err
-		}
+		return components.Dispatch(u.Componenter, func() error {
+			return u.Email().Send(msg)
+		})
 	}
 
 	if user.Phone.Valid {
-		user.Phone.String, err = validators.CheckPhoneFormat(user.Phone.String)
+		user.Phone.String, err = u.PhoneValidator().Normalize(ctx, user.Phone.String)
 		if err != nil {
 			return err
 		}
 		user, err = u.userRepository.FindByPhone(ctx, user)
 		if err != nil {
-			return err
+			return u.hideMissing(err)
 		}
 
 		code := genCode()
@@ -357,28 +442,44 @@ func (u *User) PasswordRecover(ctx context.Context, req request.PasswordRecoverR
 			return nil
 		}
 
-		err = u.Componenter.SMS().Send(ctx, user.Phone.String, fmt.Sprintf("Ваш код: %d", code))
-		if err != nil {
-			u.Logger().Error("send sms err", zap.String("user.Phone.String", user.Phone.String), zap.Int("code", code))
-		}
+		phone := user.Phone.String
+		return components.Dispatch(u.Componenter, func() error {
+			err := u.Componenter.SMS().Send(context.Background(), phone, fmt.Sprintf("Ваш код: %d", code))
+			if err != nil {
+				u.Logger().Error("send sms err", zap.String("phone", phone), zap.Error(err))
+			}
 
-		return err
+			return err
+		})
 	}
 
 	return errors.New("bad request params")
 }
 
+// hideMissing reports success for unknown accounts in anti-enumeration mode
+func (u *User) hideMissing(err error) error {
+	if u.Config().Security.AntiEnumeration && errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+
+	return err
+}
+
 func (u *User) CheckPhoneCode(ctx context.Context, req request.CheckPhoneCodeRequest) (request.RecoverChekPhoneResponse, error) {
 	var code int64
 	var user light.User
-	err := u.Cache().Get(fmt.Sprintf(PhoneRecoverKey, req.Phone), &code)
+	phone, err := u.PhoneValidator().Normalize(ctx, req.Phone)
+	if err != nil {
+		return request.RecoverChekPhoneResponse{}, err
+	}
+	err = u.Cache().Get(fmt.Sprintf(PhoneRecoverKey, phone), &code)
 	if err != nil {
 		return request.RecoverChekPhoneResponse{}, err
 	}
 	if code != req.Code {
 		return request.RecoverChekPhoneResponse{}, errors.New("user code error")
 	}
-	user.Phone = types.NewNullString(req.Phone)
+	user.Phone = types.NewNullString(phone)
 	user, err = u.userRepository.FindByPhone(ctx, user)
 	if err != nil {
 		return request.RecoverChekPhoneResponse{}, err
@@ -431,9 +532,13 @@ func (u *User) PasswordReset(ctx context.Context, req request.PasswordResetReque
 }
 
 func (u *User) EmailExist(ctx context.Context, req request.EmailRequest) error {
+	address, err := u.EmailValidator().Canonical(req.Email)
+	if err != nil {
+		return err
+	}
 	var user light.User
-	user.Email = types.NewNullString(req.Email)
-	_, err := u.userRepository.FindByEmail(ctx, user)
+	user.Email = types.NewNullString(address)
+	_, err = u.userRepository.FindByEmail(ctx, user)
 
 	return err
 }
@@ -0,0 +1,193 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	light "github.com/ptflp/go-light"
+	"github.com/ptflp/go-light/components/componentstest"
+	"github.com/ptflp/go-light/request"
+	"github.com/ptflp/go-light/types"
+	"github.com/volatiletech/null/v8"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type testPushRepository struct {
+	light.PushRepository
+	deleted []string
+}
+
+func (r *testPushRepository) DeleteByUser(ctx context.Context, user light.User) error {
+	r.deleted = append(r.deleted, user.UUID.String)
+
+	return nil
+}
+
+func (r *testPushRepository) ListByUser(ctx context.Context, user light.User) ([]light.PushSubscription, error) {
+	return nil, nil
+}
+
+func newTestUserService(t *testing.T) (*User, *componentstest.Users, *testPushRepository) {
+	t.Helper()
+	users := &componentstest.Users{}
+	push := &testPushRepository{}
+
+	return NewUserService(light.Repositories{Users: users, Push: push}, componentstest.New(t, nil)), users, push
+}
+
+func TestUser_Delete(t *testing.T) {
+	u, users, push := newTestUserService(t)
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := light.User{UUID: types.NewNullUUID(), Password: types.NewNullString(string(hash))}
+	users.Add(user)
+	tokens, err := u.JWTKeys().GenerateAuthTokens(&user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wrong, secret := "wrong", "secret"
+	impersonated := user
+	impersonated.Actor = types.NewNullUUID()
+	tests := []struct {
+		name string
+		user light.User
+		req  request.DeleteAccountReq
+	}{
+		{name: "impersonated", user: impersonated, req: request.DeleteAccountReq{Password: &secret}},
+		{name: "no password", user: user},
+		{name: "wrong password", user: user, req: request.DeleteAccountReq{Password: &wrong}},
+	}
+	for _, tt := range tests {
+		ctx := context.WithValue(context.Background(), types.User{}, &tt.user)
+		if err = u.Delete(ctx, tt.req); err == nil {
+			t.Errorf("Delete() %s error is nil", tt.name)
+		}
+	}
+	if found, _ := users.Find(context.Background(), user); found.DeletedAt.Valid {
+		t.Fatal("user deleted by rejected request")
+	}
+
+	ctx := context.WithValue(context.Background(), types.User{}, &user)
+	if err = u.Delete(ctx, request.DeleteAccountReq{Password: &secret}); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if found, _ := users.Find(ctx, user); !found.DeletedAt.Valid {
+		t.Error("user is not deleted")
+	}
+	if len(push.deleted) != 1 || push.deleted[0] != user.UUID.String {
+		t.Errorf("push subscriptions deleted = %v", push.deleted)
+	}
+	r := httptest.NewRequest("GET", "/profile", nil)
+	r.Header.Set("Authorization", "Bearer "+tokens.AccessToken)
+	if _, err = u.JWTKeys().ExtractAccessToken(r); err == nil {
+		t.Error("session issued before deletion is accepted")
+	}
+	if _, err = u.JWTKeys().ExtractRefreshToken(tokens.RefreshToken); err == nil {
+		t.Error("refresh token issued before deletion is accepted")
+	}
+	if err = u.Delete(ctx, request.DeleteAccountReq{Password: &secret}); err == nil {
+		t.Error("Delete() of deleted user error is nil")
+	}
+}
+
+func TestUser_Purge(t *testing.T) {
+	u, users, push := newTestUserService(t)
+	grace := u.Config().App.DeletionGrace()
+	expired := light.User{
+		UUID:      types.NewNullUUID(),
+		DeletedAt: types.NullTime{Time: null.TimeFrom(time.Now().Add(-grace - time.Hour))},
+	}
+	recent := light.User{
+		UUID:      types.NewNullUUID(),
+		DeletedAt: types.NullTime{Time: null.TimeFrom(time.Now().Add(-time.Hour))},
+	}
+	active := light.User{UUID: types.NewNullUUID()}
+	for _, user := range []light.User{expired, recent, active} {
+		users.Add(user)
+	}
+
+	u.purge(context.Background())
+
+	if len(users.Anonymized()) != 1 || users.Anonymized()[0] != expired.UUID.String {
+		t.Errorf("anonymized = %v, want %s only", users.Anonymized(), expired.UUID.String)
+	}
+	if len(push.deleted) != 1 || push.deleted[0] != expired.UUID.String {
+		t.Errorf("push subscriptions deleted = %v", push.deleted)
+	}
+	// guests are purged once their refresh tokens expire
+	if since := time.Since(users.GuestsPurgedBefore()); since < guestRetention || since > guestRetention+time.Minute {
+		t.Errorf("guests purged before %s", users.GuestsPurgedBefore())
+	}
+}
+
+func TestUser_hideMissing(t *testing.T) {
+	u, _, _ := newTestUserService(t)
+	failed := errors.New("connection refused")
+
+	if err := u.hideMissing(sql.ErrNoRows); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("hideMissing() disabled = %v, want sql.ErrNoRows", err)
+	}
+	u.Config().Security.AntiEnumeration = true
+	if err := u.hideMissing(sql.ErrNoRows); err != nil {
+		t.Errorf("hideMissing() of missing account = %v, want nil", err)
+	}
+	// failures are not hidden
+	if err := u.hideMissing(failed); !errors.Is(err, failed) {
+		t.Errorf("hideMissing() of failure = %v", err)
+	}
+}
+
+func TestUser_PasswordRecover_AntiEnumeration(t *testing.T) {
+	u, users, _ := newTestUserService(t)
+	mailer := u.Componenter.(*componentstest.Components).Mailer()
+	u.Config().SMSC.Dev = true
+	users.Add(light.User{
+		UUID:  types.NewNullUUID(),
+		Email: types.NewNullString("user@example.com"),
+		Phone: types.NewNullString("79644288083"),
+	})
+	passwordRecover := func(address, phone string) error {
+		req := request.PasswordRecoverRequest{}
+		if address != "" {
+			req.Email = &address
+		}
+		if phone != "" {
+			req.Phone = &phone
+		}
+
+		return u.PasswordRecover(context.Background(), req)
+	}
+
+	if err := passwordRecover("missing@example.com", ""); err == nil {
+		t.Fatal("PasswordRecover() of missing email without anti-enumeration error is nil")
+	}
+
+	u.Config().Security.AntiEnumeration = true
+	// existing and missing accounts get the same response
+	for _, address := range []string{"user@example.com", "missing@example.com"} {
+		if err := passwordRecover(address, ""); err != nil {
+			t.Errorf("PasswordRecover(%s) error = %v", address, err)
+		}
+	}
+	for _, phone := range []string{"79644288083", "79644288084"} {
+		if err := passwordRecover("", phone); err != nil {
+			t.Errorf("PasswordRecover(%s) error = %v", phone, err)
+		}
+	}
+	// only owner of existing account is emailed
+	sent := mailer.WaitSent(1)
+	if len(sent) != 1 || sent[0].GetReceiver() != "user@example.com" {
+		t.Fatalf("sent = %d messages", len(sent))
+	}
+	time.Sleep(50 * time.Millisecond)
+	if sent = mailer.Sent(); len(sent) != 1 {
+		t.Errorf("sent = %d messages, want 1", len(sent))
+	}
+}
@@ -2,10 +2,15 @@ package session
 
 import (
 	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
 	"errors"
+	"fmt"
 	"io/ioutil"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/ptflp/go-light/decoder"
@@ -31,7 +36,13 @@ const (
 	Month = 30 * Day
 	Day   = 24 * time.Hour
 
+	ImpersonationTTL = 15 * time.Minute
+	// RefreshTokenTTL lifetime of refresh token, session is over unless refreshed within it
+	RefreshTokenTTL = 2 * Month
+
 	RefreshTokenKey = "refresh_token"
+	RevokedKey      = "session:revoked:%s"
+	SessionsKey     = "session:list:%s"
 )
 
 type JWTKeys struct {
@@ -42,6 +53,8 @@ type JWTKeys struct {
 	verifyBytes []byte
 	logger      *zap.Logger
 	cache       cache.Cache
+	// sessionsMu guards read-modify-write of sessions list
+	sessionsMu sync.Mutex
 }
 
 func NewJWTKeys(logger *zap.Logger, cache cache.Cache) (*JWTKeys, error) {
@@ -58,6 +71,17 @@ func NewJWTKeys(logger *zap.Logger, cache cache.Cache) (*JWTKeys, error) {
 	return j, nil
 }
 
+// NewJWTKeysWithKey keys of private key kept in memory instead of ./keys
+func NewJWTKeysWithKey(logger *zap.Logger, cache cache.Cache, key *rsa.PrivateKey) *JWTKeys {
+	return &JWTKeys{
+		Decoder:   decoder.NewDecoder(),
+		signKey:   key,
+		verifyKey: &key.PublicKey,
+		logger:    logger,
+		cache:     cache,
+	}
+}
+
 func (j *JWTKeys) ReadKeys() error {
 	var err error
 
@@ -118,9 +142,16 @@ type RefreshToken struct {
 	Token string `json:"token"`
 	UID   int64  `json:"uid"`
 	UUID  string `json:"uuid"`
+	// Device hash of device id refresh token is bound to
+	Device string `json:"device"`
 }
 
 func (j *JWTKeys) GenerateAuthTokens(u *light.User) (*req.AuthTokenData, error) {
+	return j.GenerateDeviceAuthTokens(u, "")
+}
+
+// GenerateDeviceAuthTokens issues tokens with refresh token bound to device, empty deviceID issues unbound token
+func (j *JWTKeys) GenerateDeviceAuthTokens(u *light.User, deviceID string) (*req.AuthTokenData, error) {
 	if !u.UUID.Valid {
 		return nil, errors.New("wrong user")
 	}
@@ -128,7 +159,7 @@ func (j *JWTKeys) GenerateAuthTokens(u *light.User) (*req.AuthTokenData, error)
 	if err != nil {
 		return nil, err
 	}
-	refresh, err := j.CreateRefreshToken(access, u)
+	refresh, err := j.CreateRefreshToken(access, u, deviceID)
 	if err != nil {
 		return nil, err
 	}
@@ -147,25 +178,65 @@ func (j *JWTKeys) GenerateAuthTokens(u *light.User) (*req.AuthTokenData, error)
 }
 
 func (j *JWTKeys) CreateAccessToken(u light.User) (string, error) {
-	token, err := j.GenerateToken(jwt.MapClaims{
-		"exp":  time.Now().UTC().Add(time.Hour * 50).Unix(),
-		"uuid": u.UUID.String,
-	})
+	now := time.Now().UTC()
+	claims := jwt.MapClaims{
+		"exp":    now.Add(time.Hour * 50).Unix(),
+		"iat":    now.Unix(),
+		"iat_us": unixMicro(now),
+		"uuid":   u.UUID.String,
+	}
+	if u.Role.Valid {
+		claims["role"] = u.Role.Int64
+	}
 
-	return token, err
+	return j.GenerateToken(claims)
 }
 
-func (j *JWTKeys) CreateRefreshToken(accessToken string, u *light.User) (string, error) {
+// CreateImpersonationToken short-lived access token for admin acting as user, refresh is not issued
+func (j *JWTKeys) CreateImpersonationToken(u light.User, admin light.User) (string, error) {
+	now := time.Now().UTC()
+	claims := jwt.MapClaims{
+		"exp":    now.Add(ImpersonationTTL).Unix(),
+		"iat":    now.Unix(),
+		"iat_us": unixMicro(now),
+		"uuid":   u.UUID.String,
+		"act": map[string]interface{}{
+			"sub": admin.UUID.String,
+		},
+	}
+	// role restrictions of user, e.g. guest, apply to admin acting as user
+	if u.Role.Valid {
+		claims["role"] = u.Role.Int64
+	}
+
+	return j.GenerateToken(claims)
+}
+
+func (j *JWTKeys) CreateRefreshToken(accessToken string, u *light.User, deviceID string) (string, error) {
 	refreshToken := hasher.NewSHA256([]byte(accessToken))
 
 	key := strings.Join([]string{RefreshTokenKey, u.UUID.String, refreshToken}, ":")
-	j.cache.Set(key, u, 2*Month)
-
-	return j.GenerateToken(jwt.MapClaims{
+	j.cache.Set(key, u, RefreshTokenTTL)
+
+	now := time.Now().UTC()
+	j.addSession(u.UUID.String, req.SessionData{
+		ID:        refreshToken,
+		Device:    deviceID != "",
+		CreatedAt: now,
+		ExpiresAt: now.Add(RefreshTokenTTL),
+	})
+	claims := jwt.MapClaims{
 		"refresh_token": refreshToken,
-		"exp":           time.Now().UTC().Add(2 * Month).Unix(),
+		"exp":           now.Add(RefreshTokenTTL).Unix(),
+		"iat":           now.Unix(),
+		"iat_us":        unixMicro(now),
 		"uuid":          u.UUID.String,
-	})
+	}
+	if deviceID != "" {
+		claims["device"] = hasher.NewSHA256([]byte(deviceID))
+	}
+
+	return j.GenerateToken(claims)
 }
 
 func (j *JWTKeys) GenerateToken(m jwt.MapClaims) (string, error) {
@@ -174,10 +245,51 @@ func (j *JWTKeys) GenerateToken(m jwt.MapClaims) (string, error) {
 	if err != nil {
 		return "", err
 	}
+	token.Header["kid"] = j.KeyID()
 
 	return token.SignedString(signKey)
 }
 
+// KeyID identifier of signing key published in JWKS
+func (j *JWTKeys) KeyID() string {
+	if j.verifyKey == nil {
+		return ""
+	}
+	der, err := x509.MarshalPKIXPublicKey(j.verifyKey)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(der)
+
+	return base64.RawURLEncoding.EncodeToString(sum[:12])
+}
+
+// ParseToken verifies token signature and expiration
+func (j *JWTKeys) ParseToken(rawToken string) (jwt.MapClaims, error) {
+	verifyKey, err := j.GetVerifyKey()
+	if err != nil {
+		return nil, err
+	}
+	token, err := jwt.Parse(rawToken, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
+			return nil, errors.New("unexpected signing method")
+		}
+		return verifyKey, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+	c := token.Claims.(jwt.MapClaims)
+	if !c.VerifyExpiresAt(time.Now().Unix(), true) {
+		return nil, errors.New("token expired")
+	}
+
+	return c, nil
+}
+
 func (j *JWTKeys) ExtractRefreshToken(rawToken string) (*RefreshToken, error) {
 	verifyKey, err := j.GetVerifyKey()
 	if err != nil {
@@ -211,9 +323,10 @@ func (j *JWTKeys) ExtractRefreshToken(rawToken string) (*RefreshToken, error) {
 		return nil, errors.New("jwt map claims err: refresh_token")
 	}
 
-	uid, ok := c["uid"]
-	if !ok {
-		return nil, errors.New("jwt map claims err: uid")
+	// uid is optional, issued by legacy tokens only
+	var uid float64
+	if v, ok := c["uid"].(float64); ok {
+		uid = v
 	}
 
 	uuid, ok := c["uuid"]
@@ -221,10 +334,20 @@ func (j *JWTKeys) ExtractRefreshToken(rawToken string) (*RefreshToken, error) {
 		return nil, errors.New("jwt map claims err: uuid")
 	}
 
+	if j.revoked(uuid.(string), c) {
+		return nil, errors.New("session revoked")
+	}
+
+	var device string
+	if v, ok := c["device"].(string); ok {
+		device = v
+	}
+
 	return &RefreshToken{
-		Token: refreshToken.(string),
-		UID:   int64(uid.(float64)),
-		UUID:  uuid.(string),
+		Token:  refreshToken.(string),
+		UID:    int64(uid),
+		UUID:   uuid.(string),
+		Device: device,
 	}, nil
 }
 
@@ -245,6 +368,10 @@ func (j *JWTKeys) ExtractAccessToken(r *http.Request) (*light.User, error) {
 	}
 
 	c := token.Claims.(jwt.MapClaims)
+	// tokens issued for oauth2 clients are not accepted by api
+	if _, ok := c["aud"]; ok {
+		return nil, errors.New("token audience mismatch")
+	}
 	var exp int64
 
 	if v, ok := c["ExpiresAt"]; ok {
@@ -263,10 +390,108 @@ func (j *JWTKeys) ExtractAccessToken(r *http.Request) (*light.User, error) {
 	if v, ok := c["uuid"]; ok {
 		uuid = v.(string)
 	}
+
+	if j.revoked(uuid, c) {
+		return nil, errors.New("session revoked")
+	}
+
 	u := &light.User{
 		UUID: types.NewNullUUID(uuid),
 	}
+	if role, ok := c["role"].(float64); ok {
+		u.Role = types.NewNullInt64(int64(role))
+	}
+	if act, ok := c["act"].(map[string]interface{}); ok {
+		if sub, ok := act["sub"].(string); ok {
+			u.Actor = types.NewNullUUID(sub)
+		}
+	}
 
 	return u, nil
 
 }
+
+// Sessions active refresh tokens of user, rotated, expired and revoked tokens are skipped
+func (j *JWTKeys) Sessions(uuid string) []req.SessionData {
+	var sessions []req.SessionData
+	err := j.cache.Get(fmt.Sprintf(SessionsKey, uuid), &sessions)
+	if err != nil {
+		return nil
+	}
+	var revokedAt int64
+	_ = j.cache.Get(fmt.Sprintf(RevokedKey, uuid), &revokedAt)
+
+	now := time.Now().UTC()
+	active := sessions[:0]
+	for _, s := range sessions {
+		if s.ExpiresAt.Before(now) || unixMicro(s.CreatedAt) < revokedAt {
+			continue
+		}
+		var u light.User
+		err = j.cache.Get(strings.Join([]string{RefreshTokenKey, uuid, s.ID}, ":"), &u)
+		if err != nil {
+			continue
+		}
+		active = append(active, s)
+	}
+
+	return active
+}
+
+// addSession token issued twice within a microsecond has the same id, session is replaced
+func (j *JWTKeys) addSession(uuid string, s req.SessionData) {
+	j.sessionsMu.Lock()
+	defer j.sessionsMu.Unlock()
+
+	sessions := j.Sessions(uuid)
+	for i := range sessions {
+		if sessions[i].ID == s.ID {
+			sessions = append(sessions[:i], sessions[i+1:]...)
+			break
+		}
+	}
+	sessions = append(sessions, s)
+	j.cache.Set(fmt.Sprintf(SessionsKey, uuid), &sessions, RefreshTokenTTL)
+}
+
+// RevokeSessions invalidates all access and refresh tokens issued to user before now,
+// refresh tokens of listed sessions are deleted
+func (j *JWTKeys) RevokeSessions(uuid string) {
+	j.sessionsMu.Lock()
+	defer j.sessionsMu.Unlock()
+
+	sessions := j.Sessions(uuid)
+	revokedAt := unixMicro(time.Now())
+	j.cache.Set(fmt.Sprintf(RevokedKey, uuid), &revokedAt, RefreshTokenTTL)
+
+	for _, s := range sessions {
+		err := j.cache.Del(strings.Join([]string{RefreshTokenKey, uuid, s.ID}, ":"))
+		if err != nil {
+			j.logger.Error("delete refresh token", zap.String("uuid", uuid), zap.Error(err))
+		}
+	}
+	_ = j.cache.Del(fmt.Sprintf(SessionsKey, uuid))
+}
+
+func (j *JWTKeys) revoked(uuid string, c jwt.MapClaims) bool {
+	var revokedAt int64
+	err := j.cache.Get(fmt.Sprintf(RevokedKey, uuid), &revokedAt)
+	if err != nil {
+		return false
+	}
+
+	// tokens issued without iat_us are compared with second precision
+	var issuedAt int64
+	if v, ok := c["iat_us"].(float64); ok {
+		issuedAt = int64(v)
+	} else if v, ok := c["iat"].(float64); ok {
+		issuedAt = int64(v) * int64(time.Second/time.Microsecond)
+	}
+
+	return issuedAt < revokedAt
+}
+
+// unixMicro microseconds are exact in json number unlike nanoseconds
+func unixMicro(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Microsecond)
+}
@@ -0,0 +1,134 @@
+package session
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	light "github.com/ptflp/go-light"
+	"github.com/ptflp/go-light/cache"
+	req "github.com/ptflp/go-light/request"
+	"github.com/ptflp/go-light/types"
+	"go.uber.org/zap"
+)
+
+func newTestKeys(t *testing.T) *JWTKeys {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return NewJWTKeysWithKey(zap.NewNop(), cache.NewMemory(), key)
+}
+
+func extract(j *JWTKeys, token string) (*light.User, error) {
+	r := httptest.NewRequest("GET", "/profile", nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+
+	return j.ExtractAccessToken(r)
+}
+
+func TestJWTKeys_RevokeSessions(t *testing.T) {
+	j := newTestKeys(t)
+	u := &light.User{UUID: types.NewNullUUID()}
+	other := &light.User{UUID: types.NewNullUUID()}
+
+	before, err := j.GenerateAuthTokens(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherTokens, err := j.GenerateAuthTokens(other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	j.RevokeSessions(u.UUID.String)
+	// token issued right after revocation, e.g. by login restoring account, stays valid
+	after, err := j.GenerateAuthTokens(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = extract(j, before.AccessToken); err == nil {
+		t.Error("access token issued before revocation is accepted")
+	}
+	if _, err = j.ExtractRefreshToken(before.RefreshToken); err == nil {
+		t.Error("refresh token issued before revocation is accepted")
+	}
+	if got, err := extract(j, after.AccessToken); err != nil || got.UUID.String != u.UUID.String {
+		t.Errorf("access token issued after revocation = %v, %v", got, err)
+	}
+	if _, err = j.ExtractRefreshToken(after.RefreshToken); err != nil {
+		t.Errorf("refresh token issued after revocation error = %v", err)
+	}
+	if sessions := j.Sessions(u.UUID.String); len(sessions) != 1 {
+		t.Errorf("sessions after revocation = %+v", sessions)
+	}
+	// tokens of other users are not affected
+	if _, err = extract(j, otherTokens.AccessToken); err != nil {
+		t.Errorf("token of another user error = %v", err)
+	}
+	if sessions := j.Sessions(other.UUID.String); len(sessions) != 1 {
+		t.Errorf("sessions of another user = %+v", sessions)
+	}
+}
+
+func TestJWTKeys_Sessions(t *testing.T) {
+	j := newTestKeys(t)
+	u := &light.User{UUID: types.NewNullUUID()}
+
+	first, err := j.GenerateAuthTokens(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// claims differ from first token issued within the same second
+	u.Role = types.NewNullInt64(types.RoleUser)
+	if _, err = j.GenerateDeviceAuthTokens(u, "device-id"); err != nil {
+		t.Fatal(err)
+	}
+	sessions := j.Sessions(u.UUID.String)
+	if len(sessions) != 2 || sessions[0].Device || !sessions[1].Device {
+		t.Fatalf("sessions = %+v", sessions)
+	}
+
+	// rotated refresh token is not listed
+	refresh, err := j.ExtractRefreshToken(first.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = j.cache.Del(RefreshTokenKey + ":" + u.UUID.String + ":" + refresh.Token)
+	if sessions = j.Sessions(u.UUID.String); len(sessions) != 1 {
+		t.Fatalf("sessions after rotation = %+v", sessions)
+	}
+
+	j.RevokeSessions(u.UUID.String)
+	if sessions = j.Sessions(u.UUID.String); len(sessions) != 0 {
+		t.Errorf("sessions after revocation = %+v", sessions)
+	}
+}
+
+func TestJWTKeys_SessionsConcurrent(t *testing.T) {
+	j := newTestKeys(t)
+	uuid := types.NewNullUUID().String
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			s := req.SessionData{ID: strconv.Itoa(i), CreatedAt: time.Now(), ExpiresAt: time.Now().Add(time.Hour)}
+			j.cache.Set(strings.Join([]string{RefreshTokenKey, uuid, s.ID}, ":"), &light.User{}, time.Hour)
+			j.addSession(uuid, s)
+		}(i)
+	}
+	wg.Wait()
+
+	if sessions := j.Sessions(uuid); len(sessions) != 50 {
+		t.Errorf("sessions = %d, want 50", len(sessions))
+	}
+}
@@ -0,0 +1,39 @@
+package light
+
+import (
+	"context"
+	"time"
+
+	"github.com/ptflp/go-light/types"
+)
+
+// SMSMessage message accepted by sms provider, status is updated by poller and provider callbacks
+type SMSMessage struct {
+	UUID        types.NullUUID    `json:"message_id" db:"uuid" ops:"create" orm_type:"binary(16)" orm_default:"not null primary key"`
+	Provider    types.NullString  `json:"provider" db:"provider" ops:"create" orm_type:"varchar(32)" orm_default:"not null"`
+	ProviderID  types.NullString  `json:"provider_id" db:"provider_id" ops:"create" orm_type:"varchar(64)" orm_default:"not null"`
+	Phone       types.NullString  `json:"phone" db:"phone" ops:"create" orm_type:"varchar(32)" orm_default:"not null"`
+	Cost        types.NullFloat64 `json:"cost" db:"cost" ops:"create" orm_type:"decimal(10,4)" orm_default:"null"`
+	Status      types.NullInt64   `json:"status" db:"status" ops:"create,update" orm_type:"int" orm_default:"not null" orm_index:"index"`
+	Error       types.NullString  `json:"error" db:"error" ops:"update" orm_type:"varchar(255)" orm_default:"null"`
+	CheckedAt   types.NullTime    `json:"checked_at" db:"checked_at" ops:"update" orm_type:"timestamp" orm_default:"null"`
+	DeliveredAt types.NullTime    `json:"delivered_at" db:"delivered_at" ops:"update" orm_type:"timestamp" orm_default:"null"`
+	CreatedAt   time.Time         `json:"created_at" db:"created_at" orm_type:"timestamp" orm_default:"default (now()) not null" orm_index:"index"`
+	UpdatedAt   time.Time         `json:"updated_at" db:"updated_at" orm_type:"timestamp" orm_default:"default (now()) null on update CURRENT_TIMESTAMP"`
+}
+
+func (s SMSMessage) OnCreate() string {
+	return "create unique index sms_messages_provider_id_idx on sms_messages (provider, provider_id);"
+}
+
+func (s SMSMessage) TableName() string {
+	return "sms_messages"
+}
+
+type SMSRepository interface {
+	Create(ctx context.Context, msg SMSMessage) error
+	Update(ctx context.Context, msg SMSMessage) error
+	FindByProviderID(ctx context.Context, provider, providerID string) (SMSMessage, error)
+	// ListSent messages waiting for delivery status, least recently checked first
+	ListSent(ctx context.Context, limit uint64) ([]SMSMessage, error)
+}
@@ -0,0 +1,52 @@
+package light
+
+import (
+	"context"
+	"time"
+
+	"github.com/ptflp/go-light/types"
+)
+
+// EmailSuppression address which must not receive mail after hard bounce or complaint
+type EmailSuppression struct {
+	UUID      types.NullUUID   `json:"suppression_id" db:"uuid" ops:"create" orm_type:"binary(16)" orm_default:"not null primary key"`
+	Email     types.NullString `json:"email" db:"email" ops:"create" orm_type:"varchar(255)" orm_default:"not null" orm_index:"index,unique"`
+	Reason    types.NullInt64  `json:"reason" db:"reason" ops:"create,update" orm_type:"int" orm_default:"not null"`
+	Details   types.NullString `json:"details" db:"details" ops:"create,update" orm_type:"varchar(512)" orm_default:"null"`
+	CreatedAt time.Time        `json:"created_at" db:"created_at" orm_type:"timestamp" orm_default:"default (now()) not null" orm_index:"index"`
+	UpdatedAt time.Time        `json:"updated_at" db:"updated_at" orm_type:"timestamp" orm_default:"default (now()) null on update CURRENT_TIMESTAMP"`
+}
+
+func (e EmailSuppression) OnCreate() string {
+	return ""
+}
+
+func (e EmailSuppression) TableName() string {
+	return "email_suppressions"
+}
+
+// EmailUnsubscribe notification category user unsubscribed from by email, other channels are not affected
+type EmailUnsubscribe struct {
+	UUID      types.NullUUID   `json:"unsubscribe_id" db:"uuid" ops:"create" orm_type:"binary(16)" orm_default:"not null primary key"`
+	UserUUID  types.NullUUID   `json:"user_id" db:"user_uuid" ops:"create" orm_type:"binary(16)" orm_default:"not null" orm_index:"index"`
+	Category  types.NullString `json:"category" db:"category" ops:"create" orm_type:"varchar(32)" orm_default:"not null"`
+	CreatedAt time.Time        `json:"created_at" db:"created_at" orm_type:"timestamp" orm_default:"default (now()) not null"`
+}
+
+func (e EmailUnsubscribe) OnCreate() string {
+	return "create unique index email_unsubscribes_user_category_idx on email_unsubscribes (user_uuid, category);"
+}
+
+func (e EmailUnsubscribe) TableName() string {
+	return "email_unsubscribes"
+}
+
+type SuppressionRepository interface {
+	// Suppress adds address or updates reason of already suppressed one
+	Suppress(ctx context.Context, suppression EmailSuppression) error
+	// Suppressed returns suppressed addresses of given ones
+	Suppressed(ctx context.Context, emails []string) ([]string, error)
+	Unsubscribe(ctx context.Context, unsubscribe EmailUnsubscribe) error
+	Resubscribe(ctx context.Context, user User, category string) error
+	Unsubscribed(ctx context.Context, user User) ([]string, error)
+}
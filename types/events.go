@@ -5,3 +5,29 @@ const (
 	EventTypeSubscribe
 	EventTypeShowPost
 )
+
+const (
+	AuditImpersonation = iota + 1
+)
+
+// outbox message states
+const (
+	OutboxPending = iota + 1
+	OutboxSending
+	OutboxSent
+	OutboxDead
+)
+
+// email suppression reasons
+const (
+	SuppressionHardBounce = iota + 1
+	SuppressionComplaint
+	SuppressionManual
+)
+
+// sms delivery states
+const (
+	SMSSent = iota + 1
+	SMSDelivered
+	SMSFailed
+)
@@ -0,0 +1,6 @@
+package types
+
+const (
+	LanguageRu = iota + 1
+	LanguageEn
+)
@@ -0,0 +1,10 @@
+package types
+
+// email notification categories, NotifyAll switches User.NotifyEmail
+const (
+	NotifyAll      = "all"
+	NotifyActivity = "activity"
+	NotifyNews     = "news"
+)
+
+var NotifyCategories = []string{NotifyAll, NotifyActivity, NotifyNews}
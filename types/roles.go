@@ -0,0 +1,7 @@
+package types
+
+const (
+	RoleUser = iota + 1
+	RoleAdmin
+	RoleGuest
+)
@@ -211,3 +211,5 @@ type NullTime struct {
 type User struct{}
 
 type Chat struct{}
+
+type Region struct{}
@@ -2,6 +2,7 @@ package light
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"github.com/ptflp/go-light/types"
@@ -9,7 +10,7 @@ import (
 
 type User struct {
 	UUID           types.NullUUID    `json:"user_id" db:"uuid" ops:"create" orm_type:"binary(16)" orm_default:"not null primary key"`
-	Role           types.NullInt64   `json:"role" db:"role" orm_type:"int" orm_default:"null" ops:"update"`
+	Role           types.NullInt64   `json:"role" db:"role" orm_type:"int" orm_default:"null" ops:"update,create"`
 	Phone          types.NullString  `json:"phone" db:"phone" ops:"update,create" orm_type:"varchar(34)" orm_index:"index,unique"`
 	Email          types.NullString  `json:"email" db:"email" ops:"update,create" orm_type:"varchar(89)" orm_index:"index,unique"`
 	Avatar         types.NullString  `json:"profile_image" db:"avatar" ops:"update" orm_type:"varchar(144)"`
@@ -30,12 +31,16 @@ type User struct {
 	Language       types.NullInt64   `json:"language" db:"language" ops:"update,create" orm_type:"int"`
 	FacebookID     types.NullInt64   `json:"facebook_id" db:"facebook_id" ops:"update,create" orm_type:"bigint unsigned"`
 	GoogleID       types.NullString  `json:"google_id" db:"google_id" ops:"update,create" orm_type:"varchar(21)"`
+	TelegramID     types.NullInt64   `json:"telegram_id" db:"telegram_id" ops:"update,create" orm_type:"bigint" orm_index:"index,unique"`
 	Likes          types.NullUint64  `json:"likes_count" db:"likes" orm_type:"bigint unsigned" orm_default:"null" orm_index:"index" ops:"count"`
 	Subscribes     types.NullUint64  `json:"subscribes_count" db:"subscribes" orm_type:"bigint unsigned" orm_default:"null" orm_index:"index" ops:"count"`
 	Subscribers    types.NullUint64  `json:"subscribers_count" db:"subscribers" orm_type:"bigint unsigned" orm_default:"null" orm_index:"index" ops:"count"`
 	CreatedAt      time.Time         `json:"created_at" db:"created_at" orm_type:"timestamp" orm_default:"default (now()) not null" orm_index:"index"`
 	UpdatedAt      time.Time         `json:"updated_at" db:"updated_at" orm_type:"timestamp" orm_default:"default (now()) null on update CURRENT_TIMESTAMP" orm_index:"index"`
 	DeletedAt      types.NullTime    `json:"deleted_at" db:"deleted_at" orm_type:"timestamp" orm_default:"null" orm_index:"index"`
+
+	// Actor admin acting on behalf of user, set from access token only
+	Actor types.NullUUID `json:"-" db:"-"`
 }
 
 func (u User) OnCreate() string {
@@ -46,6 +51,21 @@ func (u User) TableName() string {
 	return "users"
 }
 
+func (u User) IsAdmin() bool {
+	return u.Role.Valid && u.Role.Int64 == types.RoleAdmin
+}
+
+func (u User) IsGuest() bool {
+	return u.Role.Valid && u.Role.Int64 == types.RoleGuest
+}
+
+// ErrImpersonated operation is reserved to user itself, denied to admin acting as user
+var ErrImpersonated = errors.New("operation is not allowed while impersonating")
+
+func (u User) Impersonated() bool {
+	return u.Actor.Valid
+}
+
 type UserRepository interface {
 	Update(ctx context.Context, user User) error
 	SetPassword(ctx context.Context, user User) error
@@ -56,13 +76,25 @@ type UserRepository interface {
 	FindByPhone(ctx context.Context, user User) (User, error)
 	FindByEmail(ctx context.Context, user User) (User, error)
 	FindByNickname(ctx context.Context, user User) (User, error)
+	FindByNicknameWithDeleted(ctx context.Context, user User) (User, error)
 	FindLikeNickname(ctx context.Context, nickname string) ([]User, error)
 	FindByFacebook(ctx context.Context, user User) (User, error)
 	FindByGoogle(ctx context.Context, user User) (User, error)
+	FindByTelegram(ctx context.Context, user User) (User, error)
 	Count(ctx context.Context, user User, field, ops string) (User, error)
 
 	CreateUser(ctx context.Context, user User) error
 	CreateUserByEmailPassword(ctx context.Context, user User) error
 
+	Delete(ctx context.Context, user User) error
+	Restore(ctx context.Context, user User) error
+	FindDeleted(ctx context.Context, before time.Time) ([]User, error)
+	Anonymize(ctx context.Context, user User) error
+	UpgradeGuest(ctx context.Context, user User) error
+	// Touch marks user active, guests are purged after inactivity
+	Touch(ctx context.Context, user User) error
+	// PurgeGuests removes guests not active since specified time
+	PurgeGuests(ctx context.Context, before time.Time) (int64, error)
+
 	Listx(ctx context.Context, condition Condition) ([]User, error)
 }
@@ -0,0 +1,126 @@
+package validators
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+//go:embed disposable_domains.txt
+var defaultDisposable []byte // used when blocklist file is not configured
+
+// DomainBlocklist set of blocked domains loaded from file, one domain per line, # starts comment.
+// File is reloaded on change, subdomains of blocked domain are blocked too.
+type DomainBlocklist struct {
+	path     string
+	interval time.Duration
+
+	mu        sync.RWMutex
+	domains   map[string]bool
+	modTime   time.Time
+	checkedAt time.Time
+}
+
+func NewDomainBlocklist(path string, interval time.Duration) (*DomainBlocklist, error) {
+	b := &DomainBlocklist{path: path, interval: interval, checkedAt: time.Now()}
+	if path == "" {
+		b.domains = parseDomains(defaultDisposable)
+		return b, nil
+	}
+	if err := b.Reload(); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// Reload reads blocklist file, current list is kept on error
+func (b *DomainBlocklist) Reload() error {
+	if b.path == "" {
+		return nil
+	}
+	info, err := os.Stat(b.path)
+	if err != nil {
+		return err
+	}
+	data, err := ioutil.ReadFile(b.path)
+	if err != nil {
+		return err
+	}
+	domains := parseDomains(data)
+
+	b.mu.Lock()
+	b.domains = domains
+	b.modTime = info.ModTime()
+	b.mu.Unlock()
+
+	return nil
+}
+
+func (b *DomainBlocklist) Blocked(domain string) bool {
+	b.refresh()
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+	for {
+		if b.domains[domain] {
+			return true
+		}
+		i := strings.Index(domain, ".")
+		if i < 0 {
+			return false
+		}
+		domain = domain[i+1:]
+	}
+}
+
+func (b *DomainBlocklist) Len() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return len(b.domains)
+}
+
+// refresh reloads file when it's modified, file is checked at most once per interval
+func (b *DomainBlocklist) refresh() {
+	if b.path == "" {
+		return
+	}
+	b.mu.Lock()
+	if time.Since(b.checkedAt) < b.interval {
+		b.mu.Unlock()
+		return
+	}
+	b.checkedAt = time.Now()
+	modTime := b.modTime
+	b.mu.Unlock()
+
+	info, err := os.Stat(b.path)
+	if err != nil || info.ModTime().Equal(modTime) {
+		return
+	}
+	_ = b.Reload()
+}
+
+func parseDomains(data []byte) map[string]bool {
+	domains := make(map[string]bool)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(line), "."))
+		if line != "" {
+			domains[line] = true
+		}
+	}
+
+	return domains
+}
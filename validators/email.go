@@ -1,9 +1,124 @@
 package validators
 
-import "net/mail"
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
 
+	"github.com/ptflp/go-light/config"
+	"golang.org/x/net/idna"
+)
+
+const (
+	maxLocalLength   = 64
+	maxAddressLength = 254
+)
+
+var (
+	ErrEmailFormat     = errors.New("wrong email format")
+	ErrEmailDisposable = errors.New("disposable email addresses are not allowed")
+)
+
+// gmailDomains domains where dots and +tag of local part are ignored
+var gmailDomains = map[string]bool{"gmail.com": true, "googlemail.com": true}
+
+// CheckEmailFormat accepts bare addr-spec only, display names and angle brackets are rejected
 func CheckEmailFormat(email string) error {
-	_, err := mail.ParseAddress(email)
+	_, _, err := splitAddress(email)
 
 	return err
 }
+
+// NormalizeEmail returns canonical form of address: lowercase local part and lowercase ASCII (punycode) domain
+func NormalizeEmail(email string) (string, error) {
+	local, domain, err := splitAddress(strings.TrimSpace(email))
+	if err != nil {
+		return "", err
+	}
+	domain, err = idna.Lookup.ToASCII(domain)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrEmailFormat, err)
+	}
+	if !strings.Contains(domain, ".") {
+		return "", ErrEmailFormat
+	}
+	email = strings.ToLower(local) + "@" + domain
+	if len(email) > maxAddressLength {
+		return "", ErrEmailFormat
+	}
+
+	return email, nil
+}
+
+// CanonicalGmail strips dots and +tag of gmail addresses, other addresses are returned as is
+func CanonicalGmail(email string) string {
+	i := strings.LastIndex(email, "@")
+	if i < 0 || !gmailDomains[email[i+1:]] {
+		return email
+	}
+	local := email[:i]
+	if plus := strings.Index(local, "+"); plus >= 0 {
+		local = local[:plus]
+	}
+
+	return strings.ReplaceAll(local, ".", "") + "@gmail.com"
+}
+
+func splitAddress(email string) (string, string, error) {
+	addr, err := mail.ParseAddress(email)
+	if err != nil {
+		return "", "", fmt.Errorf("%w: %s", ErrEmailFormat, err)
+	}
+	if addr.Name != "" || addr.Address != email {
+		return "", "", ErrEmailFormat
+	}
+	i := strings.LastIndex(addr.Address, "@")
+	local, domain := addr.Address[:i], addr.Address[i+1:]
+	if len(local) > maxLocalLength || strings.HasPrefix(domain, "[") {
+		return "", "", ErrEmailFormat
+	}
+
+	return local, domain, nil
+}
+
+// EmailValidator validation pipeline of addresses entered by users: format, normalization and disposable domains
+type EmailValidator struct {
+	blocklist *DomainBlocklist
+	gmail     bool
+}
+
+func NewEmailValidator(cfg *config.Validation) (*EmailValidator, error) {
+	blocklist, err := NewDomainBlocklist(cfg.DisposableDomains, cfg.ReloadInterval())
+	if err != nil {
+		return nil, err
+	}
+
+	return &EmailValidator{blocklist: blocklist, gmail: cfg.CanonicalGmail}, nil
+}
+
+// Canonical normalized address used for storage and lookups, gmail addresses are canonicalized when enabled
+func (v *EmailValidator) Canonical(email string) (string, error) {
+	email, err := NormalizeEmail(email)
+	if err != nil {
+		return "", err
+	}
+	if v.gmail {
+		email = CanonicalGmail(email)
+	}
+
+	return email, nil
+}
+
+// Validate canonical address of registration or email change, disposable domains are rejected
+func (v *EmailValidator) Validate(email string) (string, error) {
+	email, err := v.Canonical(email)
+	if err != nil {
+		return "", err
+	}
+	if v.blocklist.Blocked(email[strings.LastIndex(email, "@")+1:]) {
+		return "", ErrEmailDisposable
+	}
+
+	return email, nil
+}
@@ -0,0 +1,90 @@
+package validators
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ptflp/go-light/config"
+)
+
+func TestNormalizeEmail(t *testing.T) {
+	tests := []struct {
+		name    string
+		email   string
+		want    string
+		wantErr bool
+	}{
+		{name: "lowercase", email: " User.Name+Tag@Example.COM ", want: "user.name+tag@example.com"},
+		{name: "idn domain", email: "user@Пример.рф", want: "user@xn--e1afmkfd.xn--p1ai"},
+		{name: "display name", email: "User <user@example.com>", wantErr: true},
+		{name: "angle brackets", email: "<user@example.com>", wantErr: true},
+		{name: "no domain dot", email: "user@localhost", wantErr: true},
+		{name: "ip literal", email: "user@[127.0.0.1]", wantErr: true},
+		{name: "no at", email: "user.example.com", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NormalizeEmail(tt.email)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NormalizeEmail() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("NormalizeEmail() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEmailValidator_Validate(t *testing.T) {
+	v, err := NewEmailValidator(&config.Validation{CanonicalGmail: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := v.Validate("First.Last+news@GoogleMail.com")
+	if err != nil || got != "firstlast@gmail.com" {
+		t.Errorf("Validate() = %v, %v", got, err)
+	}
+	if _, err = v.Validate("user@mailinator.com"); err != ErrEmailDisposable {
+		t.Errorf("Validate() error = %v, want %v", err, ErrEmailDisposable)
+	}
+	if _, err = v.Validate("user@eu.mailinator.com"); err != ErrEmailDisposable {
+		t.Errorf("subdomain Validate() error = %v, want %v", err, ErrEmailDisposable)
+	}
+	if got, err = v.Canonical("user@mailinator.com"); err != nil || got != "user@mailinator.com" {
+		t.Errorf("Canonical() = %v, %v", got, err)
+	}
+}
+
+func TestDomainBlocklist_Reload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "blocklist")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "domains.txt")
+	if err = ioutil.WriteFile(path, []byte("# comment\nthrowaway.test\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := NewDomainBlocklist(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !b.Blocked("throwaway.test") || b.Blocked("mailinator.com") || b.Len() != 1 {
+		t.Fatalf("blocklist is not loaded from file")
+	}
+
+	if err = ioutil.WriteFile(path, []byte("other.test\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	future := time.Now().Add(time.Minute)
+	if err = os.Chtimes(path, future, future); err != nil {
+		t.Fatal(err)
+	}
+	if b.Blocked("throwaway.test") || !b.Blocked("other.test") {
+		t.Error("blocklist is not reloaded after file change")
+	}
+}
@@ -1,17 +1,126 @@
 package validators
 
 import (
-	"strconv"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
 
 	"github.com/nyaruka/phonenumbers"
+	"github.com/ptflp/go-light/config"
+	"github.com/ptflp/go-light/types"
 )
 
+const defaultPhoneRegion = "RU"
+
+var (
+	ErrPhoneFormat  = errors.New("wrong phone format")
+	ErrPhoneMobile  = errors.New("phone number can't receive sms")
+	ErrPhoneCountry = errors.New("phone numbers of this country are not accepted")
+)
+
+// CheckPhoneFormat normalizes number of default region, see PhoneValidator for configurable region
 func CheckPhoneFormat(phone string) (string, error) {
-	num, err := phonenumbers.Parse(phone, "RU")
+	num, err := parsePhone(phone, defaultPhoneRegion)
 	if err != nil {
 		return "", err
 	}
-	phone = "7" + strconv.FormatUint(*num.NationalNumber, 10)
 
-	return phone, nil
+	return formatPhone(num), nil
+}
+
+// parsePhone parses number in international format or national format of region,
+// number of other country without "+" (e.g. stored one) is accepted when it's invalid in region
+func parsePhone(phone, region string) (*phonenumbers.PhoneNumber, error) {
+	num, err := phonenumbers.Parse(phone, region)
+	if err == nil && phonenumbers.IsValidNumber(num) {
+		return num, nil
+	}
+	if strings.HasPrefix(strings.TrimSpace(phone), "+") {
+		return nil, phoneError(err)
+	}
+	num, intErr := phonenumbers.Parse("+"+strings.TrimSpace(phone), "")
+	if intErr != nil || !phonenumbers.IsValidNumber(num) {
+		return nil, phoneError(err)
+	}
+
+	return num, nil
+}
+
+func phoneError(err error) error {
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrPhoneFormat, err)
+	}
+
+	return ErrPhoneFormat
+}
+
+// formatPhone E.164 without leading "+", format of stored numbers
+func formatPhone(num *phonenumbers.PhoneNumber) string {
+	return strings.TrimPrefix(phonenumbers.Format(num, phonenumbers.E164), "+")
+}
+
+// PhoneValidator normalizes numbers entered by users, region of national numbers is taken from request
+// (see types.Region) or configured default
+type PhoneValidator struct {
+	region  string
+	allowed map[string]bool
+	denied  map[string]bool
+}
+
+func NewPhoneValidator(cfg *config.Phone) *PhoneValidator {
+	return &PhoneValidator{
+		region:  cfg.Region(),
+		allowed: regionSet(cfg.AllowedCountries),
+		denied:  regionSet(cfg.DeniedCountries),
+	}
+}
+
+// Normalize returns number in storage format, used to find existing accounts so country lists are not applied
+func (v *PhoneValidator) Normalize(ctx context.Context, phone string) (string, error) {
+	num, err := parsePhone(phone, v.Region(ctx))
+	if err != nil {
+		return "", err
+	}
+
+	return formatPhone(num), nil
+}
+
+// Mobile returns number in storage format, when sms can be sent to it and its country is accepted
+func (v *PhoneValidator) Mobile(ctx context.Context, phone string) (string, error) {
+	num, err := parsePhone(phone, v.Region(ctx))
+	if err != nil {
+		return "", err
+	}
+	switch phonenumbers.GetNumberType(num) {
+	case phonenumbers.MOBILE, phonenumbers.FIXED_LINE_OR_MOBILE:
+	default:
+		return "", ErrPhoneMobile
+	}
+	region := phonenumbers.GetRegionCodeForNumber(num)
+	if v.denied[region] || (len(v.allowed) > 0 && !v.allowed[region]) {
+		return "", fmt.Errorf("%w: %s", ErrPhoneCountry, region)
+	}
+
+	return formatPhone(num), nil
+}
+
+// Region region of request when it's known to phonenumbers, default region otherwise
+func (v *PhoneValidator) Region(ctx context.Context) string {
+	region, _ := ctx.Value(types.Region{}).(string)
+	region = strings.ToUpper(region)
+	if phonenumbers.GetCountryCodeForRegion(region) == 0 {
+		return v.region
+	}
+
+	return region
+}
+
+func regionSet(regions []string) map[string]bool {
+	set := make(map[string]bool, len(regions))
+	for _, region := range regions {
+		set[strings.ToUpper(region)] = true
+	}
+
+	return set
 }
@@ -1,6 +1,13 @@
 package validators
 
-import "testing"
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ptflp/go-light/config"
+	"github.com/ptflp/go-light/types"
+)
 
 func TestCheckPhoneFormat(t *testing.T) {
 	type args struct {
@@ -24,13 +31,30 @@ func TestCheckPhoneFormat(t *testing.T) {
 			want:    "79644288083",
 			wantErr: false,
 		},
-		// TODO: fix short number want error
 		{
 			name:    "check short number",
 			args:    args{phone: "+7964428808"},
-			want:    "7964428808",
+			want:    "",
+			wantErr: true,
+		},
+		{
+			name:    "international number keeps its country code",
+			args:    args{phone: "+49 151 12345678"},
+			want:    "4915112345678",
 			wantErr: false,
 		},
+		{
+			name:    "stored international number without plus",
+			args:    args{phone: "4915112345678"},
+			want:    "4915112345678",
+			wantErr: false,
+		},
+		{
+			name:    "check letters",
+			args:    args{phone: "phone"},
+			want:    "",
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -45,3 +69,50 @@ func TestCheckPhoneFormat(t *testing.T) {
 		})
 	}
 }
+
+func TestPhoneValidator(t *testing.T) {
+	v := NewPhoneValidator(&config.Phone{DefaultRegion: "de", DeniedCountries: []string{"kz"}})
+	ctx := context.Background()
+	us := context.WithValue(ctx, types.Region{}, "US")
+	unknown := context.WithValue(ctx, types.Region{}, "XX")
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		phone   string
+		want    string
+		wantErr error
+	}{
+		{name: "default region", ctx: ctx, phone: "0151 12345678", want: "4915112345678"},
+		{name: "region of request", ctx: us, phone: "(201) 555-0123", want: "12015550123"},
+		{name: "unknown region of request", ctx: unknown, phone: "0151 12345678", want: "4915112345678"},
+		{name: "international number", ctx: us, phone: "+7 964 428-80-83", want: "79644288083"},
+		{name: "landline", ctx: ctx, phone: "030 123456", wantErr: ErrPhoneMobile},
+		{name: "denied country", ctx: ctx, phone: "+77012345678", wantErr: ErrPhoneCountry},
+		{name: "short number", ctx: ctx, phone: "0151 1234", wantErr: ErrPhoneFormat},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := v.Mobile(tt.ctx, tt.phone)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Mobile() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Mobile() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// login of existing accounts is not limited by country lists
+	if got, err := v.Normalize(ctx, "+77012345678"); err != nil || got != "77012345678" {
+		t.Errorf("Normalize() = %v, %v", got, err)
+	}
+
+	allowed := NewPhoneValidator(&config.Phone{AllowedCountries: []string{"RU"}})
+	if _, err := allowed.Mobile(ctx, "89644288083"); err != nil {
+		t.Errorf("Mobile() of allowed country error = %v", err)
+	}
+	if _, err := allowed.Mobile(ctx, "+4915112345678"); !errors.Is(err, ErrPhoneCountry) {
+		t.Errorf("Mobile() of not allowed country error = %v", err)
+	}
+}